@@ -3,4 +3,29 @@ package golog
 import "io"
 
 
-type Appender = io.WriteCloser
\ No newline at end of file
+type Appender = io.WriteCloser
+
+// EventAppender is implemented by appenders which need the log level, the metadata
+// and the fields of an event rather than its encoded bytes.
+// The logger calls AppendEvent instead of Write for such appenders.
+// metadata is nil when metadata is disabled on the logger.
+type EventAppender interface {
+	Appender
+	AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error
+}
+
+// Syncer is implemented by appenders which buffer events.
+// Flush writes the buffered events to the underlying output.
+type Syncer interface {
+	Flush() error
+}
+
+// appendEvent delivers an event to the appender, through AppendEvent if it is an EventAppender.
+func appendEvent(appender Appender, level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	if eventAppender, ok := appender.(EventAppender); ok {
+		return eventAppender.AppendEvent(level, logEvent, metadata)
+	}
+
+	_, err := appender.Write(logEvent.Encode(metadata))
+	return err
+}
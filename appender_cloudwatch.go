@@ -0,0 +1,394 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// service limits of PutLogEvents
+const (
+	cloudWatchMaxBatchSize  = 10000
+	cloudWatchMaxBatchBytes = 1048576
+	cloudWatchMaxEventBytes = 262144
+	cloudWatchMaxBatchSpan  = time.Hour * 24
+
+	// cloudWatchEventOverhead is counted for every event in addition to the message
+	cloudWatchEventOverhead = 26
+)
+
+const cloudWatchLogsTargetPrefix = "Logs_20140328."
+
+// CloudWatchLogsAppenderConfig
+type CloudWatchLogsAppenderConfig struct {
+	// Region defaults to AWS_REGION or AWS_DEFAULT_REGION
+	Region string
+
+	// Endpoint defaults to https://logs.<Region>.amazonaws.com
+	Endpoint string
+
+	LogGroupName string
+
+	// LogStreamName defaults to the host name
+	LogStreamName string
+
+	// CreateLogGroup creates the log group and the log stream when they do not exist
+	CreateLogGroup bool
+
+	// AccessKeyId, SecretAccessKey and SessionToken default to AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// MaxBatchSize is at most 10000 events, batches are also limited to 1 MB
+	MaxBatchSize  int
+	FlushInterval time.Duration
+	QueueSize     int
+
+	// MaxRetries is used for both network errors and throttling
+	MaxRetries int
+	Timeout    time.Duration
+
+	// HttpClient is used instead of a client with Timeout if specified
+	HttpClient *http.Client
+}
+
+// NewDefaultCloudWatchLogsAppenderConfig returns the config with the region and the credentials of the environment
+func NewDefaultCloudWatchLogsAppenderConfig() CloudWatchLogsAppenderConfig {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+
+	return CloudWatchLogsAppenderConfig{
+		Region:          region,
+		CreateLogGroup:  true,
+		AccessKeyId:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		MaxBatchSize:    defaultMaxBatchSize,
+		FlushInterval:   defaultBatchFlushInterval,
+		QueueSize:       defaultBatchQueueSize,
+		MaxRetries:      defaultMaxRetries,
+		Timeout:         defaultHttpTimeout,
+	}
+}
+
+// CloudWatchLogsAppender sends events to Amazon CloudWatch Logs by PutLogEvents signed with Signature Version 4.
+// Events are queued and sent in batches from a background goroutine. Batches are split to keep the service limits,
+// 10000 events and 1 MB per request, in order of time and spanning less than 24 hours.
+type CloudWatchLogsAppender struct {
+	config    CloudWatchLogsAppenderConfig
+	client    *http.Client
+	processor *batchProcessor
+}
+
+// NewCloudWatchLogsAppender returns new CloudWatchLogsAppender
+func NewCloudWatchLogsAppender(config CloudWatchLogsAppenderConfig) *CloudWatchLogsAppender {
+	if config.Endpoint == "" {
+		config.Endpoint = "https://logs." + config.Region + ".amazonaws.com"
+	}
+
+	if config.LogStreamName == "" {
+		config.LogStreamName, _ = os.Hostname()
+	}
+
+	if config.MaxBatchSize <= 0 || config.MaxBatchSize > cloudWatchMaxBatchSize {
+		config.MaxBatchSize = cloudWatchMaxBatchSize
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHttpTimeout
+	}
+
+	client := config.HttpClient
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+
+	appender := &CloudWatchLogsAppender{
+		config: config,
+		client: client,
+	}
+	appender.processor = newBatchProcessorWithMaxBytes(appender.export, config.MaxBatchSize,
+		cloudWatchMaxBatchBytes, appender.eventSize, config.FlushInterval, config.QueueSize)
+	return appender
+}
+
+// AppendEvent implements EventAppender
+func (appender *CloudWatchLogsAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	return appender.processor.enqueue(appender.render(newEventRecord(level, logEvent, metadata)))
+}
+
+// Write implements io.Writer
+// Data is sent as the message of an INFO event.
+func (appender *CloudWatchLogsAppender) Write(data []byte) (n int, err error) {
+	if err := appender.processor.enqueue(appender.render(newRawEventRecord(data))); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush implements Syncer
+func (appender *CloudWatchLogsAppender) Flush() error {
+	return appender.processor.flush()
+}
+
+// Close implements io.Closer
+// Queued events are sent before it returns.
+func (appender *CloudWatchLogsAppender) Close() error {
+	appender.processor.close()
+	return nil
+}
+
+// cloudWatchEvent is InputLogEvent
+type cloudWatchEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// cloudWatchError is the error response of CloudWatch Logs
+type cloudWatchError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// cloudWatchErrorType returns the type of the error response, e.g. ResourceNotFoundException
+func cloudWatchErrorType(err error) string {
+	var statusErr *httpStatusError
+	if !errors.As(err, &statusErr) {
+		return ""
+	}
+
+	response := cloudWatchError{}
+	if json.Unmarshal([]byte(statusErr.Body), &response) != nil {
+		return ""
+	}
+	return response.Type[strings.LastIndex(response.Type, "#")+1:]
+}
+
+// export
+func (appender *CloudWatchLogsAppender) export(records []eventRecord) error {
+	events := make([]cloudWatchEvent, 0, len(records))
+	for _, record := range records {
+		events = append(events, cloudWatchEvent{
+			Timestamp: record.time.UnixNano() / int64(time.Millisecond),
+			Message:   record.message,
+		})
+	}
+
+	var errs []error
+	for _, batch := range splitCloudWatchEvents(events) {
+		errs = append(errs, appender.putLogEvents(batch))
+	}
+	return errors.Join(errs...)
+}
+
+// splitCloudWatchEvents sorts events by time and splits them into batches within the limits of PutLogEvents
+func splitCloudWatchEvents(events []cloudWatchEvent) [][]cloudWatchEvent {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp < events[j].Timestamp
+	})
+
+	var batches [][]cloudWatchEvent
+	start := 0
+	size := 0
+	for i, event := range events {
+		eventSize := len(event.Message) + cloudWatchEventOverhead
+		if i > start && (i-start >= cloudWatchMaxBatchSize || size+eventSize > cloudWatchMaxBatchBytes ||
+			time.Duration(event.Timestamp-events[start].Timestamp)*time.Millisecond >= cloudWatchMaxBatchSpan) {
+			batches = append(batches, events[start:i])
+			start = i
+			size = 0
+		}
+		size += eventSize
+	}
+	if start < len(events) {
+		batches = append(batches, events[start:])
+	}
+	return batches
+}
+
+// putLogEvents sends the batch, creating the log group and the log stream if they do not exist
+func (appender *CloudWatchLogsAppender) putLogEvents(events []cloudWatchEvent) error {
+	request := map[string]interface{}{
+		"logGroupName":  appender.config.LogGroupName,
+		"logStreamName": appender.config.LogStreamName,
+		"logEvents":     events,
+	}
+
+	body, err := appender.call("PutLogEvents", request)
+	if cloudWatchErrorType(err) == "ResourceNotFoundException" && appender.config.CreateLogGroup {
+		if err := appender.createLogStream(); err != nil {
+			return err
+		}
+		body, err = appender.call("PutLogEvents", request)
+	}
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		RejectedLogEventsInfo *struct {
+			TooNewLogEventStartIndex *int `json:"tooNewLogEventStartIndex"`
+			TooOldLogEventEndIndex   *int `json:"tooOldLogEventEndIndex"`
+			ExpiredLogEventEndIndex  *int `json:"expiredLogEventEndIndex"`
+		} `json:"rejectedLogEventsInfo"`
+	}
+	if len(body) == 0 || json.Unmarshal(body, &response) != nil || response.RejectedLogEventsInfo == nil {
+		return nil
+	}
+
+	rejected := response.RejectedLogEventsInfo
+	var errs []error
+	if rejected.TooOldLogEventEndIndex != nil {
+		errs = append(errs, fmt.Errorf("%d events are too old", *rejected.TooOldLogEventEndIndex+1))
+	}
+	if rejected.ExpiredLogEventEndIndex != nil {
+		errs = append(errs, fmt.Errorf("%d events are expired", *rejected.ExpiredLogEventEndIndex+1))
+	}
+	if rejected.TooNewLogEventStartIndex != nil {
+		errs = append(errs, fmt.Errorf("%d events are too new", len(events)-*rejected.TooNewLogEventStartIndex))
+	}
+	return errors.Join(errs...)
+}
+
+// createLogStream creates the log group and the log stream, ignoring those which already exist
+func (appender *CloudWatchLogsAppender) createLogStream() error {
+	_, err := appender.call("CreateLogGroup", map[string]string{"logGroupName": appender.config.LogGroupName})
+	if err != nil && cloudWatchErrorType(err) != "ResourceAlreadyExistsException" {
+		return err
+	}
+
+	_, err = appender.call("CreateLogStream", map[string]string{
+		"logGroupName":  appender.config.LogGroupName,
+		"logStreamName": appender.config.LogStreamName,
+	})
+	if err != nil && cloudWatchErrorType(err) != "ResourceAlreadyExistsException" {
+		return err
+	}
+	return nil
+}
+
+// call sends the signed action, backing off while it is throttled
+func (appender *CloudWatchLogsAppender) call(action string, payload interface{}) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := awsCredentials{
+		accessKeyId:     appender.config.AccessKeyId,
+		secretAccessKey: appender.config.SecretAccessKey,
+		sessionToken:    appender.config.SessionToken,
+	}
+
+	for attempt := 0; ; attempt++ {
+		responseBody, err := doWithRetry(appender.client, appender.config.MaxRetries, appender.processor.closing(), func() (*http.Request, error) {
+			request, err := http.NewRequest(http.MethodPost, appender.config.Endpoint, bytes.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+			request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+			request.Header.Set("X-Amz-Target", cloudWatchLogsTargetPrefix+action)
+			signAwsRequest(request, body, credentials, appender.config.Region, "logs", time.Now())
+			return request, nil
+		})
+		if cloudWatchErrorType(err) != "ThrottlingException" || attempt >= appender.config.MaxRetries ||
+			!waitRetry(retryBackoff(attempt), appender.processor.closing()) {
+			return responseBody, err
+		}
+	}
+}
+
+// render returns the record whose message is the message sent to CloudWatch Logs,
+// so that the message is built once for the size of the batch and the export
+func (appender *CloudWatchLogsAppender) render(record eventRecord) eventRecord {
+	return eventRecord{level: record.level, message: appender.message(record), time: record.time, raw: record.raw}
+}
+
+// eventSize returns the size of the rendered record counted for the 1 MB limit
+func (appender *CloudWatchLogsAppender) eventSize(record eventRecord) int {
+	return len(record.message) + cloudWatchEventOverhead
+}
+
+// cloudWatchReservedFieldNames are the keys of the metadata in JSON object messages
+var cloudWatchReservedFieldNames = map[string]bool{
+	"level":       true,
+	"logger":      true,
+	"trace_id":    true,
+	"span_id":     true,
+	"stack_trace": true,
+}
+
+// message returns the message of the record with the level, the logger, the fields and the stack trace.
+// Fields are added to a JSON object message and written after other messages.
+// Fields of the keys of the metadata are followed by _ in a JSON object message.
+func (appender *CloudWatchLogsAppender) message(record eventRecord) string {
+	metadata := record.metadata
+	message := strings.TrimRight(record.message, "\n")
+
+	var object map[string]interface{}
+	if strings.HasPrefix(message, "{") && json.Unmarshal([]byte(message), &object) == nil {
+		object["level"] = levelName(record.level)
+		if metadata.LoggerName != "" {
+			object["logger"] = metadata.LoggerName
+		}
+		if metadata.TraceId != "" {
+			object["trace_id"] = metadata.TraceId
+			object["span_id"] = metadata.SpanId
+		}
+		for _, field := range metadata.Fields {
+			key := field.Key
+			if cloudWatchReservedFieldNames[key] {
+				key += "_"
+			}
+			object[key] = otlpAttributeValue(field.Value)
+		}
+		if len(metadata.StackTrace) > 0 {
+			object["stack_trace"] = metadata.StackTrace.String()
+		}
+		if data, err := json.Marshal(object); err == nil {
+			return truncateCloudWatchMessage(string(data))
+		}
+	}
+
+	line := levelName(record.level) + " "
+	if metadata.LoggerName != "" {
+		line += metadata.LoggerName + " "
+	}
+	line += message
+
+	var fields Fields
+	if metadata.TraceId != "" {
+		fields = append(fields, F("trace_id", metadata.TraceId), F("span_id", metadata.SpanId))
+	}
+	fields = append(fields, metadata.Fields...)
+	if len(fields) > 0 {
+		line += " " + fields.String()
+	}
+	if len(metadata.StackTrace) > 0 {
+		line += "\n" + metadata.StackTrace.String()
+	}
+	return truncateCloudWatchMessage(line)
+}
+
+// truncateCloudWatchMessage truncates the message to the maximum event size at a rune boundary
+func truncateCloudWatchMessage(message string) string {
+	limit := cloudWatchMaxEventBytes - cloudWatchEventOverhead
+	if len(message) <= limit {
+		return message
+	}
+
+	for limit > 0 && !utf8.RuneStart(message[limit]) {
+		limit--
+	}
+	return message[:limit]
+}
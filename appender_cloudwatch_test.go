@@ -0,0 +1,150 @@
+package golog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// cloudWatchServer is a local CloudWatch Logs endpoint
+type cloudWatchServer struct {
+	mu sync.Mutex
+	// throttled is the number of PutLogEvents answered with ThrottlingException
+	throttled int
+	groups    map[string]bool
+	streams   map[string][]cloudWatchEvent
+	actions   []string
+}
+
+func (server *cloudWatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+		!strings.Contains(r.Header.Get("Authorization"), "/us-east-1/logs/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-target,") {
+		server.fail(w, "AccessDeniedException")
+		return
+	}
+
+	var request struct {
+		LogGroupName  string            `json:"logGroupName"`
+		LogStreamName string            `json:"logStreamName"`
+		LogEvents     []cloudWatchEvent `json:"logEvents"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&request)
+	stream := request.LogGroupName + "/" + request.LogStreamName
+
+	action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), cloudWatchLogsTargetPrefix)
+	server.actions = append(server.actions, action)
+	switch action {
+	case "CreateLogGroup":
+		server.groups[request.LogGroupName] = true
+	case "CreateLogStream":
+		if !server.groups[request.LogGroupName] {
+			server.fail(w, "ResourceNotFoundException")
+			return
+		}
+		if _, ok := server.streams[stream]; ok {
+			server.fail(w, "ResourceAlreadyExistsException")
+			return
+		}
+		server.streams[stream] = nil
+	case "PutLogEvents":
+		if server.throttled > 0 {
+			server.throttled--
+			server.fail(w, "ThrottlingException")
+			return
+		}
+		if _, ok := server.streams[stream]; !ok {
+			server.fail(w, "ResourceNotFoundException")
+			return
+		}
+		for i := 1; i < len(request.LogEvents); i++ {
+			if request.LogEvents[i].Timestamp < request.LogEvents[i-1].Timestamp {
+				server.fail(w, "InvalidParameterException")
+				return
+			}
+		}
+		server.streams[stream] = append(server.streams[stream], request.LogEvents...)
+	}
+	w.Write([]byte("{}"))
+}
+
+func (server *cloudWatchServer) fail(w http.ResponseWriter, errorType string) {
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(cloudWatchError{Type: "com.amazonaws.logs#" + errorType, Message: errorType})
+}
+
+func TestCloudWatchLogsAppender(t *testing.T) {
+
+	stub := &cloudWatchServer{throttled: 1, groups: map[string]bool{}, streams: map[string][]cloudWatchEvent{}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	config := NewDefaultCloudWatchLogsAppenderConfig()
+	config.Region = "us-east-1"
+	config.Endpoint = server.URL
+	config.LogGroupName = "group1"
+	config.LogStreamName = "stream1"
+	config.AccessKeyId = "AKIDEXAMPLE"
+	config.SecretAccessKey = "secret"
+	config.SessionToken = ""
+	appender := NewCloudWatchLogsAppender(config)
+
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.SetMetadataConfig(&MetadataConfig{IsEnabledLoggerName: true})
+	alice := logger.With(F("user", "alice"))
+	alice.Info("first")
+	levelField := logger.With(F("level", "field"))
+	levelField.Warn(`{"action":"second"}`)
+	// retries are not waited for on close
+	assert.Nil(t, appender.Flush())
+	assert.Nil(t, logger.Close())
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+
+	// the group and the stream are created on demand, throttled requests are retried
+	assert.Equal(t, []string{"PutLogEvents", "PutLogEvents", "CreateLogGroup", "CreateLogStream", "PutLogEvents"}, stub.actions)
+
+	events := stub.streams["group1/stream1"]
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "INFO testLogger first user=alice", events[0].Message)
+	assert.JSONEq(t, `{"action":"second","level":"WARN","level_":"field","logger":"testLogger"}`, events[1].Message)
+	assert.True(t, time.Since(time.UnixMilli(events[0].Timestamp)) < time.Minute)
+}
+
+func TestSplitCloudWatchEvents(t *testing.T) {
+
+	hour := int64(time.Hour / time.Millisecond)
+
+	// events are sorted and batches span less than 24 hours
+	batches := splitCloudWatchEvents([]cloudWatchEvent{
+		{Timestamp: 25 * hour, Message: "c"},
+		{Timestamp: 0, Message: "a"},
+		{Timestamp: 23 * hour, Message: "b"},
+	})
+	assert.Equal(t, [][]cloudWatchEvent{
+		{{Timestamp: 0, Message: "a"}, {Timestamp: 23 * hour, Message: "b"}},
+		{{Timestamp: 25 * hour, Message: "c"}},
+	}, batches)
+
+	// at most 10000 events
+	events := make([]cloudWatchEvent, 10001)
+	batches = splitCloudWatchEvents(events)
+	assert.Equal(t, 2, len(batches))
+	assert.Equal(t, 10000, len(batches[0]))
+
+	// at most 1 MB including 26 bytes per event
+	large := truncateCloudWatchMessage(strings.Repeat("あ", 100000))
+	assert.Equal(t, cloudWatchMaxEventBytes-cloudWatchEventOverhead-2, len(large))
+	batches = splitCloudWatchEvents([]cloudWatchEvent{{Message: large}, {Message: large}, {Message: large}, {Message: large}, {Message: large}})
+	assert.Equal(t, 2, len(batches))
+	assert.Equal(t, 4, len(batches[0]))
+}
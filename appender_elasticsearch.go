@@ -0,0 +1,376 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultElasticsearchEndpoint = "http://localhost:9200"
+
+const defaultElasticsearchIndex = "logs"
+
+const defaultElasticsearchIndexDateLayout = "2006.01.02"
+
+// defaultElasticsearchMaxBatchBytes bounds the estimated size of a bulk request
+const defaultElasticsearchMaxBatchBytes = 5 * 1024 * 1024
+
+const ecsVersion = "8.11.0"
+
+// ecsFieldNames maps the keys of JsonLogEvent to ECS field names
+var ecsFieldNames = map[string]string{
+	"logLevel":    "log.level",
+	"timestamp":   "@timestamp",
+	"sourceFile":  "log.origin.file.name",
+	"sourceLine":  "log.origin.file.line",
+	"loggerName":  "log.logger",
+	"trace_id":    "trace.id",
+	"span_id":     "span.id",
+	"trace_flags": "trace.flags",
+	"stackTrace":  "error.stack_trace",
+}
+
+// ElasticsearchAppenderConfig
+type ElasticsearchAppenderConfig struct {
+	// Endpoint is the url of Elasticsearch or OpenSearch, e.g. http://localhost:9200
+	Endpoint string
+
+	// Index is followed by the date of the event formatted by IndexDateLayout,
+	// e.g. logs-app and 2006.01.02 index into logs-app-2026.10.18
+	Index           string
+	IndexDateLayout string
+
+	// ServiceName is indexed as service.name
+	ServiceName string
+
+	// Username and Password are sent by basic authentication, ApiKey as an ApiKey authorization
+	Username string
+	Password string
+	ApiKey   string
+
+	// Headers are added to every request
+	Headers map[string]string
+
+	MaxBatchSize  int
+	MaxBatchBytes int
+	FlushInterval time.Duration
+	QueueSize     int
+
+	// MaxRetries retries failed requests and rejected documents
+	MaxRetries int
+	Timeout    time.Duration
+
+	// HttpClient is used instead of a client with Timeout if specified
+	HttpClient *http.Client
+}
+
+// NewDefaultElasticsearchAppenderConfig
+func NewDefaultElasticsearchAppenderConfig() ElasticsearchAppenderConfig {
+	return ElasticsearchAppenderConfig{
+		Endpoint:        defaultElasticsearchEndpoint,
+		Index:           defaultElasticsearchIndex,
+		IndexDateLayout: defaultElasticsearchIndexDateLayout,
+		MaxBatchSize:    defaultMaxBatchSize,
+		MaxBatchBytes:   defaultElasticsearchMaxBatchBytes,
+		FlushInterval:   defaultBatchFlushInterval,
+		QueueSize:       defaultBatchQueueSize,
+		MaxRetries:      defaultMaxRetries,
+		Timeout:         defaultHttpTimeout,
+	}
+}
+
+// ElasticsearchAppender indexes events into Elasticsearch or OpenSearch through the _bulk API.
+// Events are queued and indexed in batches bounded by count, size and time from a background goroutine.
+// Documents use ECS field names. Documents rejected with 429 or 5xx are retried alone,
+// documents rejected otherwise are dropped and reported.
+type ElasticsearchAppender struct {
+	config    ElasticsearchAppenderConfig
+	client    *http.Client
+	hostName  string
+	processor *batchProcessor
+}
+
+// NewElasticsearchAppender returns new ElasticsearchAppender
+func NewElasticsearchAppender(config ElasticsearchAppenderConfig) *ElasticsearchAppender {
+	if config.Endpoint == "" {
+		config.Endpoint = defaultElasticsearchEndpoint
+	}
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+
+	if config.Index == "" {
+		config.Index = defaultElasticsearchIndex
+	}
+
+	if config.IndexDateLayout == "" {
+		config.IndexDateLayout = defaultElasticsearchIndexDateLayout
+	}
+
+	if config.MaxBatchBytes <= 0 {
+		config.MaxBatchBytes = defaultElasticsearchMaxBatchBytes
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHttpTimeout
+	}
+
+	client := config.HttpClient
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+
+	hostName, _ := os.Hostname()
+	appender := &ElasticsearchAppender{
+		config:   config,
+		client:   client,
+		hostName: hostName,
+	}
+	appender.processor = newBatchProcessorWithMaxBytes(appender.export, config.MaxBatchSize,
+		config.MaxBatchBytes, estimateDocumentSize, config.FlushInterval, config.QueueSize)
+	return appender
+}
+
+// AppendEvent implements EventAppender
+func (appender *ElasticsearchAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	return appender.processor.enqueue(newEventRecord(level, logEvent, metadata))
+}
+
+// Write implements io.Writer
+// Data is indexed as the message of an INFO event.
+func (appender *ElasticsearchAppender) Write(data []byte) (n int, err error) {
+	if err := appender.processor.enqueue(newRawEventRecord(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush implements Syncer
+func (appender *ElasticsearchAppender) Flush() error {
+	return appender.processor.flush()
+}
+
+// Close implements io.Closer
+// Queued events are indexed before it returns.
+func (appender *ElasticsearchAppender) Close() error {
+	appender.processor.close()
+	return nil
+}
+
+// estimateDocumentSize estimates the size of the bulk lines of the record without encoding it
+func estimateDocumentSize(record eventRecord) int {
+	return len(record.message) + 256 + len(record.metadata.Fields)*32 + len(record.metadata.StackTrace)*128
+}
+
+// bulkDocument is a document and its bulk action line
+type bulkDocument struct {
+	action   []byte
+	document []byte
+}
+
+// elasticsearchBulkResponse
+type elasticsearchBulkResponse struct {
+	Errors bool                               `json:"errors"`
+	Items  []map[string]elasticsearchBulkItem `json:"items"`
+}
+
+// elasticsearchBulkItem is the result of an action
+type elasticsearchBulkItem struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// export sends the records and retries the rejected documents
+func (appender *ElasticsearchAppender) export(records []eventRecord) error {
+	documents := make([]bulkDocument, 0, len(records))
+	for _, record := range records {
+		document, err := json.Marshal(appender.ecsDocument(record))
+		if err != nil {
+			// e.g. a NaN field, the other events of the batch are still sent
+			warnExportError(fmt.Errorf("drop event which can not be encoded : %w", err))
+			continue
+		}
+		action, err := json.Marshal(map[string]interface{}{
+			"create": map[string]string{"_index": appender.index(record.time)},
+		})
+		if err != nil {
+			return err
+		}
+		documents = append(documents, bulkDocument{action: action, document: document})
+	}
+	if len(documents) == 0 {
+		return nil
+	}
+
+	var dropped []string
+	for attempt := 0; ; attempt++ {
+		rejected, failures, err := appender.bulk(documents)
+		if err != nil {
+			return err
+		}
+		dropped = append(dropped, failures...)
+
+		if len(rejected) == 0 {
+			break
+		}
+		if attempt >= appender.config.MaxRetries || !waitRetry(retryBackoff(attempt), appender.processor.closing()) {
+			dropped = append(dropped, fmt.Sprintf("%d documents are still rejected after %d attempts", len(rejected), attempt+1))
+			break
+		}
+		documents = rejected
+	}
+
+	if len(dropped) > 0 {
+		return fmt.Errorf("bulk index is failed : %s", strings.Join(dropped, ", "))
+	}
+	return nil
+}
+
+// bulk sends the documents and returns the documents to retry and the failures of the others
+func (appender *ElasticsearchAppender) bulk(documents []bulkDocument) ([]bulkDocument, []string, error) {
+	var body bytes.Buffer
+	for _, document := range documents {
+		body.Write(document.action)
+		body.WriteByte('\n')
+		body.Write(document.document)
+		body.WriteByte('\n')
+	}
+
+	responseBody, err := doWithRetry(appender.client, appender.config.MaxRetries, appender.processor.closing(), func() (*http.Request, error) {
+		request, err := http.NewRequest(http.MethodPost, appender.config.Endpoint+"/_bulk", bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", "application/x-ndjson")
+		if appender.config.Username != "" {
+			request.SetBasicAuth(appender.config.Username, appender.config.Password)
+		}
+		if appender.config.ApiKey != "" {
+			request.Header.Set("Authorization", "ApiKey "+appender.config.ApiKey)
+		}
+		for k, v := range appender.config.Headers {
+			request.Header.Set(k, v)
+		}
+		return request, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response elasticsearchBulkResponse
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return nil, nil, err
+	}
+	if !response.Errors {
+		return nil, nil, nil
+	}
+
+	var rejected []bulkDocument
+	var failures []string
+	for i, result := range response.Items {
+		if i >= len(documents) {
+			break
+		}
+		for _, item := range result {
+			if item.Status >= 200 && item.Status < 300 {
+				continue
+			}
+			if isRetryableStatus(item.Status) || item.Status >= 500 {
+				rejected = append(rejected, documents[i])
+				continue
+			}
+			failure := fmt.Sprintf("status %d", item.Status)
+			if item.Error != nil {
+				failure = fmt.Sprintf("status %d %s %s", item.Status, item.Error.Type, item.Error.Reason)
+			}
+			failures = append(failures, failure)
+		}
+	}
+	return rejected, failures, nil
+}
+
+// index returns the index of the date
+func (appender *ElasticsearchAppender) index(t time.Time) string {
+	return appender.config.Index + "-" + t.UTC().Format(appender.config.IndexDateLayout)
+}
+
+// ecsDocument returns the document of the record with ECS field names.
+// A JSON object event is merged into the document. For JSON written through io.Writer,
+// the keys of the metadata of JsonLogEvent are renamed, and its time and level are kept.
+// Fields are nested under "fields" so that they can not overwrite the ECS fields.
+func (appender *ElasticsearchAppender) ecsDocument(record eventRecord) map[string]interface{} {
+	document := map[string]interface{}{}
+
+	message := strings.TrimRight(record.message, "\n")
+	var object map[string]interface{}
+	if strings.HasPrefix(message, "{") && json.Unmarshal([]byte(message), &object) == nil {
+		for key, value := range object {
+			if !record.raw {
+				document[key] = value
+				continue
+			}
+			if name, ok := ecsFieldNames[key]; ok {
+				key = name
+			}
+			if level, ok := value.(string); ok && key == "log.level" {
+				value = strings.Trim(level, "[]")
+			}
+			document[key] = value
+		}
+	} else {
+		document["message"] = message
+	}
+
+	metadata := record.metadata
+	// the time and the level of data written by JsonLogEvent with metadata are kept
+	if _, ok := document["@timestamp"]; !ok || !record.raw {
+		document["@timestamp"] = record.time.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	}
+	if _, ok := document["log.level"]; !ok || !record.raw {
+		document["log.level"] = levelName(record.level)
+	}
+	document["ecs.version"] = ecsVersion
+
+	if appender.config.ServiceName != "" {
+		document["service.name"] = appender.config.ServiceName
+	}
+	if appender.hostName != "" {
+		document["host.name"] = appender.hostName
+	}
+	if metadata.LoggerName != "" {
+		document["log.logger"] = metadata.LoggerName
+	}
+	if metadata.SourceFile != "" {
+		document["log.origin.file.name"] = filepath.Base(metadata.SourceFile)
+		document["log.origin.file.line"] = metadata.SourceLine
+	}
+	if metadata.TraceId != "" {
+		document["trace.id"] = metadata.TraceId
+		document["span.id"] = metadata.SpanId
+	}
+	if len(metadata.StackTrace) > 0 {
+		document["error.stack_trace"] = metadata.StackTrace.String()
+	}
+
+	fields := map[string]interface{}{}
+	for _, field := range metadata.Fields {
+		if detail, ok := field.Value.(ErrorDetail); ok {
+			document["error.message"] = detail.Message
+			if block := detail.stackTraceBlock(); block != "" {
+				document["error.stack_trace"] = strings.TrimPrefix(block, "\n")
+			}
+			continue
+		}
+		fields[field.Key] = otlpAttributeValue(field.Value)
+	}
+	if len(fields) > 0 {
+		document["fields"] = fields
+	}
+	return document
+}
@@ -0,0 +1,179 @@
+package golog
+
+import (
+	"bufio"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bulkServer is a local _bulk endpoint.
+// Documents whose message contains "busy" are rejected with 429 once, "invalid" with 400 always.
+type bulkServer struct {
+	mu       sync.Mutex
+	requests [][]map[string]interface{}
+	indices  []string
+	rejected map[string]bool
+}
+
+func (server *bulkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var documents []map[string]interface{}
+	var items []map[string]interface{}
+	hasErrors := false
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]map[string]string
+		json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan()
+		var document map[string]interface{}
+		json.Unmarshal(scanner.Bytes(), &document)
+		documents = append(documents, document)
+
+		message, _ := document["message"].(string)
+		status := http.StatusCreated
+		switch {
+		case strings.Contains(message, "busy") && !server.rejected[message]:
+			server.rejected[message] = true
+			status = http.StatusTooManyRequests
+		case strings.Contains(message, "invalid"):
+			status = http.StatusBadRequest
+		default:
+			server.indices = append(server.indices, action["create"]["_index"])
+		}
+
+		item := map[string]interface{}{"status": status}
+		if status != http.StatusCreated {
+			hasErrors = true
+			item["error"] = map[string]string{"type": "rejected", "reason": message}
+		}
+		items = append(items, map[string]interface{}{"create": item})
+	}
+	server.requests = append(server.requests, documents)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"errors": hasErrors, "items": items})
+}
+
+func TestElasticsearchAppender(t *testing.T) {
+
+	stub := &bulkServer{rejected: map[string]bool{}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	config := NewDefaultElasticsearchAppenderConfig()
+	config.Endpoint = server.URL + "/"
+	config.Index = "logs-app"
+	config.ServiceName = "testService"
+	appender := NewElasticsearchAppender(config)
+
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.Info("first")
+	logger = logger.With(F("user", "alice"), F("count", 3))
+	logger.Warn("busy")
+	logger.Error("invalid")
+
+	// the invalid document is dropped and reported
+	assert.NotNil(t, appender.Flush())
+
+	stub.mu.Lock()
+	assert.Equal(t, 2, len(stub.requests))
+	assert.Equal(t, 3, len(stub.requests[0]))
+	assert.Equal(t, 1, len(stub.requests[1]))
+	assert.Equal(t, "busy", stub.requests[1][0]["message"])
+
+	today := "logs-app-" + time.Now().UTC().Format("2006.01.02")
+	assert.Equal(t, []string{today, today}, stub.indices)
+
+	document := stub.requests[0][1]
+	assert.Equal(t, "WARN", document["log.level"])
+	assert.Equal(t, "testLogger", document["log.logger"])
+	assert.Equal(t, "appender_elasticsearch_test.go", document["log.origin.file.name"])
+	assert.Equal(t, "testService", document["service.name"])
+	assert.Equal(t, ecsVersion, document["ecs.version"])
+	assert.Equal(t, map[string]interface{}{"user": "alice", "count": float64(3)}, document["fields"])
+	_, err := time.Parse(time.RFC3339, document["@timestamp"].(string))
+	assert.Nil(t, err)
+	stub.mu.Unlock()
+
+	assert.Nil(t, appender.Close())
+}
+
+func TestElasticsearchAppender_unencodable(t *testing.T) {
+
+	stub := &bulkServer{rejected: map[string]bool{}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	config := NewDefaultElasticsearchAppenderConfig()
+	config.Endpoint = server.URL
+	appender := NewElasticsearchAppender(config)
+	defer appender.Close()
+
+	// the event with a NaN field is dropped, the batch is sent
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	ratioLogger := logger.With(F("ratio", math.NaN()))
+	ratioLogger.Info("nan")
+	logger.Info("valid")
+	assert.Nil(t, appender.Flush())
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	assert.Equal(t, 1, len(stub.requests))
+	assert.Equal(t, 1, len(stub.requests[0]))
+	assert.Equal(t, "valid", stub.requests[0][0]["message"])
+}
+
+func TestElasticsearchAppender_ecsDocument(t *testing.T) {
+
+	appender := NewElasticsearchAppender(NewDefaultElasticsearchAppenderConfig())
+	defer appender.Close()
+
+	// json events are merged, the keys of JsonLogEvent are renamed
+	record := newEventRecord(LogLevel_ERROR, &JsonLogEvent{event: map[string]interface{}{"name": "value"}}, &LogEventMetadata{
+		TraceId: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanId:  "00f067aa0ba902b7",
+		Fields:  Fields{Err(WithStack(assert.AnError))},
+	})
+	document := appender.ecsDocument(record)
+	assert.Equal(t, "value", document["name"])
+	assert.Nil(t, document["message"])
+	assert.Equal(t, "ERROR", document["log.level"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", document["trace.id"])
+	assert.Equal(t, "00f067aa0ba902b7", document["span.id"])
+	assert.Equal(t, assert.AnError.Error(), document["error.message"])
+	assert.True(t, strings.HasPrefix(document["error.stack_trace"].(string), assert.AnError.Error()+"\n\t"))
+
+	// fields and json events can not overwrite the ECS fields
+	eventTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	record = newEventRecord(LogLevel_WARN, &JsonLogEvent{event: map[string]interface{}{"timestamp": "event", "@timestamp": "event"}}, &LogEventMetadata{
+		UnixNano: eventTime.UnixNano(),
+		Fields:   Fields{F("log.level", "DEBUG"), F("@timestamp", "field"), F("message", "field")},
+	})
+	document = appender.ecsDocument(record)
+	assert.Equal(t, "2024-01-02T03:04:05.000Z", document["@timestamp"])
+	assert.Equal(t, "WARN", document["log.level"])
+	assert.Equal(t, "event", document["timestamp"])
+	assert.Equal(t, map[string]interface{}{"log.level": "DEBUG", "@timestamp": "field", "message": "field"}, document["fields"])
+
+	// raw json written with metadata
+	document = appender.ecsDocument(newRawEventRecord([]byte(`{"logLevel":"[WARN]","timestamp":"2024-01-02T03:04:05Z","loggerName":"raw"}`)))
+	assert.Equal(t, "WARN", document["log.level"])
+	assert.Equal(t, "2024-01-02T03:04:05Z", document["@timestamp"])
+	assert.Equal(t, "raw", document["log.logger"])
+
+	assert.Equal(t, "logs-2026.10.18", appender.index(time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)))
+}
@@ -81,6 +81,16 @@ func (appender *FileAppender) Write(data []byte) (n int, err error) {
 	return 0, fmt.Errorf("appender is closed")
 }
 
+// Flush writes the buffered events to the file
+func (appender *FileAppender) Flush() error {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	if appender.activated {
+		return appender.bufferedWriter.Flush()
+	}
+	return nil
+}
+
 // Close implements io.Closer
 func (appender *FileAppender) Close() error {
 	appender.mu.Lock()
@@ -9,11 +9,24 @@ import (
 )
 
 // RotatableFileAppender RotatableFileAppender struct
+// The file is reopened on SIGHUP until the appender is closed.
 type RotatableFileAppender struct {
 	*FileAppender
-	mu *sync.Mutex
+	mu            *sync.Mutex
+	fileName      string
+	bufferSize    int
+	flushInterval time.Duration
+	closed        bool
 }
 
+// rotatableFileAppenders are the open appenders reopened on SIGHUP.
+// SIGHUP is watched by one goroutine, and stays handled after the appenders are closed so that it does not terminate the process.
+var rotatableFileAppenders = map[*RotatableFileAppender]struct{}{}
+
+var rotatableFileAppendersMu = new(sync.Mutex)
+
+var watchHupOnce = new(sync.Once)
+
 // NewRotatableFileAppender returns new FileAppender
 func NewRotatableFileAppender(fileName string) (asyncFileAppender *RotatableFileAppender, err error) {
 	return NewRotatableFileAppenderWithBufferSize(fileName, defaultBufferSize)
@@ -33,33 +46,61 @@ func NewRotatableFileAppenderWithBufferSizeAndFlushInterval(fileName string, buf
 	}
 
 	appender := &RotatableFileAppender{
-		FileAppender: fileAppender,
-		mu:           new(sync.Mutex),
+		FileAppender:  fileAppender,
+		mu:            new(sync.Mutex),
+		fileName:      fileName,
+		bufferSize:    bufferSize,
+		flushInterval: flushInterval,
 	}
 
-	hup := make(chan os.Signal, 1)
-	signal.Notify(hup, syscall.SIGHUP)
-
-	go func() {
-		for {
-			<-hup
-			appender.mu.Lock()
-
-			appender.FileAppender.Close()
+	watchHupOnce.Do(func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				rotatableFileAppendersMu.Lock()
+				appenders := make([]*RotatableFileAppender, 0, len(rotatableFileAppenders))
+				for appender := range rotatableFileAppenders {
+					appenders = append(appenders, appender)
+				}
+				rotatableFileAppendersMu.Unlock()
 
-			newAppender, err := NewFileAppenderWithBufferSizeAndFlushInterval(fileName, bufferSize, flushInterval)
-			if err != nil {
-				panic(err)
+				for _, appender := range appenders {
+					if err := appender.reopen(); err != nil {
+						warnLogger.Warnf("reopen file appender is failed , error : %s", err.Error())
+					}
+				}
 			}
-			appender.FileAppender = newAppender
+		}()
+	})
 
-			appender.mu.Unlock()
-		}
-	}()
+	rotatableFileAppendersMu.Lock()
+	rotatableFileAppenders[appender] = struct{}{}
+	rotatableFileAppendersMu.Unlock()
 
 	return appender, nil
 }
 
+// reopen opens the file name again and closes the old file.
+// The old file is kept to write if the file name can not be opened.
+func (appender *RotatableFileAppender) reopen() error {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	if appender.closed {
+		return nil
+	}
+
+	newAppender, err := NewFileAppenderWithBufferSizeAndFlushInterval(appender.fileName, appender.bufferSize, appender.flushInterval)
+	if err != nil {
+		return err
+	}
+
+	appender.FileAppender.Close()
+	appender.FileAppender = newAppender
+	return nil
+}
+
 // Write implements io.Write
 func (appender *RotatableFileAppender) Write(data []byte) (n int, err error) {
 	appender.mu.Lock()
@@ -67,9 +108,21 @@ func (appender *RotatableFileAppender) Write(data []byte) (n int, err error) {
 	return appender.FileAppender.Write(data)
 }
 
+// Flush writes the buffered events to the file
+func (appender *RotatableFileAppender) Flush() error {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	return appender.FileAppender.Flush()
+}
+
 // Close implements io.Closer
 func (appender *RotatableFileAppender) Close() error {
+	rotatableFileAppendersMu.Lock()
+	delete(rotatableFileAppenders, appender)
+	rotatableFileAppendersMu.Unlock()
+
 	appender.mu.Lock()
 	defer appender.mu.Unlock()
+	appender.closed = true
 	return appender.FileAppender.Close()
 }
@@ -0,0 +1,54 @@
+//go:build unix
+
+package golog
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatableFileAppender_Close(t *testing.T) {
+
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "appender_file_rotatable")
+
+	appender, err := NewRotatableFileAppender(fileName)
+	assert.Nil(t, err)
+	appender.Write([]byte("test1"))
+	appender.Close()
+
+	// a closed appender is not reopened
+	os.Rename(fileName, filepath.Join(dir, "appender_file_rotatable_bk"))
+	syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+	time.Sleep(1 * time.Second)
+
+	_, err = os.Stat(fileName)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRotatableFileAppender_reopen(t *testing.T) {
+
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "logs", "appender_file_rotatable")
+	assert.Nil(t, os.Mkdir(filepath.Dir(fileName), 0777))
+
+	appender, err := NewRotatableFileAppender(fileName)
+	assert.Nil(t, err)
+	defer appender.Close()
+	appender.Write([]byte("test1"))
+
+	// the old file is kept if the file name can not be opened
+	assert.Nil(t, os.Rename(filepath.Dir(fileName), filepath.Join(dir, "logs_bk")))
+	assert.NotNil(t, appender.reopen())
+
+	appender.Write([]byte("test2"))
+	assert.Nil(t, appender.Flush())
+	actual, err := os.ReadFile(filepath.Join(dir, "logs_bk", "appender_file_rotatable"))
+	assert.Nil(t, err)
+	assert.Equal(t, "test1\ntest2\n", string(actual))
+}
@@ -0,0 +1,232 @@
+package golog
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// defaultFlightRecorderSize is the number of events kept per key
+const defaultFlightRecorderSize = 256
+
+// defaultFlightRecorderMaxKeys is the number of keys kept at once
+const defaultFlightRecorderMaxKeys = 1024
+
+// FlightRecorderKeyFunc returns the key which groups recorded events
+type FlightRecorderKeyFunc = func(metadata *LogEventMetadata) string
+
+// KeyByLoggerName groups recorded events per logger
+func KeyByLoggerName(metadata *LogEventMetadata) string {
+	if metadata == nil {
+		return ""
+	}
+	return metadata.LoggerName
+}
+
+// KeyByField groups recorded events by the value of the given field, e.g. a request id
+func KeyByField(key string) FlightRecorderKeyFunc {
+	return func(metadata *LogEventMetadata) string {
+		if metadata == nil {
+			return ""
+		}
+		if value, ok := metadata.Fields.Get(key); ok {
+			return formatFieldValue(value)
+		}
+		return ""
+	}
+}
+
+// recordedEvent
+type recordedEvent struct {
+	level    LogLevel
+	logEvent LogEvent
+	metadata *LogEventMetadata
+}
+
+// snapshotLogEvent formats the arguments of the event, so that later changes of them are not dumped
+func snapshotLogEvent(logEvent LogEvent) LogEvent {
+	switch event := logEvent.(type) {
+	case *FormatLogEvent:
+		return &TextLogEvent{Event: fmt.Sprintf(event.format, event.args...)}
+	case *JsonLogEvent:
+		encoded, err := json.Marshal(event.event)
+		if err != nil {
+			return event
+		}
+		return &JsonLogEvent{event: json.RawMessage(encoded)}
+	}
+	return logEvent
+}
+
+// eventRing is a bounded ring of recorded events, it grows up to size as events are recorded
+type eventRing struct {
+	key    string
+	size   int
+	events []recordedEvent
+	next   int
+}
+
+// push appends the event, overwriting the oldest one if the ring is full
+func (ring *eventRing) push(event recordedEvent) {
+	if len(ring.events) < ring.size {
+		ring.events = append(ring.events, event)
+		return
+	}
+	ring.events[ring.next] = event
+	ring.next = (ring.next + 1) % ring.size
+}
+
+// drain returns recorded events in order and empties the ring
+func (ring *eventRing) drain() []recordedEvent {
+	events := make([]recordedEvent, 0, len(ring.events))
+	events = append(events, ring.events[ring.next:]...)
+	events = append(events, ring.events[:ring.next]...)
+
+	ring.events = nil
+	ring.next = 0
+	return events
+}
+
+// FlightRecorderAppender keeps recent low level events in memory and
+// forwards them to the target appender only when a trigger event arrives.
+//
+// Events below thresholdLevel are recorded in a bounded ring per key and dropped as they age out.
+// Events at or above thresholdLevel are forwarded immediately.
+// Events at or above triggerLevel first dump the recorded events of their key, oldest first.
+//
+// The logger must enable the recorded levels, e.g. run the logger at DEBUG and
+// set thresholdLevel to INFO to get the DEBUG context of an ERROR.
+type FlightRecorderAppender struct {
+	target         Appender
+	thresholdLevel LogLevel
+	triggerLevel   LogLevel
+	size           int
+	maxKeys        int
+	keyFunc        FlightRecorderKeyFunc
+	mu             *sync.Mutex
+	rings          map[string]*list.Element
+	lru            *list.List
+}
+
+// NewFlightRecorderAppender returns new FlightRecorderAppender which records events per logger
+func NewFlightRecorderAppender(target Appender, thresholdLevel LogLevel, triggerLevel LogLevel) *FlightRecorderAppender {
+	return NewFlightRecorderAppenderWithKey(target, thresholdLevel, triggerLevel, defaultFlightRecorderSize, KeyByLoggerName)
+}
+
+// NewFlightRecorderAppenderWithKey returns new FlightRecorderAppender
+// which records up to size events per key returned by keyFunc
+func NewFlightRecorderAppenderWithKey(target Appender, thresholdLevel LogLevel, triggerLevel LogLevel, size int, keyFunc FlightRecorderKeyFunc) *FlightRecorderAppender {
+	if size <= 0 {
+		size = defaultFlightRecorderSize
+	}
+
+	if keyFunc == nil {
+		keyFunc = KeyByLoggerName
+	}
+
+	return &FlightRecorderAppender{
+		target:         target,
+		thresholdLevel: thresholdLevel,
+		triggerLevel:   triggerLevel,
+		size:           size,
+		maxKeys:        defaultFlightRecorderMaxKeys,
+		keyFunc:        keyFunc,
+		mu:             new(sync.Mutex),
+		rings:          map[string]*list.Element{},
+		lru:            list.New(),
+	}
+}
+
+// AppendEvent implements EventAppender
+func (appender *FlightRecorderAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	if level >= appender.thresholdLevel {
+		if level >= appender.triggerLevel {
+			if err := appender.dump(appender.keyFunc(metadata)); err != nil {
+				return err
+			}
+		}
+		return appendEvent(appender.target, level, logEvent, metadata)
+	}
+
+	// the metadata is owned by the caller, keep a copy
+	var recorded *LogEventMetadata
+	if metadata != nil {
+		copied := *metadata
+		recorded = &copied
+	}
+
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	appender.ring(appender.keyFunc(metadata)).push(recordedEvent{
+		level:    level,
+		logEvent: snapshotLogEvent(logEvent),
+		metadata: recorded,
+	})
+	return nil
+}
+
+// ring returns the ring of the key, creating it and evicting the least recently used one if necessary
+func (appender *FlightRecorderAppender) ring(key string) *eventRing {
+	if element, ok := appender.rings[key]; ok {
+		appender.lru.MoveToFront(element)
+		return element.Value.(*eventRing)
+	}
+
+	if appender.lru.Len() >= appender.maxKeys {
+		oldest := appender.lru.Back()
+		appender.lru.Remove(oldest)
+		delete(appender.rings, oldest.Value.(*eventRing).key)
+	}
+
+	ring := &eventRing{key: key, size: appender.size}
+	appender.rings[key] = appender.lru.PushFront(ring)
+	return ring
+}
+
+// dump forwards the recorded events of the key to the target and forgets them
+func (appender *FlightRecorderAppender) dump(key string) error {
+	appender.mu.Lock()
+	element, ok := appender.rings[key]
+	if !ok {
+		appender.mu.Unlock()
+		return nil
+	}
+	appender.lru.Remove(element)
+	delete(appender.rings, key)
+	events := element.Value.(*eventRing).drain()
+	appender.mu.Unlock()
+
+	for _, event := range events {
+		if err := appendEvent(appender.target, event.level, event.logEvent, event.metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write implements io.Writer
+// Encoded events carry no level, so they are forwarded to the target as is.
+func (appender *FlightRecorderAppender) Write(data []byte) (n int, err error) {
+	return appender.target.Write(data)
+}
+
+// Flush flushes the target if it buffers events.
+// Recorded events are kept until a trigger event arrives.
+func (appender *FlightRecorderAppender) Flush() error {
+	if target, ok := appender.target.(Syncer); ok {
+		return target.Flush()
+	}
+	return nil
+}
+
+// Close implements io.Closer
+// Recorded events are dropped and the target is closed.
+func (appender *FlightRecorderAppender) Close() error {
+	appender.mu.Lock()
+	appender.rings = map[string]*list.Element{}
+	appender.lru.Init()
+	appender.mu.Unlock()
+
+	return appender.target.Close()
+}
@@ -0,0 +1,73 @@
+package golog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlightRecorderAppender_AppendEvent(t *testing.T) {
+
+	// debug events are recorded, info events are forwarded
+	func() {
+		buffer := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, NewFlightRecorderAppender(buffer, LogLevel_INFO, LogLevel_ERROR))
+		logger.DisableLogEventMetadata()
+		logger.Debug("debug1")
+		logger.Debug("debug2")
+		logger.Info("info")
+		assert.Equal(t, "info\n", buffer.String())
+	}()
+
+	// debug events are dumped before the error
+	func() {
+		buffer := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, NewFlightRecorderAppender(buffer, LogLevel_INFO, LogLevel_ERROR))
+		logger.SetMetadataConfig(&MetadataConfig{})
+		logger.Debug("debug1")
+		logger.Info("info")
+		logger.Debug("debug2")
+		logger.Error("error")
+		logger.Error("error2")
+		assert.Equal(t, "   () info\n   () debug1\n   () debug2\n   () error\n   () error2\n", buffer.String())
+	}()
+
+	// old events age out
+	func() {
+		buffer := newBufferAppender()
+		appender := NewFlightRecorderAppenderWithKey(buffer, LogLevel_INFO, LogLevel_ERROR, 2, KeyByLoggerName)
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		logger.Debug("debug1")
+		logger.Debug("debug2")
+		logger.Debug("debug3")
+		logger.Error("error")
+		assert.Equal(t, "   () debug2\n   () debug3\n   () error\n", buffer.String())
+	}()
+
+	// events are grouped per field
+	func() {
+		buffer := newBufferAppender()
+		appender := NewFlightRecorderAppenderWithKey(buffer, LogLevel_INFO, LogLevel_ERROR, 10, KeyByField("request_id"))
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		request1 := logger.With(F("request_id", 1))
+		request2 := logger.With(F("request_id", 2))
+		request1.Debug("debug1")
+		request2.Debug("debug2")
+		request2.Error("error")
+		assert.Equal(t, "   () debug2 request_id=2\n   () error request_id=2\n", buffer.String())
+	}()
+
+	// arguments are formatted when the event is recorded
+	func() {
+		buffer := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, NewFlightRecorderAppender(buffer, LogLevel_INFO, LogLevel_ERROR))
+		logger.SetMetadataConfig(&MetadataConfig{})
+		values := []string{"before"}
+		logger.Debugf("debug %v", values)
+		values[0] = "after"
+		logger.Error("error")
+		assert.Equal(t, "   () debug [before]\n   () error\n", buffer.String())
+	}()
+}
@@ -0,0 +1,334 @@
+package golog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GelfCompression is the compression of UDP messages, TCP messages are not compressed
+type GelfCompression string
+
+const GelfCompression_NONE GelfCompression = "none"
+const GelfCompression_GZIP GelfCompression = "gzip"
+const GelfCompression_ZLIB GelfCompression = "zlib"
+
+// defaultGelfChunkSize fits a datagram into the MTU of most networks
+const defaultGelfChunkSize = 1420
+
+const defaultGelfTimeout = time.Second * 5
+
+// gelfChunkHeaderSize is the magic bytes, the message id, the sequence number and the sequence count
+const gelfChunkHeaderSize = 12
+
+// gelfMaxChunks is the maximum number of chunks of a message
+const gelfMaxChunks = 128
+
+// GelfAppenderConfig
+type GelfAppenderConfig struct {
+	// Network is udp or tcp
+	Network string
+	Address string
+
+	Compression GelfCompression
+
+	// ChunkSize is the maximum size of a datagram, larger messages are chunked
+	ChunkSize int
+
+	// Host defaults to the host name
+	Host string
+
+	// AdditionalFields are added to every message, without the _ prefix
+	AdditionalFields map[string]string
+
+	// Timeout bounds connecting and writing
+	Timeout time.Duration
+}
+
+// NewDefaultGelfAppenderConfig
+func NewDefaultGelfAppenderConfig() GelfAppenderConfig {
+	return GelfAppenderConfig{
+		Network:     "udp",
+		Address:     "localhost:12201",
+		Compression: GelfCompression_GZIP,
+		ChunkSize:   defaultGelfChunkSize,
+		Timeout:     defaultGelfTimeout,
+	}
+}
+
+// GelfAppender sends events to Graylog as GELF 1.1 messages.
+// The first line of the message is the short_message, the full_message holds the whole
+// message and the stack traces. Metadata and fields are sent as additional fields.
+// Over udp messages are compressed and chunked, over tcp they are terminated by a null byte.
+type GelfAppender struct {
+	config GelfAppenderConfig
+	mu     *sync.Mutex
+	conn   net.Conn
+}
+
+// NewGelfAppender returns new GelfAppender
+func NewGelfAppender(config GelfAppenderConfig) *GelfAppender {
+	if config.Network == "" {
+		config.Network = "udp"
+	}
+
+	if config.Compression == "" {
+		config.Compression = GelfCompression_GZIP
+	}
+
+	if config.ChunkSize <= gelfChunkHeaderSize {
+		config.ChunkSize = defaultGelfChunkSize
+	}
+
+	if config.Host == "" {
+		config.Host, _ = os.Hostname()
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultGelfTimeout
+	}
+
+	return &GelfAppender{
+		config: config,
+		mu:     new(sync.Mutex),
+	}
+}
+
+// AppendEvent implements EventAppender
+func (appender *GelfAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	message, err := appender.encode(newEventRecord(level, logEvent, metadata))
+	if err != nil {
+		return err
+	}
+	return appender.send(message)
+}
+
+// Write implements io.Writer
+// Data is sent as the message of an INFO event.
+func (appender *GelfAppender) Write(data []byte) (n int, err error) {
+	message, err := appender.encode(newRawEventRecord(data))
+	if err != nil {
+		return 0, err
+	}
+	if err := appender.send(message); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Close implements io.Closer
+func (appender *GelfAppender) Close() error {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	if appender.conn == nil {
+		return nil
+	}
+	err := appender.conn.Close()
+	appender.conn = nil
+	return err
+}
+
+// encode returns the GELF message of the record
+func (appender *GelfAppender) encode(record eventRecord) ([]byte, error) {
+	metadata := record.metadata
+	message := strings.TrimRight(record.message, "\n")
+
+	shortMessage := message
+	if i := strings.IndexByte(shortMessage, '\n'); i >= 0 {
+		shortMessage = shortMessage[:i]
+	}
+	if shortMessage == "" {
+		shortMessage = "-"
+	}
+
+	fullMessage := message
+	if len(metadata.StackTrace) > 0 {
+		fullMessage += "\n" + metadata.StackTrace.String()
+	}
+
+	gelf := map[string]interface{}{
+		"version":       "1.1",
+		"host":          appender.config.Host,
+		"short_message": shortMessage,
+		"timestamp":     float64(record.time.UnixNano()/int64(time.Millisecond)) / 1000,
+		"level":         SyslogSeverity(record.level),
+	}
+
+	for key, value := range appender.config.AdditionalFields {
+		gelf[gelfFieldName(key)] = value
+	}
+
+	if metadata.LoggerName != "" {
+		gelf["_logger_name"] = metadata.LoggerName
+	}
+	if metadata.SourceFile != "" {
+		gelf["_file"] = metadata.SourceFile
+		gelf["_line"] = metadata.SourceLine
+	}
+	if metadata.TraceId != "" {
+		gelf["_trace_id"] = metadata.TraceId
+		gelf["_span_id"] = metadata.SpanId
+	}
+
+	for _, field := range metadata.Fields {
+		if detail, ok := field.Value.(ErrorDetail); ok {
+			gelf[gelfFieldName(field.Key)] = detail.Message
+			fullMessage += detail.stackTraceBlock()
+			continue
+		}
+
+		value := otlpAttributeValue(field.Value)
+		if b, ok := value.(bool); ok {
+			// GELF values are strings or numbers
+			value = formatFieldValue(b)
+		}
+		gelf[gelfFieldName(field.Key)] = value
+	}
+
+	if fullMessage != shortMessage {
+		gelf["full_message"] = fullMessage
+	}
+
+	return json.Marshal(gelf)
+}
+
+// gelfReservedFieldNames are the names of the metadata fields and _id, which is reserved by GELF
+var gelfReservedFieldNames = map[string]bool{
+	"id":          true,
+	"logger_name": true,
+	"file":        true,
+	"line":        true,
+	"trace_id":    true,
+	"span_id":     true,
+}
+
+// gelfFieldName returns the key as an additional field name, which is _ followed by word characters, '.' or '-'.
+// Reserved names are followed by _ so that fields can not overwrite the metadata.
+func gelfFieldName(key string) string {
+	name := []byte(key)
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+			name[i] = '_'
+		}
+	}
+
+	if gelfReservedFieldNames[string(name)] {
+		return "_" + string(name) + "_"
+	}
+	return "_" + string(name)
+}
+
+// send writes the message, reconnecting once if the write fails
+func (appender *GelfAppender) send(message []byte) error {
+	stream := isStreamNetwork(appender.config.Network)
+
+	var datagrams [][]byte
+	if stream {
+		datagrams = [][]byte{append(message, 0)}
+	} else {
+		compressed, err := appender.compress(message)
+		if err != nil {
+			return err
+		}
+		if datagrams, err = appender.chunk(compressed); err != nil {
+			return err
+		}
+	}
+
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if appender.conn == nil {
+			appender.conn, err = net.DialTimeout(appender.config.Network, appender.config.Address, appender.config.Timeout)
+			if err != nil {
+				appender.conn = nil
+				continue
+			}
+		}
+
+		appender.conn.SetWriteDeadline(time.Now().Add(appender.config.Timeout))
+		for _, datagram := range datagrams {
+			if _, err = appender.conn.Write(datagram); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		appender.conn.Close()
+		appender.conn = nil
+
+		// a datagram message may have been partially sent, do not resend
+		if !stream {
+			return err
+		}
+	}
+	return err
+}
+
+// compress
+func (appender *GelfAppender) compress(message []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	switch appender.config.Compression {
+	case GelfCompression_GZIP:
+		writer := gzip.NewWriter(&buffer)
+		writer.Write(message)
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+	case GelfCompression_ZLIB:
+		writer := zlib.NewWriter(&buffer)
+		writer.Write(message)
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return message, nil
+	}
+	return buffer.Bytes(), nil
+}
+
+// chunk splits the message into GELF chunks if it does not fit into a datagram
+func (appender *GelfAppender) chunk(message []byte) ([][]byte, error) {
+	if len(message) <= appender.config.ChunkSize {
+		return [][]byte{message}, nil
+	}
+
+	dataSize := appender.config.ChunkSize - gelfChunkHeaderSize
+	count := (len(message) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return nil, errors.New("gelf message is too large")
+	}
+
+	messageId := make([]byte, 8)
+	if _, err := rand.Read(messageId); err != nil {
+		return nil, err
+	}
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * dataSize
+		if end > len(message) {
+			end = len(message)
+		}
+
+		chunk := make([]byte, 0, gelfChunkHeaderSize+end-i*dataSize)
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, messageId...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, message[i*dataSize:end]...)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
@@ -0,0 +1,198 @@
+package golog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readGelfDatagrams reads count datagrams
+func readGelfDatagrams(t *testing.T, conn net.PacketConn, count int) [][]byte {
+	var datagrams [][]byte
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	for i := 0; i < count; i++ {
+		buffer := make([]byte, 65536)
+		n, _, err := conn.ReadFrom(buffer)
+		assert.Nil(t, err)
+		datagrams = append(datagrams, buffer[:n])
+	}
+	return datagrams
+}
+
+func TestGelfAppender_encode(t *testing.T) {
+
+	config := NewDefaultGelfAppenderConfig()
+	config.Host = "host"
+	config.AdditionalFields = map[string]string{"env": "test"}
+	appender := NewGelfAppender(config)
+
+	detail := NewErrorDetail(WithStack(errors.New("broken")))
+	message, err := appender.encode(eventRecord{
+		level:   LogLevel_ERROR,
+		message: "failed\nsecond line",
+		time:    time.Unix(1704164645, 123456789),
+		metadata: LogEventMetadata{
+			LoggerName: "gelfLogger",
+			SourceFile: "main.go",
+			SourceLine: 10,
+			TraceId:    "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanId:     "00f067aa0ba902b7",
+			Fields:     Fields{F("id", 1), F("file", "field.go"), F("logger_name", "field"), F("user name", "bob"), F("ok", true), F("error", detail)},
+		},
+	})
+	assert.Nil(t, err)
+
+	var gelf map[string]interface{}
+	assert.Nil(t, json.Unmarshal(message, &gelf))
+	assert.Equal(t, "1.1", gelf["version"])
+	assert.Equal(t, "host", gelf["host"])
+	assert.Equal(t, "failed", gelf["short_message"])
+	assert.True(t, strings.HasPrefix(gelf["full_message"].(string), "failed\nsecond line\nbroken\n\t"))
+	assert.Equal(t, 1704164645.123, gelf["timestamp"])
+	assert.Equal(t, float64(3), gelf["level"])
+	assert.Equal(t, "test", gelf["_env"])
+	assert.Equal(t, "gelfLogger", gelf["_logger_name"])
+	assert.Equal(t, "main.go", gelf["_file"])
+	assert.Equal(t, float64(10), gelf["_line"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", gelf["_trace_id"])
+	assert.Equal(t, float64(1), gelf["_id_"])
+	assert.Equal(t, "field.go", gelf["_file_"])
+	assert.Equal(t, "field", gelf["_logger_name_"])
+	assert.Equal(t, "bob", gelf["_user_name"])
+	assert.Equal(t, "true", gelf["_ok"])
+	assert.Equal(t, "broken", gelf["_error"])
+
+	// single line without stack trace
+	message, err = appender.encode(newRawEventRecord([]byte("raw\n")))
+	assert.Nil(t, err)
+	gelf = map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(message, &gelf))
+	assert.Equal(t, "raw", gelf["short_message"])
+	assert.Equal(t, float64(6), gelf["level"])
+	assert.Nil(t, gelf["full_message"])
+}
+
+func TestGelfAppender_AppendEvent(t *testing.T) {
+
+	// udp with gzip
+	func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		config := NewDefaultGelfAppenderConfig()
+		config.Address = conn.LocalAddr().String()
+		appender := NewGelfAppender(config)
+		defer appender.Close()
+		logger := NewLogger("gelfLogger", LogLevel_TRACE, appender)
+		logger.Warn("message")
+
+		reader, err := gzip.NewReader(bytes.NewReader(readGelfDatagrams(t, conn, 1)[0]))
+		assert.Nil(t, err)
+		var gelf map[string]interface{}
+		assert.Nil(t, json.NewDecoder(reader).Decode(&gelf))
+		assert.Equal(t, "message", gelf["short_message"])
+		assert.Equal(t, float64(4), gelf["level"])
+	}()
+
+	// udp with zlib, chunked
+	func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		config := NewDefaultGelfAppenderConfig()
+		config.Address = conn.LocalAddr().String()
+		config.Compression = GelfCompression_ZLIB
+		config.ChunkSize = 100
+		appender := NewGelfAppender(config)
+		defer appender.Close()
+
+		// random letters compress to more than a chunk
+		random := rand.New(rand.NewSource(1))
+		long := make([]byte, 1000)
+		for i := range long {
+			long[i] = 'a' + byte(random.Intn(26))
+		}
+		expected, err := appender.encode(newRawEventRecord(long))
+		assert.Nil(t, err)
+		compressed, err := appender.compress(expected)
+		assert.Nil(t, err)
+		count := (len(compressed) + 87) / 88
+		assert.True(t, count > 1)
+
+		_, err = appender.Write(long)
+		assert.Nil(t, err)
+
+		chunks := readGelfDatagrams(t, conn, count)
+		assembled := make([][]byte, count)
+		for _, chunk := range chunks {
+			assert.True(t, len(chunk) <= 100)
+			assert.Equal(t, []byte{0x1e, 0x0f}, chunk[:2])
+			assert.Equal(t, chunks[0][2:10], chunk[2:10])
+			assert.Equal(t, byte(count), chunk[11])
+			assembled[chunk[10]] = chunk[12:]
+		}
+
+		reader, err := zlib.NewReader(bytes.NewReader(bytes.Join(assembled, nil)))
+		assert.Nil(t, err)
+		message, err := io.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, string(long), func() string {
+			var gelf map[string]interface{}
+			json.Unmarshal(message, &gelf)
+			return gelf["short_message"].(string)
+		}())
+	}()
+
+	// tcp with null byte framing
+	func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer listener.Close()
+
+		config := NewDefaultGelfAppenderConfig()
+		config.Network = "tcp"
+		config.Address = listener.Addr().String()
+		appender := NewGelfAppender(config)
+		defer appender.Close()
+		logger := NewLogger("gelfLogger", LogLevel_TRACE, appender)
+		logger.Info("first")
+		logger.Info("second")
+
+		conn, err := listener.Accept()
+		assert.Nil(t, err)
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		reader := bufio.NewReader(conn)
+
+		for _, expected := range []string{"first", "second"} {
+			message, err := reader.ReadBytes(0)
+			assert.Nil(t, err)
+			var gelf map[string]interface{}
+			assert.Nil(t, json.Unmarshal(message[:len(message)-1], &gelf))
+			assert.Equal(t, expected, gelf["short_message"])
+		}
+	}()
+
+	// too many chunks
+	func() {
+		config := NewDefaultGelfAppenderConfig()
+		config.Compression = GelfCompression_NONE
+		config.ChunkSize = 13
+		appender := NewGelfAppender(config)
+		_, err := appender.chunk(make([]byte, 129))
+		assert.NotNil(t, err)
+	}()
+}
@@ -0,0 +1,187 @@
+package golog
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultJournaldSocket = "/run/systemd/journal/socket"
+
+// journaldMaxFieldName is the maximum length of a field name accepted by journald
+const journaldMaxFieldName = 64
+
+// JournaldAppenderConfig
+type JournaldAppenderConfig struct {
+	// SocketPath defaults to /run/systemd/journal/socket
+	SocketPath string
+
+	// SyslogIdentifier is used for events without a logger name, defaults to the program name
+	SyslogIdentifier string
+
+	// Fields are added to every entry, their names are converted like the fields of events
+	Fields map[string]string
+}
+
+// NewDefaultJournaldAppenderConfig
+func NewDefaultJournaldAppenderConfig() JournaldAppenderConfig {
+	return JournaldAppenderConfig{
+		SocketPath:       defaultJournaldSocket,
+		SyslogIdentifier: filepath.Base(os.Args[0]),
+	}
+}
+
+// JournaldAppender sends events to systemd-journald in its native protocol.
+// PRIORITY is mapped from LogLevel like the syslog severity, SYSLOG_IDENTIFIER is the logger name
+// and CODE_FILE and CODE_LINE are the source of the event. Fields are sent as entry fields with uppercase names.
+// Entries too large for a datagram are passed through a sealed memfd on Linux.
+type JournaldAppender struct {
+	config JournaldAppenderConfig
+	mu     *sync.Mutex
+	conn   *net.UnixConn
+}
+
+// NewJournaldAppender returns new JournaldAppender
+func NewJournaldAppender(config JournaldAppenderConfig) *JournaldAppender {
+	if config.SocketPath == "" {
+		config.SocketPath = defaultJournaldSocket
+	}
+
+	if config.SyslogIdentifier == "" {
+		config.SyslogIdentifier = filepath.Base(os.Args[0])
+	}
+
+	return &JournaldAppender{
+		config: config,
+		mu:     new(sync.Mutex),
+	}
+}
+
+// AppendEvent implements EventAppender
+func (appender *JournaldAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	return appender.send(appender.encode(newEventRecord(level, logEvent, metadata)))
+}
+
+// Write implements io.Writer
+// Data is sent as the message of an INFO event.
+func (appender *JournaldAppender) Write(data []byte) (n int, err error) {
+	if err := appender.send(appender.encode(newRawEventRecord(data))); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Close implements io.Closer
+func (appender *JournaldAppender) Close() error {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	if appender.conn == nil {
+		return nil
+	}
+	err := appender.conn.Close()
+	appender.conn = nil
+	return err
+}
+
+// send writes the entry as a datagram, through a file descriptor if it is too large.
+// The socket is connected again once if the write fails.
+func (appender *JournaldAppender) send(entry []byte) error {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if appender.conn == nil {
+			appender.conn, err = net.DialUnix("unixgram", nil, &net.UnixAddr{Name: appender.config.SocketPath, Net: "unixgram"})
+			if err != nil {
+				continue
+			}
+		}
+
+		if _, err = appender.conn.Write(entry); err == nil {
+			return nil
+		}
+		if isJournaldEntryTooLarge(err) {
+			return sendJournaldEntryFd(appender.conn, entry)
+		}
+		appender.conn.Close()
+		appender.conn = nil
+	}
+	return err
+}
+
+// encode returns the entry of the record in the native protocol
+func (appender *JournaldAppender) encode(record eventRecord) []byte {
+	metadata := record.metadata
+
+	message := strings.TrimRight(record.message, "\n")
+	if len(metadata.StackTrace) > 0 {
+		message += "\n" + metadata.StackTrace.String()
+	}
+
+	identifier := metadata.LoggerName
+	if identifier == "" {
+		identifier = appender.config.SyslogIdentifier
+	}
+
+	var entry []byte
+	entry = appendJournaldField(entry, "MESSAGE", message)
+	entry = appendJournaldField(entry, "PRIORITY", strconv.Itoa(SyslogSeverity(record.level)))
+	entry = appendJournaldField(entry, "SYSLOG_IDENTIFIER", identifier)
+	if metadata.SourceFile != "" {
+		entry = appendJournaldField(entry, "CODE_FILE", metadata.SourceFile)
+	}
+	if metadata.SourceLine > 0 {
+		entry = appendJournaldField(entry, "CODE_LINE", strconv.Itoa(metadata.SourceLine))
+	}
+	if metadata.TraceId != "" {
+		entry = appendJournaldField(entry, "TRACE_ID", metadata.TraceId)
+		entry = appendJournaldField(entry, "SPAN_ID", metadata.SpanId)
+	}
+	for name, value := range appender.config.Fields {
+		entry = appendJournaldField(entry, journaldFieldName(name), value)
+	}
+	for _, field := range metadata.Fields {
+		entry = appendJournaldField(entry, journaldFieldName(field.Key), formatLabelValue(field.Value))
+	}
+	return entry
+}
+
+// appendJournaldField appends NAME=value, or the name and the value with its length if the value has a newline
+func appendJournaldField(entry []byte, name string, value string) []byte {
+	if !strings.Contains(value, "\n") {
+		entry = append(entry, name...)
+		entry = append(entry, '=')
+		entry = append(entry, value...)
+		return append(entry, '\n')
+	}
+
+	entry = append(entry, name...)
+	entry = append(entry, '\n')
+	entry = binary.LittleEndian.AppendUint64(entry, uint64(len(value)))
+	entry = append(entry, value...)
+	return append(entry, '\n')
+}
+
+// journaldFieldName returns the key as a field name of uppercase letters, digits and underscores.
+// Names must not start with an underscore or a digit, those are prefixed with F.
+func journaldFieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			name[i] = '_'
+		}
+	}
+	if len(name) == 0 || name[0] == '_' || name[0] >= '0' && name[0] <= '9' {
+		name = append([]byte("F"), name...)
+	}
+	if len(name) > journaldMaxFieldName {
+		name = name[:journaldMaxFieldName]
+	}
+	return string(name)
+}
@@ -0,0 +1,142 @@
+//go:build linux
+
+package golog
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// parseJournaldEntry parses an entry of the native protocol
+func parseJournaldEntry(t *testing.T, data []byte) map[string]string {
+	fields := map[string]string{}
+	for len(data) > 0 {
+		line := data[:strings.IndexByte(string(data), '\n')]
+		if name, value, ok := strings.Cut(string(line), "="); ok {
+			fields[name] = value
+			data = data[len(line)+1:]
+			continue
+		}
+
+		data = data[len(line)+1:]
+		size := binary.LittleEndian.Uint64(data)
+		fields[string(line)] = string(data[8 : 8+size])
+		assert.Equal(t, byte('\n'), data[8+size])
+		data = data[8+size+1:]
+	}
+	return fields
+}
+
+// listenJournald binds a local journal socket
+func listenJournald(t *testing.T) (*net.UnixConn, string) {
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	assert.Nil(t, err)
+	return conn, path
+}
+
+// receiveJournaldEntry returns the entry of a datagram, reading the file if a descriptor is passed
+func receiveJournaldEntry(t *testing.T, conn *net.UnixConn) ([]byte, *os.File) {
+	buf := make([]byte, 1024*1024)
+	oob := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	assert.Nil(t, err)
+	if oobn == 0 {
+		return buf[:n], nil
+	}
+
+	messages, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	assert.Nil(t, err)
+	fds, err := syscall.ParseUnixRights(&messages[0])
+	assert.Nil(t, err)
+	file := os.NewFile(uintptr(fds[0]), "entry")
+	file.Seek(0, io.SeekStart)
+	data, err := io.ReadAll(file)
+	assert.Nil(t, err)
+	return data, file
+}
+
+func TestJournaldAppender(t *testing.T) {
+
+	server, path := listenJournald(t)
+	defer server.Close()
+
+	config := NewDefaultJournaldAppenderConfig()
+	config.SocketPath = path
+	config.Fields = map[string]string{"unit-role": "api"}
+	appender := NewJournaldAppender(config)
+
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.SetMetadataConfig(&MetadataConfig{IsEnabledLoggerName: true, IsEnabledSourceFile: true, IsEnabledSourceLine: true})
+	request := logger.With(F("request.id", "abc"), F("count", 3), F("_hidden", true))
+	request.Warn("first line\nsecond line")
+
+	entry, _ := receiveJournaldEntry(t, server)
+	fields := parseJournaldEntry(t, entry)
+	assert.Equal(t, "first line\nsecond line", fields["MESSAGE"])
+	assert.Equal(t, "4", fields["PRIORITY"])
+	assert.Equal(t, "testLogger", fields["SYSLOG_IDENTIFIER"])
+	assert.True(t, strings.HasSuffix(fields["CODE_FILE"], "appender_journald_test.go"))
+	assert.NotEqual(t, "", fields["CODE_LINE"])
+	assert.Equal(t, "api", fields["UNIT_ROLE"])
+	assert.Equal(t, "abc", fields["REQUEST_ID"])
+	assert.Equal(t, "3", fields["COUNT"])
+	assert.Equal(t, "true", fields["F_HIDDEN"])
+
+	// the program name is the identifier without the logger name
+	logger.SetMetadataConfig(&MetadataConfig{})
+	logger.Error("message")
+	entry, _ = receiveJournaldEntry(t, server)
+	fields = parseJournaldEntry(t, entry)
+	assert.Equal(t, "3", fields["PRIORITY"])
+	assert.Equal(t, filepath.Base(os.Args[0]), fields["SYSLOG_IDENTIFIER"])
+	assert.Equal(t, "", fields["CODE_FILE"])
+
+	assert.Nil(t, logger.Close())
+}
+
+func TestJournaldAppender_LargeEntry(t *testing.T) {
+
+	server, path := listenJournald(t)
+	defer server.Close()
+
+	config := NewDefaultJournaldAppenderConfig()
+	config.SocketPath = path
+	appender := NewJournaldAppender(config)
+	defer appender.Close()
+
+	message := strings.Repeat("large entry ", 100000)
+	_, err := appender.Write([]byte(message))
+	assert.Nil(t, err)
+
+	entry, file := receiveJournaldEntry(t, server)
+	assert.NotNil(t, file)
+	defer file.Close()
+	assert.Equal(t, message, parseJournaldEntry(t, entry)["MESSAGE"])
+
+	// the memfd is sealed
+	if _, ok := memfdCreateSyscalls[runtime.GOARCH]; ok {
+		seals, _, errno := syscall.Syscall(syscall.SYS_FCNTL, file.Fd(), fcntlAddSeals+1, 0)
+		assert.Equal(t, syscall.Errno(0), errno)
+		assert.Equal(t, uintptr(journaldEntrySeals), seals)
+	}
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	assert.Equal(t, "REQUEST_ID", journaldFieldName("request.id"))
+	assert.Equal(t, "F_HIDDEN", journaldFieldName("_hidden"))
+	assert.Equal(t, "F1ST", journaldFieldName("1st"))
+	assert.Equal(t, "F", journaldFieldName(""))
+	assert.Equal(t, 64, len(journaldFieldName(strings.Repeat("a", 100))))
+}
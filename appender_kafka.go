@@ -0,0 +1,425 @@
+package golog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// KafkaCompression
+type KafkaCompression string
+
+const KafkaCompression_NONE KafkaCompression = "none"
+const KafkaCompression_GZIP KafkaCompression = "gzip"
+const KafkaCompression_SNAPPY KafkaCompression = "snappy"
+const KafkaCompression_LZ4 KafkaCompression = "lz4"
+
+// KafkaAcks is the number of acknowledgements the leader waits for
+type KafkaAcks int16
+
+// KafkaAcks_NONE does not wait for the broker, KafkaAcks_LEADER waits for the leader
+// and KafkaAcks_ALL waits for all in-sync replicas
+const KafkaAcks_NONE KafkaAcks = 0
+const KafkaAcks_LEADER KafkaAcks = 1
+const KafkaAcks_ALL KafkaAcks = -1
+
+const defaultKafkaBroker = "localhost:9092"
+
+const defaultKafkaClientId = "golog"
+
+// defaultKafkaMaxBatchBytes is below the default message.max.bytes of brokers
+const defaultKafkaMaxBatchBytes = 900 * 1024
+
+// KafkaAppenderConfig
+type KafkaAppenderConfig struct {
+	// Brokers are the bootstrap brokers, e.g. localhost:9092
+	Brokers []string
+
+	Topic string
+
+	// KeyField is the field whose value is the key of the message, e.g. request_id.
+	// Messages with the same key go to the same partition, messages without the key are spread over partitions.
+	KeyField string
+
+	Compression KafkaCompression
+	Acks        KafkaAcks
+	ClientId    string
+
+	MaxBatchSize  int
+	MaxBatchBytes int
+	FlushInterval time.Duration
+	QueueSize     int
+	MaxRetries    int
+
+	// Timeout is used for connecting, for requests and as the timeout of produce requests
+	Timeout time.Duration
+
+	// Fallback receives the events which can not be queued or produced, e.g. a file appender as a spool
+	Fallback Appender
+}
+
+// NewDefaultKafkaAppenderConfig
+func NewDefaultKafkaAppenderConfig() KafkaAppenderConfig {
+	return KafkaAppenderConfig{
+		Brokers:       []string{defaultKafkaBroker},
+		Compression:   KafkaCompression_SNAPPY,
+		Acks:          KafkaAcks_LEADER,
+		ClientId:      defaultKafkaClientId,
+		MaxBatchSize:  defaultMaxBatchSize,
+		MaxBatchBytes: defaultKafkaMaxBatchBytes,
+		FlushInterval: defaultBatchFlushInterval,
+		QueueSize:     defaultBatchQueueSize,
+		MaxRetries:    defaultMaxRetries,
+		Timeout:       defaultHttpTimeout,
+	}
+}
+
+// KafkaAppender produces events to a Kafka topic.
+// Events are queued and produced in compressed record batches from a background goroutine, one batch per partition.
+// The level, the logger and the trace context are sent as record headers.
+type KafkaAppender struct {
+	config    KafkaAppenderConfig
+	processor *batchProcessor
+
+	// the connections and the metadata are used by the goroutine of the processor only
+	conns         map[string]*kafkaConn
+	metadata      *kafkaTopicMetadata
+	nextPartition int
+}
+
+// NewKafkaAppender returns new KafkaAppender
+func NewKafkaAppender(config KafkaAppenderConfig) *KafkaAppender {
+	if len(config.Brokers) == 0 {
+		config.Brokers = []string{defaultKafkaBroker}
+	}
+
+	if config.Compression == "" {
+		config.Compression = KafkaCompression_NONE
+	}
+
+	if config.ClientId == "" {
+		config.ClientId = defaultKafkaClientId
+	}
+
+	if config.MaxBatchBytes <= 0 {
+		config.MaxBatchBytes = defaultKafkaMaxBatchBytes
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHttpTimeout
+	}
+
+	appender := &KafkaAppender{
+		config: config,
+		conns:  map[string]*kafkaConn{},
+	}
+	appender.processor = newBatchProcessorWithMaxBytes(appender.export, config.MaxBatchSize,
+		config.MaxBatchBytes, estimateDocumentSize, config.FlushInterval, config.QueueSize)
+	return appender
+}
+
+// AppendEvent implements EventAppender
+// The event is passed to the fallback if the queue is full.
+func (appender *KafkaAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	err := appender.processor.enqueue(newEventRecord(level, logEvent, metadata))
+	if err == errQueueFull && appender.config.Fallback != nil {
+		return appendEvent(appender.config.Fallback, level, logEvent, metadata)
+	}
+	return err
+}
+
+// Write implements io.Writer
+// Data is produced as the value of an INFO event.
+func (appender *KafkaAppender) Write(data []byte) (n int, err error) {
+	err = appender.processor.enqueue(newRawEventRecord(data))
+	if err == errQueueFull && appender.config.Fallback != nil {
+		return appender.config.Fallback.Write(data)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush implements Syncer
+// The fallback is flushed if it buffers events.
+func (appender *KafkaAppender) Flush() error {
+	err := appender.processor.flush()
+	if fallback, ok := appender.config.Fallback.(Syncer); ok {
+		err = errors.Join(err, fallback.Flush())
+	}
+	return err
+}
+
+// Close implements io.Closer
+// Queued events are produced before the connections and the fallback are closed.
+func (appender *KafkaAppender) Close() error {
+	appender.processor.close()
+
+	var errs []error
+	for address, conn := range appender.conns {
+		errs = append(errs, conn.close())
+		delete(appender.conns, address)
+	}
+	if appender.config.Fallback != nil {
+		errs = append(errs, appender.config.Fallback.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// export produces the records, refreshing the metadata and retrying the partitions which failed.
+// Records which are not produced, either rejected by the broker or still failing after the retries,
+// are passed to the fallback.
+func (appender *KafkaAppender) export(records []eventRecord) error {
+	pending := records
+	var rejected []eventRecord
+	var causes []error
+	for attempt := 0; ; attempt++ {
+		var rejectedNow []eventRecord
+		var err error
+		pending, rejectedNow, err = appender.produce(pending)
+		if err != nil {
+			causes = append(causes, err)
+		}
+		rejected = append(rejected, rejectedNow...)
+		if len(pending) == 0 {
+			break
+		}
+
+		// metadata is requested again for moved leaders and broken connections
+		appender.metadata = nil
+		if attempt >= appender.config.MaxRetries || !waitRetry(retryBackoff(attempt), appender.processor.closing()) {
+			break
+		}
+	}
+
+	unproduced := make([]eventRecord, 0, len(pending)+len(rejected))
+	unproduced = append(append(unproduced, pending...), rejected...)
+	if len(unproduced) == 0 {
+		return nil
+	}
+
+	// the errors of the attempts which rejected records and of the last attempt
+	var lastErr error
+	if len(causes) > 0 {
+		lastErr = causes[len(causes)-1]
+	}
+
+	if appender.config.Fallback != nil {
+		var errs []error
+		for _, record := range unproduced {
+			errs = append(errs, appendEvent(appender.config.Fallback, record.level, &TextLogEvent{Event: record.message}, &record.metadata))
+		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+		return fmt.Errorf("%d events are passed to the fallback : %w", len(unproduced), lastErr)
+	}
+	return fmt.Errorf("giving up %d events : %w", len(unproduced), lastErr)
+}
+
+// produce sends the records to the leaders of their partitions.
+// It returns the records which may be retried and the records which are rejected for good.
+func (appender *KafkaAppender) produce(records []eventRecord) (retry []eventRecord, rejected []eventRecord, err error) {
+	if appender.metadata == nil {
+		metadata, err := appender.fetchMetadata()
+		if err != nil {
+			return records, nil, err
+		}
+		appender.metadata = metadata
+	}
+
+	// records without the key stick to one partition per batch, keys are hashed as by the Java client
+	partitions := appender.metadata.partitions
+	sticky := partitions[appender.nextPartition%len(partitions)]
+	appender.nextPartition++
+
+	byPartition := map[int32][]eventRecord{}
+	for _, record := range records {
+		partition := sticky
+		if key := appender.key(record); key != nil {
+			partition = (kafkaMurmur2(key) & 0x7fffffff) % int32(len(partitions))
+		}
+		byPartition[partition] = append(byPartition[partition], record)
+	}
+
+	byLeader := map[string]map[int32][]eventRecord{}
+	for partition, partitionRecords := range byPartition {
+		leader, ok := appender.metadata.leaders[partition]
+		if !ok {
+			retry = append(retry, partitionRecords...)
+			continue
+		}
+		if byLeader[leader] == nil {
+			byLeader[leader] = map[int32][]eventRecord{}
+		}
+		byLeader[leader][partition] = partitionRecords
+	}
+
+	var errs []error
+	for leader, leaderRecords := range byLeader {
+		leaderRetry, leaderRejected, err := appender.produceTo(leader, leaderRecords)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		retry = append(retry, leaderRetry...)
+		rejected = append(rejected, leaderRejected...)
+	}
+	if len(retry) > 0 && len(errs) == 0 {
+		errs = append(errs, kafkaError(5))
+	}
+	return retry, rejected, errors.Join(errs...)
+}
+
+// produceTo sends the records of the partitions to their leader.
+// It returns the records which may be retried and the records which are rejected for good.
+func (appender *KafkaAppender) produceTo(leader string, records map[int32][]eventRecord) (retry []eventRecord, rejected []eventRecord, err error) {
+	var encodeErrs []error
+	batches := map[int32][]byte{}
+	for partition, partitionRecords := range records {
+		messages := make([]kafkaMessage, 0, len(partitionRecords))
+		for _, record := range partitionRecords {
+			messages = append(messages, appender.message(record))
+		}
+		batch, err := encodeKafkaRecordBatch(messages, appender.codec())
+		if err != nil {
+			rejected = append(rejected, partitionRecords...)
+			encodeErrs = append(encodeErrs, fmt.Errorf("partition %d : %w", partition, err))
+			continue
+		}
+		batches[partition] = batch
+	}
+	if len(batches) == 0 {
+		return nil, rejected, errors.Join(encodeErrs...)
+	}
+
+	conn, err := appender.conn(leader)
+	if err == nil {
+		var errs map[int32]error
+		errs, err = conn.produce(appender.config.Topic, int16(appender.config.Acks), appender.config.Timeout, batches)
+		if err == nil {
+			partitionErrs := encodeErrs
+			for partition, partitionErr := range errs {
+				if kafkaErr, ok := partitionErr.(kafkaError); ok && kafkaErr.retryable() {
+					retry = append(retry, records[partition]...)
+				} else {
+					rejected = append(rejected, records[partition]...)
+				}
+				partitionErrs = append(partitionErrs, fmt.Errorf("partition %d : %w", partition, partitionErr))
+			}
+			return retry, rejected, errors.Join(partitionErrs...)
+		}
+
+		conn.close()
+		delete(appender.conns, leader)
+	}
+
+	for partition := range batches {
+		retry = append(retry, records[partition]...)
+	}
+	return retry, rejected, errors.Join(append(encodeErrs, err)...)
+}
+
+// fetchMetadata requests the metadata of the topic from the bootstrap brokers
+func (appender *KafkaAppender) fetchMetadata() (*kafkaTopicMetadata, error) {
+	var errs []error
+	for _, broker := range appender.config.Brokers {
+		conn, err := appender.conn(broker)
+		if err == nil {
+			var metadata *kafkaTopicMetadata
+			metadata, err = conn.metadata(appender.config.Topic)
+			if err == nil {
+				return metadata, nil
+			}
+			conn.close()
+			delete(appender.conns, broker)
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// conn returns the connection to the broker, connecting if needed
+func (appender *KafkaAppender) conn(address string) (*kafkaConn, error) {
+	if conn, ok := appender.conns[address]; ok {
+		return conn, nil
+	}
+
+	conn, err := dialKafka(address, appender.config.ClientId, appender.config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	appender.conns[address] = conn
+	return conn, nil
+}
+
+// codec
+func (appender *KafkaAppender) codec() int16 {
+	switch appender.config.Compression {
+	case KafkaCompression_GZIP:
+		return kafkaCodecGzip
+	case KafkaCompression_SNAPPY:
+		return kafkaCodecSnappy
+	case KafkaCompression_LZ4:
+		return kafkaCodecLz4
+	default:
+		return kafkaCodecNone
+	}
+}
+
+// key returns the value of KeyField of the record, nil if the record does not have it
+func (appender *KafkaAppender) key(record eventRecord) []byte {
+	if appender.config.KeyField == "" {
+		return nil
+	}
+	for _, field := range record.metadata.Fields {
+		if field.Key == appender.config.KeyField {
+			return []byte(formatLabelValue(field.Value))
+		}
+	}
+	return nil
+}
+
+// message returns the record as a message, with the fields added to a JSON object message or written after other messages
+func (appender *KafkaAppender) message(record eventRecord) kafkaMessage {
+	metadata := record.metadata
+	message := kafkaMessage{
+		key:       appender.key(record),
+		timestamp: record.time,
+		headers:   []kafkaHeader{{key: "level", value: []byte(levelName(record.level))}},
+	}
+	if metadata.LoggerName != "" {
+		message.headers = append(message.headers, kafkaHeader{key: "logger", value: []byte(metadata.LoggerName)})
+	}
+	if metadata.TraceId != "" {
+		message.headers = append(message.headers,
+			kafkaHeader{key: "trace_id", value: []byte(metadata.TraceId)},
+			kafkaHeader{key: "span_id", value: []byte(metadata.SpanId)})
+	}
+
+	value := strings.TrimRight(record.message, "\n")
+	var object map[string]interface{}
+	if strings.HasPrefix(value, "{") && json.Unmarshal([]byte(value), &object) == nil {
+		for _, field := range metadata.Fields {
+			object[field.Key] = otlpAttributeValue(field.Value)
+		}
+		if len(metadata.StackTrace) > 0 {
+			object["stack_trace"] = metadata.StackTrace.String()
+		}
+		if data, err := json.Marshal(object); err == nil {
+			message.value = data
+			return message
+		}
+	}
+
+	if len(metadata.Fields) > 0 {
+		value += " " + metadata.Fields.String()
+	}
+	if len(metadata.StackTrace) > 0 {
+		value += "\n" + metadata.StackTrace.String()
+	}
+	message.value = []byte(value)
+	return message
+}
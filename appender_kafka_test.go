@@ -0,0 +1,349 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// kafkaBroker is an in-process broker leading all partitions of every topic
+type kafkaBroker struct {
+	listener   net.Listener
+	partitions int32
+
+	mu sync.Mutex
+	// notLeader is the number of produce requests answered with NOT_LEADER_OR_FOLLOWER
+	notLeader int
+	// tooLarge is the number of produce requests answered with MESSAGE_TOO_LARGE
+	tooLarge  int
+	metadatas int
+	acks      []int16
+	codecs    []int16
+	messages  map[int32][]kafkaMessage
+}
+
+// startKafkaBroker
+func startKafkaBroker(t *testing.T, partitions int32) *kafkaBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	broker := &kafkaBroker{listener: listener, partitions: partitions, messages: map[int32][]kafkaMessage{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go broker.serve(conn)
+		}
+	}()
+	return broker
+}
+
+func (broker *kafkaBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		request := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+
+		decoder := &kafkaDecoder{buf: request}
+		apiKey := decoder.int16()
+		decoder.int16() // api version
+		correlationId := decoder.int32()
+		decoder.string() // client id
+
+		response := &kafkaEncoder{}
+		response.int32(0)
+		response.int32(correlationId)
+		switch apiKey {
+		case kafkaApiKeyMetadata:
+			broker.metadata(decoder, response)
+		case kafkaApiKeyProduce:
+			if !broker.produce(decoder, response) {
+				continue
+			}
+		default:
+			return
+		}
+		binary.BigEndian.PutUint32(response.buf, uint32(len(response.buf)-4))
+		if _, err := conn.Write(response.buf); err != nil {
+			return
+		}
+	}
+}
+
+func (broker *kafkaBroker) metadata(request *kafkaDecoder, response *kafkaEncoder) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	broker.metadatas++
+
+	request.int32()
+	topic := request.string()
+
+	host, port, _ := net.SplitHostPort(broker.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	response.int32(0) // throttle time
+	response.int32(1)
+	response.int32(0) // node id
+	response.string(host)
+	response.int32(int32(portNumber))
+	response.nullableString(nil) // rack
+	response.nullableString(nil) // cluster id
+	response.int32(0)            // controller id
+	response.int32(1)
+	response.int16(0)
+	response.string(topic)
+	response.int8(0)
+	response.int32(broker.partitions)
+	for partition := int32(0); partition < broker.partitions; partition++ {
+		response.int16(0)
+		response.int32(partition)
+		response.int32(0) // leader
+		response.int32(1)
+		response.int32(0) // replica nodes
+		response.int32(1)
+		response.int32(0) // isr nodes
+	}
+}
+
+// produce returns false if the broker does not respond
+func (broker *kafkaBroker) produce(request *kafkaDecoder, response *kafkaEncoder) bool {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	request.int16() // transactional id
+	acks := request.int16()
+	broker.acks = append(broker.acks, acks)
+	request.int32() // timeout
+
+	var errorCode int16
+	if broker.notLeader > 0 {
+		broker.notLeader--
+		errorCode = 6
+	} else if broker.tooLarge > 0 {
+		broker.tooLarge--
+		errorCode = 10
+	}
+
+	response.int32(request.int32())
+	topic := request.string()
+	response.string(topic)
+	partitions := request.int32()
+	response.int32(partitions)
+	for i := int32(0); i < partitions; i++ {
+		partition := request.int32()
+		batch := request.bytes()
+		if errorCode == 0 {
+			codec, messages, err := decodeKafkaRecordBatch(batch)
+			if err != nil {
+				errorCode = 2 // corrupt message
+			}
+			broker.codecs = append(broker.codecs, codec)
+			broker.messages[partition] = append(broker.messages[partition], messages...)
+		}
+		response.int32(partition)
+		response.int16(errorCode)
+		response.int64(0) // base offset
+		response.int64(-1)
+	}
+	response.int32(0) // throttle time
+	return acks != 0
+}
+
+// values returns the values of the messages of the partition
+func (broker *kafkaBroker) values(partition int32) []string {
+	var values []string
+	for _, message := range broker.messages[partition] {
+		values = append(values, string(message.value))
+	}
+	return values
+}
+
+// kafkaFallback is a locked buffer for the events passed to the fallback
+type kafkaFallback struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (fallback *kafkaFallback) Write(data []byte) (int, error) {
+	fallback.mu.Lock()
+	defer fallback.mu.Unlock()
+	fallback.buf.Write(data)
+	return fallback.buf.WriteString("\n")
+}
+
+func (fallback *kafkaFallback) Close() error {
+	fallback.mu.Lock()
+	defer fallback.mu.Unlock()
+	fallback.closed = true
+	return nil
+}
+
+func TestKafkaAppender(t *testing.T) {
+
+	for _, compression := range []KafkaCompression{KafkaCompression_NONE, KafkaCompression_GZIP, KafkaCompression_SNAPPY, KafkaCompression_LZ4} {
+		broker := startKafkaBroker(t, 8)
+
+		config := NewDefaultKafkaAppenderConfig()
+		config.Brokers = []string{broker.listener.Addr().String()}
+		config.Topic = "logs"
+		config.KeyField = "request_id"
+		config.Compression = compression
+		config.Acks = KafkaAcks_ALL
+		appender := NewKafkaAppender(config)
+
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{IsEnabledLoggerName: true})
+		request1 := logger.With(F("request_id", "foobar"))
+		request2 := logger.With(F("request_id", "21"))
+		request1.Info("first")
+		request2.Info("second")
+		request1.Warn(`{"message":"third"}`)
+		logger.Info("fourth")
+		assert.Nil(t, logger.Close())
+		broker.listener.Close()
+
+		broker.mu.Lock()
+		// murmur2 of foobar is -790332482 and 21 is -973932308
+		assert.Equal(t, []string{"first request_id=foobar", `{"message":"third","request_id":"foobar"}`}, broker.values((-790332482&0x7fffffff)%8))
+		assert.Equal(t, []string{"second request_id=21"}, broker.values((-973932308&0x7fffffff)%8))
+
+		message := broker.messages[(-790332482&0x7fffffff)%8][1]
+		assert.Equal(t, "foobar", string(message.key))
+		assert.Equal(t, []kafkaHeader{{key: "level", value: []byte("WARN")}, {key: "logger", value: []byte("testLogger")}}, message.headers)
+		assert.True(t, time.Since(message.timestamp) < time.Minute)
+
+		// messages without the key stick to a partition
+		assert.Equal(t, []string{"fourth"}, broker.values(0))
+		assert.Equal(t, appender.codec(), broker.codecs[0])
+		assert.Equal(t, int16(-1), broker.acks[0])
+		broker.mu.Unlock()
+	}
+}
+
+func TestKafkaAppender_Retry(t *testing.T) {
+
+	broker := startKafkaBroker(t, 1)
+	defer broker.listener.Close()
+	broker.notLeader = 1
+
+	config := NewDefaultKafkaAppenderConfig()
+	config.Brokers = []string{broker.listener.Addr().String()}
+	config.Topic = "logs"
+	appender := NewKafkaAppender(config)
+
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.Info("message")
+	assert.Nil(t, appender.Flush())
+
+	// metadata is requested again after NOT_LEADER_OR_FOLLOWER
+	broker.mu.Lock()
+	assert.Equal(t, 2, broker.metadatas)
+	assert.Equal(t, []string{"message"}, broker.values(0))
+	broker.mu.Unlock()
+
+	// acks none is not answered
+	appender.config.Acks = KafkaAcks_NONE
+	logger.Info("no ack")
+	assert.Nil(t, logger.Close())
+
+	assert.Eventually(t, func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return len(broker.values(0)) == 2
+	}, time.Second, time.Millisecond*10)
+
+	broker.mu.Lock()
+	assert.Equal(t, []int16{1, 1, 0}, broker.acks)
+	assert.Equal(t, "no ack", broker.values(0)[1])
+	broker.mu.Unlock()
+}
+
+func TestKafkaAppender_Fallback(t *testing.T) {
+
+	// no broker is listening
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	fallback := &kafkaFallback{}
+	config := NewDefaultKafkaAppenderConfig()
+	config.Brokers = []string{address}
+	config.Topic = "logs"
+	config.MaxRetries = 0
+	config.QueueSize = 1
+	config.Fallback = fallback
+	appender := NewKafkaAppender(config)
+
+	// events which are not produced are passed to the fallback
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.SetMetadataConfig(&MetadataConfig{IsEnabledLogLevel: true})
+	alice := logger.With(F("user", "alice"))
+	alice.Info("message")
+	assert.NotNil(t, appender.Flush())
+
+	fallback.mu.Lock()
+	assert.Equal(t, "[INFO]   () message user=alice\n", fallback.buf.String())
+	fallback.mu.Unlock()
+
+	// events which can not be queued are passed to the fallback
+	for i := 0; i < 100; i++ {
+		logger.Info("message" + strconv.Itoa(i))
+	}
+	assert.Nil(t, logger.Close())
+
+	fallback.mu.Lock()
+	defer fallback.mu.Unlock()
+	assert.True(t, fallback.closed)
+	for i := 0; i < 100; i++ {
+		assert.True(t, strings.Contains(fallback.buf.String(), "() message"+strconv.Itoa(i)+"\n"))
+	}
+}
+
+func TestKafkaAppender_Rejected(t *testing.T) {
+
+	broker := startKafkaBroker(t, 1)
+	defer broker.listener.Close()
+	broker.tooLarge = 1
+
+	fallback := &kafkaFallback{}
+	config := NewDefaultKafkaAppenderConfig()
+	config.Brokers = []string{broker.listener.Addr().String()}
+	config.Topic = "logs"
+	config.Fallback = fallback
+	appender := NewKafkaAppender(config)
+
+	// events rejected with MESSAGE_TOO_LARGE are not retried but passed to the fallback
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.SetMetadataConfig(&MetadataConfig{})
+	logger.Info("too large")
+	assert.NotNil(t, appender.Flush())
+
+	logger.Info("message")
+	assert.Nil(t, logger.Close())
+
+	broker.mu.Lock()
+	assert.Equal(t, []string{"message"}, broker.values(0))
+	assert.Equal(t, 1, broker.metadatas)
+	broker.mu.Unlock()
+
+	fallback.mu.Lock()
+	assert.Equal(t, "   () too large\n", fallback.buf.String())
+	fallback.mu.Unlock()
+}
@@ -0,0 +1,318 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LokiEncoding
+type LokiEncoding string
+
+const LokiEncoding_PROTOBUF LokiEncoding = "protobuf"
+const LokiEncoding_JSON LokiEncoding = "json"
+
+const defaultLokiEndpoint = "http://localhost:3100/loki/api/v1/push"
+
+// LokiAppenderConfig
+type LokiAppenderConfig struct {
+	// Endpoint is the push endpoint, e.g. http://localhost:3100/loki/api/v1/push
+	Endpoint string
+
+	// Encoding is either snappy compressed protobuf or json
+	Encoding LokiEncoding
+
+	// TenantId is sent as X-Scope-OrgID for multi-tenant Loki
+	TenantId string
+
+	// Labels are added to every stream, e.g. job and env
+	Labels map[string]string
+
+	// LabelFields is the allow-list of fields sent as labels, other fields are written to the line.
+	// Only fields of a small number of values should be labels.
+	LabelFields []string
+
+	// Username and Password are sent by basic authentication
+	Username string
+	Password string
+
+	// Headers are added to every request
+	Headers map[string]string
+
+	MaxBatchSize  int
+	FlushInterval time.Duration
+	QueueSize     int
+	MaxRetries    int
+	Timeout       time.Duration
+
+	// HttpClient is used instead of a client with Timeout if specified
+	HttpClient *http.Client
+}
+
+// NewDefaultLokiAppenderConfig
+func NewDefaultLokiAppenderConfig() LokiAppenderConfig {
+	return LokiAppenderConfig{
+		Endpoint:      defaultLokiEndpoint,
+		Encoding:      LokiEncoding_PROTOBUF,
+		MaxBatchSize:  defaultMaxBatchSize,
+		FlushInterval: defaultBatchFlushInterval,
+		QueueSize:     defaultBatchQueueSize,
+		MaxRetries:    defaultMaxRetries,
+		Timeout:       defaultHttpTimeout,
+	}
+}
+
+// LokiAppender pushes events to Grafana Loki.
+// Events are queued and pushed in batches from a background goroutine, grouped into streams per label set.
+// The labels of an event are the configured labels, logger, level and the allowed fields.
+type LokiAppender struct {
+	config    LokiAppenderConfig
+	client    *http.Client
+	processor *batchProcessor
+}
+
+// NewLokiAppender returns new LokiAppender
+func NewLokiAppender(config LokiAppenderConfig) *LokiAppender {
+	if config.Endpoint == "" {
+		config.Endpoint = defaultLokiEndpoint
+	}
+
+	if config.Encoding == "" {
+		config.Encoding = LokiEncoding_PROTOBUF
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHttpTimeout
+	}
+
+	client := config.HttpClient
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+
+	appender := &LokiAppender{
+		config: config,
+		client: client,
+	}
+	appender.processor = newBatchProcessor(appender.export, config.MaxBatchSize, config.FlushInterval, config.QueueSize)
+	return appender
+}
+
+// AppendEvent implements EventAppender
+func (appender *LokiAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	return appender.processor.enqueue(newEventRecord(level, logEvent, metadata))
+}
+
+// Write implements io.Writer
+// Data is pushed as the line of an INFO event.
+func (appender *LokiAppender) Write(data []byte) (n int, err error) {
+	if err := appender.processor.enqueue(newRawEventRecord(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush implements Syncer
+func (appender *LokiAppender) Flush() error {
+	return appender.processor.flush()
+}
+
+// Close implements io.Closer
+// Queued events are pushed before it returns.
+func (appender *LokiAppender) Close() error {
+	appender.processor.close()
+	return nil
+}
+
+// lokiEntry
+type lokiEntry struct {
+	time time.Time
+	line string
+}
+
+// lokiStream is the entries of a label set
+type lokiStream struct {
+	labels  map[string]string
+	entries []lokiEntry
+}
+
+// key returns the label set in the Prometheus format, e.g. {level="info", logger="app"}
+func (stream *lokiStream) key() string {
+	names := make([]string, 0, len(stream.labels))
+	for name := range stream.labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+strconv.Quote(stream.labels[name]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// export
+func (appender *LokiAppender) export(records []eventRecord) error {
+	streams := appender.groupByLabels(records)
+
+	var body []byte
+	var contentType string
+	var err error
+	switch appender.config.Encoding {
+	case LokiEncoding_JSON:
+		contentType = "application/json"
+		body, err = encodeLokiJson(streams)
+		if err != nil {
+			return err
+		}
+	default:
+		contentType = "application/x-protobuf"
+		body = snappyEncode(encodeLokiProtobuf(streams))
+	}
+
+	_, err = doWithRetry(appender.client, appender.config.MaxRetries, appender.processor.closing(), func() (*http.Request, error) {
+		request, err := http.NewRequest(http.MethodPost, appender.config.Endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", contentType)
+		if appender.config.TenantId != "" {
+			request.Header.Set("X-Scope-OrgID", appender.config.TenantId)
+		}
+		if appender.config.Username != "" {
+			request.SetBasicAuth(appender.config.Username, appender.config.Password)
+		}
+		for k, v := range appender.config.Headers {
+			request.Header.Set(k, v)
+		}
+		return request, nil
+	})
+	return err
+}
+
+// groupByLabels groups records into streams, keeping the order of the records
+func (appender *LokiAppender) groupByLabels(records []eventRecord) []*lokiStream {
+	var streams []*lokiStream
+	index := map[string]*lokiStream{}
+	for _, record := range records {
+		labels, line := appender.labelsAndLine(record)
+		stream := &lokiStream{labels: labels}
+		key := stream.key()
+		if found, ok := index[key]; ok {
+			stream = found
+		} else {
+			index[key] = stream
+			streams = append(streams, stream)
+		}
+		stream.entries = append(stream.entries, lokiEntry{time: record.time, line: line})
+	}
+
+	// entries of a stream are accepted in order of time
+	for _, stream := range streams {
+		sort.SliceStable(stream.entries, func(i, j int) bool {
+			return stream.entries[i].time.Before(stream.entries[j].time)
+		})
+	}
+	return streams
+}
+
+// labelsAndLine returns the labels of the record and its line holding the other fields
+func (appender *LokiAppender) labelsAndLine(record eventRecord) (map[string]string, string) {
+	labels := map[string]string{}
+	for name, value := range appender.config.Labels {
+		labels[lokiLabelName(name)] = value
+	}
+	if record.metadata.LoggerName != "" {
+		labels["logger"] = record.metadata.LoggerName
+	}
+	labels["level"] = strings.ToLower(levelName(record.level))
+
+	var fields Fields
+	if record.metadata.TraceId != "" {
+		fields = append(fields, F("trace_id", record.metadata.TraceId), F("span_id", record.metadata.SpanId))
+	}
+	for _, field := range record.metadata.Fields {
+		if contains(appender.config.LabelFields, field.Key) {
+			labels[lokiLabelName(field.Key)] = formatLabelValue(field.Value)
+			continue
+		}
+		fields = append(fields, field)
+	}
+
+	line := strings.TrimRight(record.message, "\n")
+	if len(fields) > 0 {
+		line += " " + fields.String()
+	}
+	if len(record.metadata.StackTrace) > 0 {
+		line += "\n" + record.metadata.StackTrace.String()
+	}
+	return labels, line
+}
+
+// lokiLabelName returns the name as a valid label name, which matches [a-zA-Z_][a-zA-Z0-9_]*
+func lokiLabelName(name string) string {
+	label := []byte(name)
+	for i, c := range label {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			label[i] = '_'
+		}
+	}
+	if len(label) == 0 || label[0] >= '0' && label[0] <= '9' {
+		return "_" + string(label)
+	}
+	return string(label)
+}
+
+// formatLabelValue
+func formatLabelValue(value interface{}) string {
+	return fmt.Sprint(otlpAttributeValue(value))
+}
+
+// encodeLokiProtobuf encodes streams as logproto.PushRequest
+func encodeLokiProtobuf(streams []*lokiStream) []byte {
+	request := &protoBuffer{}
+	for _, stream := range streams {
+		// PushRequest.streams
+		request.messageField(1, func(streamAdapter *protoBuffer) {
+			streamAdapter.stringField(1, stream.key())
+			for _, entry := range stream.entries {
+				// StreamAdapter.entries
+				streamAdapter.messageField(2, func(entryAdapter *protoBuffer) {
+					// google.protobuf.Timestamp
+					entryAdapter.messageField(1, func(timestamp *protoBuffer) {
+						timestamp.varintField(1, uint64(entry.time.Unix()))
+						timestamp.varintField(2, uint64(entry.time.Nanosecond()))
+					})
+					entryAdapter.stringField(2, entry.line)
+				})
+			}
+		})
+	}
+	return request.bytes()
+}
+
+// lokiJsonStream
+type lokiJsonStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// encodeLokiJson
+func encodeLokiJson(streams []*lokiStream) ([]byte, error) {
+	jsonStreams := make([]lokiJsonStream, 0, len(streams))
+	for _, stream := range streams {
+		jsonStream := lokiJsonStream{Stream: stream.labels}
+		for _, entry := range stream.entries {
+			jsonStream.Values = append(jsonStream.Values, [2]string{strconv.FormatInt(entry.time.UnixNano(), 10), entry.line})
+		}
+		jsonStreams = append(jsonStreams, jsonStream)
+	}
+	return json.Marshal(struct {
+		Streams []lokiJsonStream `json:"streams"`
+	}{Streams: jsonStreams})
+}
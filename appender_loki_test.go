@@ -0,0 +1,118 @@
+package golog
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lokiServer is a local push endpoint
+type lokiServer struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (server *lokiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	server.requests = append(server.requests, r)
+	server.bodies = append(server.bodies, body)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestLokiAppender_Json(t *testing.T) {
+
+	stub := &lokiServer{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	config := NewDefaultLokiAppenderConfig()
+	config.Endpoint = server.URL + "/loki/api/v1/push"
+	config.Encoding = LokiEncoding_JSON
+	config.TenantId = "tenant1"
+	config.Labels = map[string]string{"job": "test", "app-name": "app"}
+	config.LabelFields = []string{"region"}
+	appender := NewLokiAppender(config)
+
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.SetMetadataConfig(&MetadataConfig{IsEnabledLoggerName: true, IsEnabledTime: true})
+	east := logger.With(F("region", "east"), F("user", "alice"))
+	east.Info("first")
+	west := logger.With(F("region", "west"))
+	west.Info("second")
+	east.Info("third")
+	east.Error("fourth")
+	assert.Nil(t, logger.Close())
+
+	assert.Equal(t, 1, len(stub.requests))
+	assert.Equal(t, "tenant1", stub.requests[0].Header.Get("X-Scope-OrgID"))
+	assert.Equal(t, "application/json", stub.requests[0].Header.Get("Content-Type"))
+
+	var request struct {
+		Streams []lokiJsonStream `json:"streams"`
+	}
+	assert.Nil(t, json.Unmarshal(stub.bodies[0], &request))
+	assert.Equal(t, 3, len(request.Streams))
+
+	assert.Equal(t, map[string]string{"job": "test", "app_name": "app", "logger": "testLogger", "level": "info", "region": "east"}, request.Streams[0].Stream)
+	assert.Equal(t, 2, len(request.Streams[0].Values))
+	assert.Equal(t, "first user=alice", request.Streams[0].Values[0][1])
+	assert.Equal(t, "third user=alice", request.Streams[0].Values[1][1])
+
+	assert.Equal(t, "west", request.Streams[1].Stream["region"])
+	assert.Equal(t, "second", request.Streams[1].Values[0][1])
+
+	assert.Equal(t, "error", request.Streams[2].Stream["level"])
+	assert.Equal(t, "fourth user=alice", request.Streams[2].Values[0][1])
+}
+
+func TestLokiAppender_Protobuf(t *testing.T) {
+
+	stub := &lokiServer{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	config := NewDefaultLokiAppenderConfig()
+	config.Endpoint = server.URL + "/loki/api/v1/push"
+	appender := NewLokiAppender(config)
+
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.Warn("message")
+	assert.Nil(t, appender.Flush())
+	assert.Nil(t, logger.Close())
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	assert.Equal(t, "application/x-protobuf", stub.requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "", stub.requests[0].Header.Get("X-Scope-OrgID"))
+
+	body, err := snappyDecode(stub.bodies[0])
+	assert.Nil(t, err)
+
+	// PushRequest.streams
+	streams := protoFields(decodeProto(t, body), 1)
+	assert.Equal(t, 1, len(streams))
+	stream := decodeProto(t, streams[0].data)
+	assert.Equal(t, `{level="warn", logger="testLogger"}`, string(protoFields(stream, 1)[0].data))
+
+	entry := decodeProto(t, protoFields(stream, 2)[0].data)
+	timestamp := decodeProto(t, protoFields(entry, 1)[0].data)
+	seconds := int64(protoFields(timestamp, 1)[0].value)
+	assert.True(t, time.Since(time.Unix(seconds, 0)) < time.Minute)
+	assert.Equal(t, "message", string(protoFields(entry, 2)[0].data))
+}
+
+func TestLokiLabelName(t *testing.T) {
+	assert.Equal(t, "app_name", lokiLabelName("app-name"))
+	assert.Equal(t, "_1st", lokiLabelName("1st"))
+	assert.Equal(t, "a1_b", lokiLabelName("a1.b"))
+	assert.Equal(t, "_", lokiLabelName(""))
+}
@@ -0,0 +1,479 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OtlpEncoding
+type OtlpEncoding string
+
+const OtlpEncoding_PROTOBUF OtlpEncoding = "protobuf"
+const OtlpEncoding_JSON OtlpEncoding = "json"
+
+const defaultOtlpEndpoint = "http://localhost:4318/v1/logs"
+
+const defaultHttpTimeout = time.Second * 10
+
+// otlpScopeName is used when the logger has no name
+const otlpScopeName = "golog"
+
+// OtlpAppenderConfig
+type OtlpAppenderConfig struct {
+	// Endpoint is the OTLP/HTTP logs endpoint, e.g. http://localhost:4318/v1/logs
+	Endpoint string
+
+	// Encoding is either protobuf or json
+	Encoding OtlpEncoding
+
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string
+
+	// ServiceName is exported as the service.name resource attribute
+	ServiceName string
+
+	// ResourceAttributes are exported as resource attributes
+	ResourceAttributes map[string]string
+
+	MaxBatchSize  int
+	FlushInterval time.Duration
+	QueueSize     int
+	MaxRetries    int
+	Timeout       time.Duration
+
+	// HttpClient is used instead of a client with Timeout if specified
+	HttpClient *http.Client
+}
+
+// NewDefaultOtlpAppenderConfig
+func NewDefaultOtlpAppenderConfig() OtlpAppenderConfig {
+	return OtlpAppenderConfig{
+		Endpoint:      defaultOtlpEndpoint,
+		Encoding:      OtlpEncoding_PROTOBUF,
+		MaxBatchSize:  defaultMaxBatchSize,
+		FlushInterval: defaultBatchFlushInterval,
+		QueueSize:     defaultBatchQueueSize,
+		MaxRetries:    defaultMaxRetries,
+		Timeout:       defaultHttpTimeout,
+	}
+}
+
+// OtlpAppender exports events as OpenTelemetry LogRecords over OTLP/HTTP.
+// Events are queued and exported in batches from a background goroutine.
+// The severity is mapped from LogLevel, fields are mapped to attributes
+// and the logger name is used as the instrumentation scope.
+type OtlpAppender struct {
+	config    OtlpAppenderConfig
+	client    *http.Client
+	processor *batchProcessor
+}
+
+// NewOtlpAppender returns new OtlpAppender
+func NewOtlpAppender(config OtlpAppenderConfig) *OtlpAppender {
+	if config.Endpoint == "" {
+		config.Endpoint = defaultOtlpEndpoint
+	}
+
+	if config.Encoding == "" {
+		config.Encoding = OtlpEncoding_PROTOBUF
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHttpTimeout
+	}
+
+	client := config.HttpClient
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+
+	appender := &OtlpAppender{
+		config: config,
+		client: client,
+	}
+	appender.processor = newBatchProcessor(appender.export, config.MaxBatchSize, config.FlushInterval, config.QueueSize)
+	return appender
+}
+
+// AppendEvent implements EventAppender
+func (appender *OtlpAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	return appender.processor.enqueue(newEventRecord(level, logEvent, metadata))
+}
+
+// Write implements io.Writer
+// Data is exported as the body of an INFO record.
+func (appender *OtlpAppender) Write(data []byte) (n int, err error) {
+	if err := appender.processor.enqueue(newRawEventRecord(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush implements Syncer
+func (appender *OtlpAppender) Flush() error {
+	return appender.processor.flush()
+}
+
+// Close implements io.Closer
+// Queued events are exported before it returns.
+func (appender *OtlpAppender) Close() error {
+	appender.processor.close()
+	return nil
+}
+
+// export
+func (appender *OtlpAppender) export(records []eventRecord) error {
+	var body []byte
+	var contentType string
+	var err error
+
+	switch appender.config.Encoding {
+	case OtlpEncoding_JSON:
+		contentType = "application/json"
+		body, err = appender.encodeJson(records)
+		if err != nil {
+			return err
+		}
+	default:
+		contentType = "application/x-protobuf"
+		body = appender.encodeProtobuf(records)
+	}
+
+	_, err = doWithRetry(appender.client, appender.config.MaxRetries, appender.processor.closing(), func() (*http.Request, error) {
+		request, err := http.NewRequest(http.MethodPost, appender.config.Endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", contentType)
+		for k, v := range appender.config.Headers {
+			request.Header.Set(k, v)
+		}
+		return request, nil
+	})
+	return err
+}
+
+// OtlpSeverityNumber returns the OpenTelemetry severity number of the level
+func OtlpSeverityNumber(level LogLevel) int {
+	switch level {
+	case LogLevel_TRACE:
+		return 1
+	case LogLevel_DEBUG:
+		return 5
+	case LogLevel_INFO:
+		return 9
+	case LogLevel_WARN:
+		return 13
+	case LogLevel_ERROR:
+		return 17
+	case LogLevel_FATAL:
+		return 21
+	default:
+		return 0
+	}
+}
+
+// levelName returns the level without brackets, e.g. INFO
+func levelName(level LogLevel) string {
+	return strings.Trim(level.String(), "[]")
+}
+
+// otlpAttribute holds a value of string, bool, int64 or float64
+type otlpAttribute struct {
+	key   string
+	value interface{}
+}
+
+// otlpScope is the records of a logger
+type otlpScope struct {
+	name    string
+	records []eventRecord
+}
+
+// groupByScope groups records per logger, keeping the order of the records
+func groupByScope(records []eventRecord) []*otlpScope {
+	var scopes []*otlpScope
+	index := map[string]*otlpScope{}
+	for _, record := range records {
+		name := record.metadata.LoggerName
+		if name == "" {
+			name = otlpScopeName
+		}
+		scope, ok := index[name]
+		if !ok {
+			scope = &otlpScope{name: name}
+			index[name] = scope
+			scopes = append(scopes, scope)
+		}
+		scope.records = append(scope.records, record)
+	}
+	return scopes
+}
+
+// resourceAttributes
+func (appender *OtlpAppender) resourceAttributes() []otlpAttribute {
+	var attributes []otlpAttribute
+	if appender.config.ServiceName != "" {
+		attributes = append(attributes, otlpAttribute{key: "service.name", value: appender.config.ServiceName})
+	}
+
+	keys := make([]string, 0, len(appender.config.ResourceAttributes))
+	for k := range appender.config.ResourceAttributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attributes = append(attributes, otlpAttribute{key: k, value: appender.config.ResourceAttributes[k]})
+	}
+	return attributes
+}
+
+// recordAttributes maps the source, the stack trace and the fields of the record to attributes
+func recordAttributes(record eventRecord) []otlpAttribute {
+	var attributes []otlpAttribute
+	metadata := record.metadata
+
+	if metadata.SourceFile != "" {
+		attributes = append(attributes,
+			otlpAttribute{key: "code.filepath", value: metadata.SourceFile},
+			otlpAttribute{key: "code.lineno", value: int64(metadata.SourceLine)})
+	}
+
+	if len(metadata.StackTrace) > 0 {
+		attributes = append(attributes, otlpAttribute{key: "code.stacktrace", value: metadata.StackTrace.String()})
+	}
+
+	for _, field := range metadata.Fields {
+		if detail, ok := field.Value.(ErrorDetail); ok {
+			attributes = append(attributes, otlpAttribute{key: field.Key, value: detail.Message})
+			if block := detail.stackTraceBlock(); block != "" {
+				attributes = append(attributes, otlpAttribute{key: "exception.stacktrace", value: strings.TrimPrefix(block, "\n")})
+			}
+			continue
+		}
+		attributes = append(attributes, otlpAttribute{key: field.Key, value: otlpAttributeValue(field.Value)})
+	}
+	return attributes
+}
+
+// otlpAttributeValue converts value to string, bool, int64 or float64
+func otlpAttributeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string, bool, int64, float64:
+		return v
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// traceFlags parses the hex trace flags
+func traceFlags(flags string) uint32 {
+	value, err := strconv.ParseUint(flags, 16, 8)
+	if err != nil {
+		return 0
+	}
+	return uint32(value)
+}
+
+// encodeProtobuf encodes records as opentelemetry.proto.collector.logs.v1.ExportLogsServiceRequest
+func (appender *OtlpAppender) encodeProtobuf(records []eventRecord) []byte {
+	request := &protoBuffer{}
+
+	// ExportLogsServiceRequest.resource_logs
+	request.messageField(1, func(resourceLogs *protoBuffer) {
+		// ResourceLogs.resource
+		resourceLogs.messageField(1, func(resource *protoBuffer) {
+			for _, attribute := range appender.resourceAttributes() {
+				resource.messageField(1, attribute.encodeProtobuf)
+			}
+		})
+
+		// ResourceLogs.scope_logs
+		for _, scope := range groupByScope(records) {
+			resourceLogs.messageField(2, func(scopeLogs *protoBuffer) {
+				scopeLogs.messageField(1, func(instrumentationScope *protoBuffer) {
+					instrumentationScope.stringField(1, scope.name)
+				})
+				for _, record := range scope.records {
+					scopeLogs.messageField(2, func(logRecord *protoBuffer) {
+						encodeOtlpLogRecord(logRecord, record)
+					})
+				}
+			})
+		}
+	})
+
+	return request.bytes()
+}
+
+// encodeOtlpLogRecord encodes opentelemetry.proto.logs.v1.LogRecord
+func encodeOtlpLogRecord(logRecord *protoBuffer, record eventRecord) {
+	logRecord.fixed64Field(1, uint64(record.time.UnixNano()))
+	logRecord.varintField(2, uint64(OtlpSeverityNumber(record.level)))
+	logRecord.stringField(3, levelName(record.level))
+	logRecord.messageField(5, func(body *protoBuffer) {
+		body.stringField(1, record.message)
+	})
+	for _, attribute := range recordAttributes(record) {
+		logRecord.messageField(6, attribute.encodeProtobuf)
+	}
+
+	if record.metadata.TraceId != "" {
+		traceId, err1 := hex.DecodeString(record.metadata.TraceId)
+		spanId, err2 := hex.DecodeString(record.metadata.SpanId)
+		if err1 == nil && err2 == nil {
+			logRecord.fixed32Field(8, traceFlags(record.metadata.TraceFlags))
+			logRecord.bytesField(9, traceId)
+			logRecord.bytesField(10, spanId)
+		}
+	}
+	logRecord.fixed64Field(11, uint64(record.time.UnixNano()))
+}
+
+// encodeProtobuf encodes opentelemetry.proto.common.v1.KeyValue
+func (attribute otlpAttribute) encodeProtobuf(keyValue *protoBuffer) {
+	keyValue.stringField(1, attribute.key)
+	keyValue.messageField(2, func(anyValue *protoBuffer) {
+		switch v := attribute.value.(type) {
+		case bool:
+			value := uint64(0)
+			if v {
+				value = 1
+			}
+			anyValue.varintField(2, value)
+		case int64:
+			anyValue.varintField(3, uint64(v))
+		case float64:
+			anyValue.doubleField(4, v)
+		default:
+			anyValue.stringField(1, fmt.Sprint(v))
+		}
+	})
+}
+
+// otlpJsonKeyValue
+type otlpJsonKeyValue struct {
+	Key   string           `json:"key"`
+	Value otlpJsonAnyValue `json:"value"`
+}
+
+// otlpJsonAnyValue, 64 bit integers are encoded as strings
+type otlpJsonAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// encodeJson
+func (attribute otlpAttribute) encodeJson() otlpJsonKeyValue {
+	keyValue := otlpJsonKeyValue{Key: attribute.key}
+	switch v := attribute.value.(type) {
+	case bool:
+		keyValue.Value.BoolValue = &v
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		keyValue.Value.IntValue = &s
+	case float64:
+		keyValue.Value.DoubleValue = &v
+	default:
+		s := fmt.Sprint(v)
+		keyValue.Value.StringValue = &s
+	}
+	return keyValue
+}
+
+// otlpJsonLogRecord
+type otlpJsonLogRecord struct {
+	TimeUnixNano         string             `json:"timeUnixNano"`
+	ObservedTimeUnixNano string             `json:"observedTimeUnixNano"`
+	SeverityNumber       int                `json:"severityNumber"`
+	SeverityText         string             `json:"severityText"`
+	Body                 otlpJsonAnyValue   `json:"body"`
+	Attributes           []otlpJsonKeyValue `json:"attributes,omitempty"`
+	Flags                uint32             `json:"flags,omitempty"`
+	TraceId              string             `json:"traceId,omitempty"`
+	SpanId               string             `json:"spanId,omitempty"`
+}
+
+// encodeJson encodes records as ExportLogsServiceRequest in the OTLP/JSON format
+func (appender *OtlpAppender) encodeJson(records []eventRecord) ([]byte, error) {
+	type scopeLogs struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		LogRecords []otlpJsonLogRecord `json:"logRecords"`
+	}
+
+	type resourceLogs struct {
+		Resource struct {
+			Attributes []otlpJsonKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []scopeLogs `json:"scopeLogs"`
+	}
+
+	resource := resourceLogs{}
+	resource.Resource.Attributes = []otlpJsonKeyValue{}
+	for _, attribute := range appender.resourceAttributes() {
+		resource.Resource.Attributes = append(resource.Resource.Attributes, attribute.encodeJson())
+	}
+
+	for _, scope := range groupByScope(records) {
+		logs := scopeLogs{}
+		logs.Scope.Name = scope.name
+		for _, record := range scope.records {
+			message := record.message
+			timeUnixNano := strconv.FormatInt(record.time.UnixNano(), 10)
+			logRecord := otlpJsonLogRecord{
+				TimeUnixNano:         timeUnixNano,
+				ObservedTimeUnixNano: timeUnixNano,
+				SeverityNumber:       OtlpSeverityNumber(record.level),
+				SeverityText:         levelName(record.level),
+				Body:                 otlpJsonAnyValue{StringValue: &message},
+			}
+			for _, attribute := range recordAttributes(record) {
+				logRecord.Attributes = append(logRecord.Attributes, attribute.encodeJson())
+			}
+			if record.metadata.TraceId != "" {
+				logRecord.TraceId = record.metadata.TraceId
+				logRecord.SpanId = record.metadata.SpanId
+				logRecord.Flags = traceFlags(record.metadata.TraceFlags)
+			}
+			logs.LogRecords = append(logs.LogRecords, logRecord)
+		}
+		resource.ScopeLogs = append(resource.ScopeLogs, logs)
+	}
+
+	return json.Marshal(struct {
+		ResourceLogs []resourceLogs `json:"resourceLogs"`
+	}{
+		ResourceLogs: []resourceLogs{resource},
+	})
+}
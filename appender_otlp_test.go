@@ -0,0 +1,130 @@
+package golog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// otlpCollector is a local OTLP/HTTP collector
+type otlpCollector struct {
+	mu           sync.Mutex
+	contentTypes []string
+	bodies       [][]byte
+	failures     int
+}
+
+func (collector *otlpCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if collector.failures > 0 {
+		collector.failures--
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	collector.contentTypes = append(collector.contentTypes, r.Header.Get("Content-Type"))
+	collector.bodies = append(collector.bodies, body)
+}
+
+func TestOtlpAppender_Json(t *testing.T) {
+
+	collector := &otlpCollector{failures: 1}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	config := NewDefaultOtlpAppenderConfig()
+	config.Endpoint = server.URL + "/v1/logs"
+	config.Encoding = OtlpEncoding_JSON
+	config.ServiceName = "testService"
+	appender := NewOtlpAppender(config)
+
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	ctx := ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	requestLogger := logger.WithContext(ctx)
+	requestLogger = requestLogger.With(F("user", "alice"), F("count", 3))
+	requestLogger.Warn("message")
+	// retries are not waited for on close
+	assert.Nil(t, appender.Flush())
+	assert.Nil(t, logger.Close())
+
+	assert.Equal(t, []string{"application/json"}, collector.contentTypes)
+
+	var request struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []otlpJsonKeyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				Scope struct {
+					Name string `json:"name"`
+				} `json:"scope"`
+				LogRecords []otlpJsonLogRecord `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	assert.Nil(t, json.Unmarshal(collector.bodies[0], &request))
+
+	resource := request.ResourceLogs[0]
+	assert.Equal(t, "service.name", resource.Resource.Attributes[0].Key)
+	assert.Equal(t, "testService", *resource.Resource.Attributes[0].Value.StringValue)
+	assert.Equal(t, "testLogger", resource.ScopeLogs[0].Scope.Name)
+
+	record := resource.ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, 13, record.SeverityNumber)
+	assert.Equal(t, "WARN", record.SeverityText)
+	assert.Equal(t, "message", *record.Body.StringValue)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", record.TraceId)
+	assert.Equal(t, "00f067aa0ba902b7", record.SpanId)
+	assert.Equal(t, uint32(1), record.Flags)
+
+	attributes := map[string]otlpJsonAnyValue{}
+	for _, attribute := range record.Attributes {
+		attributes[attribute.Key] = attribute.Value
+	}
+	assert.True(t, strings.HasSuffix(*attributes["code.filepath"].StringValue, "appender_otlp_test.go"))
+	assert.Equal(t, "alice", *attributes["user"].StringValue)
+	assert.Equal(t, "3", *attributes["count"].IntValue)
+}
+
+func TestOtlpAppender_Protobuf(t *testing.T) {
+
+	collector := &otlpCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	config := NewDefaultOtlpAppenderConfig()
+	config.Endpoint = server.URL + "/v1/logs"
+	appender := NewOtlpAppender(config)
+
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.Error("message1")
+	logger.Info("message2")
+	assert.Nil(t, appender.Flush())
+
+	assert.Equal(t, []string{"application/x-protobuf"}, collector.contentTypes)
+
+	resourceLogs := decodeProto(t, collector.bodies[0])
+	scopeLogs := decodeProto(t, protoFields(decodeProto(t, resourceLogs[0].data), 2)[0].data)
+	scope := decodeProto(t, protoFields(scopeLogs, 1)[0].data)
+	assert.Equal(t, "testLogger", string(scope[0].data))
+
+	logRecords := protoFields(scopeLogs, 2)
+	assert.Equal(t, 2, len(logRecords))
+
+	logRecord := decodeProto(t, logRecords[0].data)
+	assert.Equal(t, uint64(17), protoFields(logRecord, 2)[0].value)
+	assert.Equal(t, "ERROR", string(protoFields(logRecord, 3)[0].data))
+	body := decodeProto(t, protoFields(logRecord, 5)[0].data)
+	assert.Equal(t, "message1", string(body[0].data))
+
+	assert.Nil(t, appender.Close())
+}
@@ -0,0 +1,484 @@
+package golog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultRedisAddress = "localhost:6379"
+
+const defaultRedisStream = "logs"
+
+const defaultRedisReadCount = 100
+
+const defaultRedisReadBlock = time.Second * 5
+
+// entry fields of the metadata, the fields of an event follow them with redisFieldPrefix
+const (
+	redisFieldTime       = "time"
+	redisFieldLevel      = "level"
+	redisFieldLogger     = "logger"
+	redisFieldMessage    = "message"
+	redisFieldTraceId    = "trace_id"
+	redisFieldSpanId     = "span_id"
+	redisFieldStackTrace = "stack_trace"
+
+	// redisFieldPrefix keeps the fields of an event apart from the metadata fields of the same names
+	redisFieldPrefix = "f."
+)
+
+// RedisStreamAppenderConfig
+type RedisStreamAppenderConfig struct {
+	// Address is the address of the server, e.g. localhost:6379
+	Address string
+
+	// Username and Password are sent by AUTH if Password is not empty
+	Username string
+	Password string
+	Database int
+
+	Stream string
+
+	// MaxLen caps the stream by MAXLEN ~, the stream is not capped if it is 0
+	MaxLen int64
+
+	MaxBatchSize  int
+	FlushInterval time.Duration
+	QueueSize     int
+	MaxRetries    int
+	Timeout       time.Duration
+}
+
+// NewDefaultRedisStreamAppenderConfig
+func NewDefaultRedisStreamAppenderConfig() RedisStreamAppenderConfig {
+	return RedisStreamAppenderConfig{
+		Address:       defaultRedisAddress,
+		Stream:        defaultRedisStream,
+		MaxBatchSize:  defaultMaxBatchSize,
+		FlushInterval: defaultBatchFlushInterval,
+		QueueSize:     defaultBatchQueueSize,
+		MaxRetries:    defaultMaxRetries,
+		Timeout:       defaultHttpTimeout,
+	}
+}
+
+// RedisStreamAppender adds events to a Redis stream by XADD.
+// Events are queued and the XADD commands of a batch are pipelined from a background goroutine.
+// An entry holds the time, the level, the logger, the message and the trace context,
+// followed by the fields of the event prefixed with "f.".
+// The connection is established again after network errors.
+type RedisStreamAppender struct {
+	config    RedisStreamAppenderConfig
+	processor *batchProcessor
+
+	// conn is used by the goroutine of the processor only
+	conn *respConn
+}
+
+// NewRedisStreamAppender returns new RedisStreamAppender
+func NewRedisStreamAppender(config RedisStreamAppenderConfig) *RedisStreamAppender {
+	if config.Address == "" {
+		config.Address = defaultRedisAddress
+	}
+
+	if config.Stream == "" {
+		config.Stream = defaultRedisStream
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHttpTimeout
+	}
+
+	appender := &RedisStreamAppender{
+		config: config,
+	}
+	appender.processor = newBatchProcessor(appender.export, config.MaxBatchSize, config.FlushInterval, config.QueueSize)
+	return appender
+}
+
+// AppendEvent implements EventAppender
+func (appender *RedisStreamAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	return appender.processor.enqueue(newEventRecord(level, logEvent, metadata))
+}
+
+// Write implements io.Writer
+// Data is added as the message of an INFO event.
+func (appender *RedisStreamAppender) Write(data []byte) (n int, err error) {
+	if err := appender.processor.enqueue(newRawEventRecord(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush implements Syncer
+func (appender *RedisStreamAppender) Flush() error {
+	return appender.processor.flush()
+}
+
+// Close implements io.Closer
+// Queued events are added before the connection is closed.
+func (appender *RedisStreamAppender) Close() error {
+	appender.processor.close()
+	if appender.conn != nil {
+		err := appender.conn.close()
+		appender.conn = nil
+		return err
+	}
+	return nil
+}
+
+// export adds the records, reconnecting and resuming after the last reply when the connection fails
+func (appender *RedisStreamAppender) export(records []eventRecord) error {
+	var errs []error
+	for attempt := 0; ; attempt++ {
+		n, replyErrs, err := appender.xadd(records)
+		errs = append(errs, replyErrs...)
+		records = records[n:]
+		if err == nil {
+			return errors.Join(errs...)
+		}
+
+		if attempt >= appender.config.MaxRetries || !waitRetry(retryBackoff(attempt), appender.processor.closing()) {
+			return errors.Join(append(errs, fmt.Errorf("giving up %d events : %w", len(records), err))...)
+		}
+	}
+}
+
+// xadd pipelines XADD of the records and returns the number of replies read.
+// Error replies are returned in replyErrs, a connection error stops reading replies and closes the connection.
+func (appender *RedisStreamAppender) xadd(records []eventRecord) (n int, replyErrs []error, err error) {
+	if appender.conn == nil {
+		appender.conn, err = dialResp(appender.config.Address, appender.config.Username,
+			appender.config.Password, appender.config.Database, appender.config.Timeout)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	for _, record := range records {
+		appender.conn.send(appender.command(record)...)
+	}
+
+	err = appender.conn.flush(appender.config.Timeout)
+	for err == nil && n < len(records) {
+		_, err = appender.conn.receive()
+		if replyErr, ok := err.(respError); ok {
+			replyErrs = append(replyErrs, replyErr)
+			err = nil
+		}
+		if err == nil {
+			n++
+		}
+	}
+
+	if err != nil {
+		appender.conn.close()
+		appender.conn = nil
+	}
+	return n, replyErrs, err
+}
+
+// command returns XADD of the record
+func (appender *RedisStreamAppender) command(record eventRecord) []string {
+	metadata := record.metadata
+	args := []string{"XADD", appender.config.Stream}
+	if appender.config.MaxLen > 0 {
+		args = append(args, "MAXLEN", "~", strconv.FormatInt(appender.config.MaxLen, 10))
+	}
+	args = append(args, "*",
+		redisFieldTime, record.time.Format(time.RFC3339Nano),
+		redisFieldLevel, levelName(record.level))
+	if metadata.LoggerName != "" {
+		args = append(args, redisFieldLogger, metadata.LoggerName)
+	}
+	args = append(args, redisFieldMessage, strings.TrimRight(record.message, "\n"))
+	if metadata.TraceId != "" {
+		args = append(args, redisFieldTraceId, metadata.TraceId, redisFieldSpanId, metadata.SpanId)
+	}
+	if len(metadata.StackTrace) > 0 {
+		args = append(args, redisFieldStackTrace, metadata.StackTrace.String())
+	}
+	for _, field := range metadata.Fields {
+		args = append(args, redisFieldPrefix+field.Key, formatLabelValue(field.Value))
+	}
+	return args
+}
+
+// RedisStreamReaderConfig
+type RedisStreamReaderConfig struct {
+	Address  string
+	Username string
+	Password string
+	Database int
+	Stream   string
+
+	// StartId is the id after which entries are read, $ for new entries and 0 for all entries
+	StartId string
+
+	// Count is the maximum number of entries of a read
+	Count int
+
+	// Block is how long a read waits for new entries
+	Block   time.Duration
+	Timeout time.Duration
+}
+
+// NewDefaultRedisStreamReaderConfig
+func NewDefaultRedisStreamReaderConfig() RedisStreamReaderConfig {
+	return RedisStreamReaderConfig{
+		Address: defaultRedisAddress,
+		Stream:  defaultRedisStream,
+		StartId: "$",
+		Count:   defaultRedisReadCount,
+		Block:   defaultRedisReadBlock,
+		Timeout: defaultHttpTimeout,
+	}
+}
+
+// RedisStreamEvent is an event read from a stream
+type RedisStreamEvent struct {
+	Id         string
+	Time       time.Time
+	Level      LogLevel
+	LoggerName string
+	Message    string
+	TraceId    string
+	SpanId     string
+	StackTrace string
+
+	// Fields are the other entry fields, their values are strings
+	Fields Fields
+}
+
+// RedisStreamReader reads the events added by RedisStreamAppender, e.g. for tailing.
+// It is not safe for concurrent use.
+type RedisStreamReader struct {
+	config RedisStreamReaderConfig
+	conn   *respConn
+	lastId string
+}
+
+// NewRedisStreamReader returns new RedisStreamReader
+func NewRedisStreamReader(config RedisStreamReaderConfig) *RedisStreamReader {
+	if config.Address == "" {
+		config.Address = defaultRedisAddress
+	}
+
+	if config.Stream == "" {
+		config.Stream = defaultRedisStream
+	}
+
+	if config.StartId == "" {
+		config.StartId = "$"
+	}
+
+	if config.Count <= 0 {
+		config.Count = defaultRedisReadCount
+	}
+
+	if config.Block <= 0 {
+		config.Block = defaultRedisReadBlock
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHttpTimeout
+	}
+
+	return &RedisStreamReader{
+		config: config,
+		lastId: config.StartId,
+	}
+}
+
+// Read returns the entries added after the last read, waiting up to Block for new entries.
+// It returns no events if there is no new entry.
+func (reader *RedisStreamReader) Read() ([]RedisStreamEvent, error) {
+	if err := reader.connect(); err != nil {
+		return nil, err
+	}
+
+	reader.conn.send("XREAD", "COUNT", strconv.Itoa(reader.config.Count),
+		"BLOCK", strconv.FormatInt(int64(reader.config.Block/time.Millisecond), 10),
+		"STREAMS", reader.config.Stream, reader.lastId)
+	err := reader.conn.flush(reader.config.Timeout + reader.config.Block)
+	var reply interface{}
+	if err == nil {
+		reply, err = reader.conn.receive()
+	}
+	if err != nil {
+		if _, ok := err.(respError); !ok {
+			reader.conn.close()
+			reader.conn = nil
+		}
+		return nil, err
+	}
+
+	var events []RedisStreamEvent
+	streams, _ := reply.([]interface{})
+	for _, stream := range streams {
+		streamReply, _ := stream.([]interface{})
+		if len(streamReply) != 2 {
+			continue
+		}
+		entries, _ := streamReply[1].([]interface{})
+		for _, entry := range entries {
+			if event, ok := parseRedisStreamEntry(entry); ok {
+				events = append(events, event)
+				reader.lastId = event.Id
+			}
+		}
+	}
+	return events, nil
+}
+
+// Tail reads entries until ctx is done and appends them to the appender as text events.
+// Connection errors and transient error replies are retried with backoff, other error replies are returned.
+func (reader *RedisStreamReader) Tail(ctx context.Context, appender Appender) error {
+	for attempt := 0; ctx.Err() == nil; {
+		events, err := reader.Read()
+		if err != nil {
+			if replyErr, ok := err.(respError); ok && !replyErr.retryable() {
+				return err
+			}
+			warnLogger.Warnf("read redis stream is failed , error : %s", err.Error())
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryBackoff(attempt)):
+			}
+			attempt++
+			continue
+		}
+		attempt = 0
+
+		for _, event := range events {
+			if err := event.appendTo(appender); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// Close implements io.Closer
+func (reader *RedisStreamReader) Close() error {
+	if reader.conn != nil {
+		err := reader.conn.close()
+		reader.conn = nil
+		return err
+	}
+	return nil
+}
+
+// connect connects if needed, resolving $ to the last id of the stream so that entries added between reads are not missed
+func (reader *RedisStreamReader) connect() error {
+	if reader.conn != nil {
+		return nil
+	}
+
+	conn, err := dialResp(reader.config.Address, reader.config.Username,
+		reader.config.Password, reader.config.Database, reader.config.Timeout)
+	if err != nil {
+		return err
+	}
+
+	if reader.lastId == "$" {
+		reply, err := conn.do("XREVRANGE", reader.config.Stream, "+", "-", "COUNT", "1")
+		if err != nil {
+			conn.close()
+			return err
+		}
+		reader.lastId = "0-0"
+		if entries, _ := reply.([]interface{}); len(entries) > 0 {
+			if event, ok := parseRedisStreamEntry(entries[0]); ok {
+				reader.lastId = event.Id
+			}
+		}
+	}
+
+	reader.conn = conn
+	return nil
+}
+
+// parseRedisStreamEntry parses an entry reply of an id and a list of fields and values
+func parseRedisStreamEntry(entry interface{}) (RedisStreamEvent, bool) {
+	entryReply, _ := entry.([]interface{})
+	if len(entryReply) != 2 {
+		return RedisStreamEvent{}, false
+	}
+
+	id, _ := entryReply[0].(string)
+	values, _ := entryReply[1].([]interface{})
+	event := RedisStreamEvent{Id: id, Level: LogLevel_INFO}
+
+	// metadata is only taken from entries of the RedisStreamAppender layout starting with the time and the level,
+	// the fields of entries added by others are kept as they are
+	known := len(values) >= 4 && values[0] == redisFieldTime && values[2] == redisFieldLevel
+	for i := 0; i+1 < len(values); i += 2 {
+		name, _ := values[i].(string)
+		value, _ := values[i+1].(string)
+
+		if !known {
+			event.Fields = append(event.Fields, F(name, value))
+			continue
+		}
+
+		switch name {
+		case redisFieldTime:
+			event.Time, _ = time.Parse(time.RFC3339Nano, value)
+		case redisFieldLevel:
+			event.Level = parseLevelName(value)
+		case redisFieldLogger:
+			event.LoggerName = value
+		case redisFieldMessage:
+			event.Message = value
+		case redisFieldTraceId:
+			event.TraceId = value
+		case redisFieldSpanId:
+			event.SpanId = value
+		case redisFieldStackTrace:
+			event.StackTrace = value
+		default:
+			event.Fields = append(event.Fields, F(strings.TrimPrefix(name, redisFieldPrefix), value))
+		}
+	}
+	return event, id != ""
+}
+
+// parseLevelName returns the level of the name returned by levelName, INFO for unknown names
+func parseLevelName(name string) LogLevel {
+	for _, level := range logLevelMap {
+		if levelName(level) == name {
+			return level
+		}
+	}
+	return LogLevel_INFO
+}
+
+// appendTo appends the event to the appender with the time, the level and the logger name as metadata
+func (event *RedisStreamEvent) appendTo(appender Appender) error {
+	message := event.Message
+	if event.StackTrace != "" {
+		message += "\n" + event.StackTrace
+	}
+
+	metadata := &LogEventMetadata{
+		LogLevel:          event.Level,
+		UnixTime:          event.Time.Unix(),
+		UnixNano:          event.Time.UnixNano(),
+		LoggerName:        event.LoggerName,
+		Fields:            event.Fields,
+		TraceId:           event.TraceId,
+		SpanId:            event.SpanId,
+		MetadataFormatter: NewDefaultMetadataFormatter(),
+		MetadataConfig: MetadataConfig{
+			IsEnabledLogLevel:   true,
+			IsEnabledTime:       true,
+			IsEnabledLoggerName: true,
+		},
+	}
+	return appendEvent(appender, event.Level, &TextLogEvent{Event: message}, metadata)
+}
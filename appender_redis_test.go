@@ -0,0 +1,415 @@
+package golog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// redisEntry
+type redisEntry struct {
+	id     string
+	fields []string
+}
+
+// redisServer is an in-process RESP stand-in of a single stream
+type redisServer struct {
+	listener net.Listener
+
+	mu sync.Mutex
+	// dropAt closes the connection on receiving the XADD of the index, -1 never
+	dropAt int
+	// xreadError is the error reply of XREAD if not empty
+	xreadError string
+	xadds      int
+	conns      int
+	lastArgs   []string
+	entries    []redisEntry
+	lastMs     int64
+	seq        int64
+}
+
+// startRedisServer
+func startRedisServer(t *testing.T) *redisServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	server := &redisServer{listener: listener, dropAt: -1}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (server *redisServer) serve(conn net.Conn) {
+	defer conn.Close()
+	server.mu.Lock()
+	server.conns++
+	server.mu.Unlock()
+
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readRespCommand(reader)
+		if err != nil {
+			return
+		}
+
+		reply, ok := server.execute(args)
+		if !ok {
+			return
+		}
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+// readRespCommand reads an array of bulk strings
+func readRespCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+// execute returns the reply of the command, false to close the connection
+func (server *redisServer) execute(args []string) ([]byte, bool) {
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		if args[len(args)-1] != "secret" {
+			return []byte("-WRONGPASS invalid password\r\n"), true
+		}
+		return []byte("+OK\r\n"), true
+	case "SELECT":
+		return []byte("+OK\r\n"), true
+	case "XADD":
+		return server.xadd(args)
+	case "XREVRANGE":
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		if len(server.entries) == 0 {
+			return []byte("*0\r\n"), true
+		}
+		return respEntries(server.entries[len(server.entries)-1:]), true
+	case "XREAD":
+		server.mu.Lock()
+		xreadError := server.xreadError
+		server.mu.Unlock()
+		if xreadError != "" {
+			return []byte("-" + xreadError + "\r\n"), true
+		}
+		return server.xread(args), true
+	default:
+		return []byte("-ERR unknown command\r\n"), true
+	}
+}
+
+func (server *redisServer) xadd(args []string) ([]byte, bool) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	index := server.xadds
+	server.xadds++
+	if index == server.dropAt {
+		return nil, false
+	}
+	server.lastArgs = args
+
+	i := 2
+	maxLen := -1
+	if args[i] == "MAXLEN" {
+		maxLen, _ = strconv.Atoi(args[i+2])
+		i += 3
+	}
+	if args[i] != "*" || (len(args)-i-1)%2 != 0 {
+		return []byte("-ERR wrong number of arguments\r\n"), true
+	}
+
+	ms := time.Now().UnixMilli()
+	if ms <= server.lastMs {
+		ms = server.lastMs
+		server.seq++
+	} else {
+		server.seq = 0
+	}
+	server.lastMs = ms
+
+	id := fmt.Sprintf("%d-%d", ms, server.seq)
+	server.entries = append(server.entries, redisEntry{id: id, fields: args[i+1:]})
+	if maxLen >= 0 && len(server.entries) > maxLen {
+		server.entries = server.entries[len(server.entries)-maxLen:]
+	}
+	return []byte(fmt.Sprintf("$%d\r\n%s\r\n", len(id), id)), true
+}
+
+func (server *redisServer) xread(args []string) []byte {
+	count, _ := strconv.Atoi(args[2])
+	block, _ := strconv.Atoi(args[4])
+	stream := args[6]
+	lastId := args[7]
+
+	deadline := time.Now().Add(time.Duration(block) * time.Millisecond)
+	for {
+		server.mu.Lock()
+		var entries []redisEntry
+		for _, entry := range server.entries {
+			if compareRedisIds(entry.id, lastId) > 0 && len(entries) < count {
+				entries = append(entries, entry)
+			}
+		}
+		server.mu.Unlock()
+
+		if len(entries) > 0 {
+			reply := fmt.Sprintf("*1\r\n*2\r\n$%d\r\n%s\r\n", len(stream), stream)
+			return append([]byte(reply), respEntries(entries)...)
+		}
+		if time.Now().After(deadline) {
+			return []byte("*-1\r\n")
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+}
+
+// respEntries encodes entries as the array of XRANGE
+func respEntries(entries []redisEntry) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n", len(entries))
+	for _, entry := range entries {
+		fmt.Fprintf(&buf, "*2\r\n$%d\r\n%s\r\n*%d\r\n", len(entry.id), entry.id, len(entry.fields))
+		for _, field := range entry.fields {
+			fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(field), field)
+		}
+	}
+	return buf.Bytes()
+}
+
+// compareRedisIds
+func compareRedisIds(a string, b string) int {
+	parse := func(id string) (int64, int64) {
+		ms, seq, _ := strings.Cut(id, "-")
+		msValue, _ := strconv.ParseInt(ms, 10, 64)
+		seqValue, _ := strconv.ParseInt(seq, 10, 64)
+		return msValue, seqValue
+	}
+	aMs, aSeq := parse(a)
+	bMs, bSeq := parse(b)
+	switch {
+	case aMs < bMs, aMs == bMs && aSeq < bSeq:
+		return -1
+	case aMs == bMs && aSeq == bSeq:
+		return 0
+	default:
+		return 1
+	}
+}
+
+func TestRedisStreamReader_TailError(t *testing.T) {
+
+	server := startRedisServer(t)
+	defer server.listener.Close()
+	server.xreadError = "WRONGTYPE Operation against a key holding the wrong kind of value"
+
+	config := NewDefaultRedisStreamReaderConfig()
+	config.Address = server.listener.Addr().String()
+	reader := NewRedisStreamReader(config)
+	defer reader.Close()
+
+	// error replies which are not transient are returned
+	err := reader.Tail(context.Background(), &redisTailAppender{})
+	assert.Equal(t, respError(server.xreadError), err)
+
+	assert.True(t, respError("LOADING Redis is loading the dataset in memory").retryable())
+	assert.False(t, respError("ERR unknown command").retryable())
+}
+
+// redisTailAppender is a locked buffer of the tailed events
+type redisTailAppender struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (appender *redisTailAppender) Write(data []byte) (int, error) {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	appender.buf.Write(data)
+	return appender.buf.WriteString("\n")
+}
+
+func (appender *redisTailAppender) Close() error {
+	return nil
+}
+
+func (appender *redisTailAppender) String() string {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	return appender.buf.String()
+}
+
+func TestRedisStreamAppender(t *testing.T) {
+
+	server := startRedisServer(t)
+	defer server.listener.Close()
+	server.dropAt = 1
+
+	config := NewDefaultRedisStreamAppenderConfig()
+	config.Address = server.listener.Addr().String()
+	config.Password = "secret"
+	config.Database = 2
+	config.MaxLen = 3
+	appender := NewRedisStreamAppender(config)
+
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.SetMetadataConfig(&MetadataConfig{IsEnabledLoggerName: true})
+	alice := logger.With(F("user", "alice"), F("count", 3))
+	alice.Info("first")
+	logger.Warn("second")
+	logger.Error("third")
+	logger.Info("fourth")
+	// retries are not waited for on close
+	assert.Nil(t, appender.Flush())
+	assert.Nil(t, logger.Close())
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	// the pipeline is resumed on a new connection after the dropped command
+	assert.Equal(t, 2, server.conns)
+	assert.Equal(t, "MAXLEN ~ 3 *", strings.Join(server.lastArgs[2:6], " "))
+
+	// the stream is capped
+	assert.Equal(t, 3, len(server.entries))
+	fields := server.entries[0].fields
+	assert.Equal(t, []string{"level", "WARN", "logger", "testLogger", "message", "second"}, fields[2:])
+	_, err := time.Parse(time.RFC3339Nano, fields[1])
+	assert.Nil(t, err)
+}
+
+func TestRedisStreamAppender_Fields(t *testing.T) {
+
+	server := startRedisServer(t)
+	defer server.listener.Close()
+
+	config := NewDefaultRedisStreamAppenderConfig()
+	config.Address = server.listener.Addr().String()
+	appender := NewRedisStreamAppender(config)
+
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.SetMetadataConfig(&MetadataConfig{})
+	alice := logger.With(F("user", "alice"), F("count", 3), F("message", "field"), F("logger", "field"))
+	alice.Info("first")
+	assert.Nil(t, logger.Close())
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, []string{"level", "INFO", "message", "first", "f.user", "alice", "f.count", "3", "f.message", "field", "f.logger", "field"}, server.entries[0].fields[2:])
+
+	// fields of the names of the metadata are not taken for the metadata
+	event, ok := parseRedisStreamEntry([]interface{}{server.entries[0].id, toInterfaces(server.entries[0].fields)})
+	assert.True(t, ok)
+	assert.Equal(t, "first", event.Message)
+	assert.Equal(t, "", event.LoggerName)
+	assert.Equal(t, LogLevel_INFO, event.Level)
+	assert.Equal(t, Fields{F("user", "alice"), F("count", "3"), F("message", "field"), F("logger", "field")}, event.Fields)
+
+	// entries added by others are not taken for the metadata
+	event, ok = parseRedisStreamEntry([]interface{}{"1-0", []interface{}{"message", "other", "level", "ERROR", "f.user", "bob"}})
+	assert.True(t, ok)
+	assert.Equal(t, "", event.Message)
+	assert.Equal(t, LogLevel_INFO, event.Level)
+	assert.Equal(t, Fields{F("message", "other"), F("level", "ERROR"), F("f.user", "bob")}, event.Fields)
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+	}
+	return result
+}
+
+func TestRedisStreamReader(t *testing.T) {
+
+	server := startRedisServer(t)
+	defer server.listener.Close()
+
+	appenderConfig := NewDefaultRedisStreamAppenderConfig()
+	appenderConfig.Address = server.listener.Addr().String()
+	appender := NewRedisStreamAppender(appenderConfig)
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.SetMetadataConfig(&MetadataConfig{IsEnabledLoggerName: true})
+	logger.Info("before")
+	assert.Nil(t, appender.Flush())
+
+	config := NewDefaultRedisStreamReaderConfig()
+	config.Address = server.listener.Addr().String()
+	config.Block = time.Millisecond * 50
+	reader := NewRedisStreamReader(config)
+	defer reader.Close()
+
+	// entries before the first read are skipped with $
+	events, err := reader.Read()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(events))
+
+	alice := logger.With(F("user", "alice"))
+	alice.Warn("after")
+	assert.Nil(t, appender.Flush())
+
+	events, err = reader.Read()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, LogLevel_WARN, events[0].Level)
+	assert.Equal(t, "testLogger", events[0].LoggerName)
+	assert.Equal(t, "after", events[0].Message)
+	assert.Equal(t, Fields{F("user", "alice")}, events[0].Fields)
+	assert.True(t, time.Since(events[0].Time) < time.Minute)
+
+	// tailing appends the events to an appender
+	tail := &redisTailAppender{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- reader.Tail(ctx, tail)
+	}()
+
+	logger.Error("tailed")
+	assert.Nil(t, logger.Close())
+	assert.Eventually(t, func() bool {
+		return strings.Contains(tail.String(), "tailed")
+	}, time.Second, time.Millisecond*10)
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	assert.Regexp(t, `^\[ERROR\] \S+ testLogger \(\) tailed\n$`, tail.String())
+}
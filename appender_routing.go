@@ -0,0 +1,401 @@
+package golog
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// RoutingKey is the value of events which selects the child appender
+type RoutingKey string
+
+const RoutingKey_LOGGER_NAME RoutingKey = "logger-name"
+const RoutingKey_FIELD RoutingKey = "field"
+const RoutingKey_LEVEL RoutingKey = "level"
+
+const defaultRoutingDefaultValue = "default"
+
+const defaultRoutingIdleTimeout = time.Minute * 5
+
+const defaultRoutingMaxOpen = 64
+
+// routingPlaceholder matches {name} in the patterns of NewRoutingFileAppenderFactory
+var routingPlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// RoutingAppenderConfig
+type RoutingAppenderConfig struct {
+	Key RoutingKey
+
+	// Field is the key of the field for RoutingKey_FIELD
+	Field string
+
+	// DefaultValue is used for events without the key, such as events without metadata or the field
+	DefaultValue string
+
+	// Factory returns the appender of a value, it is called when the first event of the value is appended
+	Factory func(value string) (Appender, error)
+
+	// IdleTimeout closes the appenders which have not been used for the duration, negative keeps them open
+	IdleTimeout time.Duration
+
+	// MaxOpen bounds the open appenders, the least recently used appender is closed to open another
+	MaxOpen int
+}
+
+// NewDefaultRoutingAppenderConfig
+func NewDefaultRoutingAppenderConfig() RoutingAppenderConfig {
+	return RoutingAppenderConfig{
+		Key:          RoutingKey_LOGGER_NAME,
+		DefaultValue: defaultRoutingDefaultValue,
+		IdleTimeout:  defaultRoutingIdleTimeout,
+		MaxOpen:      defaultRoutingMaxOpen,
+	}
+}
+
+// routingChild is an appender created for a value
+type routingChild struct {
+	value    string
+	appender Appender
+	element  *list.Element
+	lastUsed time.Time
+
+	// mu serializes the events and the close of the appender
+	mu     *sync.Mutex
+	closed bool
+}
+
+// routingCreation is a child being created by the factory, events of the value wait for it
+type routingCreation struct {
+	ready chan struct{}
+	err   error
+}
+
+// RoutingAppender splits events into child appenders by the logger name, a field or the level,
+// e.g. a file per tenant. Children are created by the factory on the first event of a value
+// and closed when idle or when MaxOpen appenders are open, a closed child is created again by the next event.
+type RoutingAppender struct {
+	config RoutingAppenderConfig
+
+	mu       *sync.Mutex
+	children map[string]*routingChild
+
+	// creating has the values whose children are being created out of mu
+	creating map[string]*routingCreation
+
+	// lru has the children from the most recently used
+	lru    *list.List
+	closed bool
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewRoutingAppender returns new RoutingAppender
+func NewRoutingAppender(config RoutingAppenderConfig) (*RoutingAppender, error) {
+	if config.Factory == nil {
+		return nil, errors.New("routing appender requires Factory")
+	}
+
+	if config.Key == "" {
+		config.Key = RoutingKey_LOGGER_NAME
+	}
+
+	if config.Key == RoutingKey_FIELD && config.Field == "" {
+		return nil, errors.New("routing appender requires Field to route by field")
+	}
+
+	if config.DefaultValue == "" {
+		config.DefaultValue = defaultRoutingDefaultValue
+	}
+
+	if config.IdleTimeout == 0 {
+		config.IdleTimeout = defaultRoutingIdleTimeout
+	}
+
+	if config.MaxOpen <= 0 {
+		config.MaxOpen = defaultRoutingMaxOpen
+	}
+
+	appender := &RoutingAppender{
+		config:   config,
+		mu:       new(sync.Mutex),
+		children: map[string]*routingChild{},
+		creating: map[string]*routingCreation{},
+		lru:      list.New(),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	if config.IdleTimeout > 0 {
+		go appender.closeIdleChildren()
+	} else {
+		close(appender.stopped)
+	}
+	return appender, nil
+}
+
+// NewRoutingFileAppenderFactory returns a factory of RotatableFileAppender at the pattern
+// with {name} replaced by the value, e.g. logs/{tenant}.log.
+// Path separators and dots of the value are replaced by _ so that it can not escape the directory.
+func NewRoutingFileAppenderFactory(pattern string) func(value string) (Appender, error) {
+	return func(value string) (Appender, error) {
+		return NewRotatableFileAppender(RoutingFileName(pattern, value))
+	}
+}
+
+// RoutingFileName returns the pattern with {name} replaced by the value safe as a file name
+func RoutingFileName(pattern string, value string) string {
+	safe := []byte(value)
+	for i, c := range safe {
+		if c == '/' || c == '\\' || c == '.' || c == 0 {
+			safe[i] = '_'
+		}
+	}
+	if len(safe) == 0 {
+		safe = []byte("_")
+	}
+	return routingPlaceholder.ReplaceAllLiteralString(pattern, string(safe))
+}
+
+// AppendEvent implements EventAppender
+func (appender *RoutingAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	return appender.route(appender.value(level, metadata), func(child Appender) error {
+		return appendEvent(child, level, logEvent, metadata)
+	})
+}
+
+// Write implements io.Writer
+// Data is routed as an INFO event without metadata.
+func (appender *RoutingAppender) Write(data []byte) (n int, err error) {
+	err = appender.route(appender.value(LogLevel_INFO, nil), func(child Appender) error {
+		_, err := child.Write(data)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush implements Syncer
+// Children which implement Syncer are flushed.
+func (appender *RoutingAppender) Flush() error {
+	var errs []error
+	for _, child := range appender.openChildren() {
+		child.mu.Lock()
+		if syncer, ok := child.appender.(Syncer); ok && !child.closed {
+			errs = append(errs, syncer.Flush())
+		}
+		child.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Close implements io.Closer
+// All the children are closed.
+func (appender *RoutingAppender) Close() error {
+	appender.mu.Lock()
+	if appender.closed {
+		appender.mu.Unlock()
+		return nil
+	}
+	appender.closed = true
+	close(appender.done)
+
+	children := make([]*routingChild, 0, len(appender.children))
+	for _, child := range appender.children {
+		children = append(children, appender.remove(child))
+	}
+	appender.mu.Unlock()
+
+	<-appender.stopped
+	return closeRoutingChildren(children)
+}
+
+// value returns the key of the event
+func (appender *RoutingAppender) value(level LogLevel, metadata *LogEventMetadata) string {
+	var value string
+	switch appender.config.Key {
+	case RoutingKey_LEVEL:
+		value = levelName(level)
+	case RoutingKey_FIELD:
+		if metadata != nil {
+			for _, field := range metadata.Fields {
+				if field.Key == appender.config.Field {
+					value = formatLabelValue(field.Value)
+				}
+			}
+		}
+	default:
+		if metadata != nil {
+			value = metadata.LoggerName
+		}
+	}
+
+	if value == "" {
+		return appender.config.DefaultValue
+	}
+	return value
+}
+
+// route calls write with the child of the value, creating it if it is not open
+func (appender *RoutingAppender) route(value string, write func(child Appender) error) error {
+	for {
+		child, evicted, err := appender.child(value)
+		if err != nil {
+			return err
+		}
+		warnExportError(closeRoutingChildren(evicted))
+
+		child.mu.Lock()
+		if child.closed {
+			// closed while waiting, the child is opened again
+			child.mu.Unlock()
+			continue
+		}
+		err = write(child.appender)
+		child.mu.Unlock()
+		return err
+	}
+}
+
+// child returns the open child of the value and the children to close to keep MaxOpen.
+// The factory is called without holding mu, so that a slow factory does not block the other values.
+func (appender *RoutingAppender) child(value string) (*routingChild, []*routingChild, error) {
+	appender.mu.Lock()
+	for {
+		if appender.closed {
+			appender.mu.Unlock()
+			return nil, nil, errAppenderClosed
+		}
+
+		if child, ok := appender.children[value]; ok {
+			child.lastUsed = time.Now()
+			appender.lru.MoveToFront(child.element)
+			appender.mu.Unlock()
+			return child, nil, nil
+		}
+
+		creation, ok := appender.creating[value]
+		if !ok {
+			break
+		}
+
+		// the child of the value is being created by another event
+		appender.mu.Unlock()
+		<-creation.ready
+		if creation.err != nil {
+			return nil, nil, creation.err
+		}
+		appender.mu.Lock()
+	}
+
+	creation := &routingCreation{ready: make(chan struct{})}
+	appender.creating[value] = creation
+	appender.mu.Unlock()
+
+	created, err := appender.config.Factory(value)
+
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	delete(appender.creating, value)
+	creation.err = err
+	close(creation.ready)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if appender.closed {
+		// closed while creating, the child is not kept
+		warnExportError(created.Close())
+		return nil, nil, errAppenderClosed
+	}
+
+	child := &routingChild{
+		value:    value,
+		appender: created,
+		lastUsed: time.Now(),
+		mu:       new(sync.Mutex),
+	}
+	child.element = appender.lru.PushFront(child)
+	appender.children[value] = child
+
+	var evicted []*routingChild
+	for len(appender.children) > appender.config.MaxOpen {
+		evicted = append(evicted, appender.remove(appender.lru.Back().Value.(*routingChild)))
+	}
+	return child, evicted, nil
+}
+
+// remove removes the child from the open children, appender.mu must be held
+func (appender *RoutingAppender) remove(child *routingChild) *routingChild {
+	delete(appender.children, child.value)
+	appender.lru.Remove(child.element)
+	return child
+}
+
+// openChildren
+func (appender *RoutingAppender) openChildren() []*routingChild {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	children := make([]*routingChild, 0, len(appender.children))
+	for element := appender.lru.Front(); element != nil; element = element.Next() {
+		children = append(children, element.Value.(*routingChild))
+	}
+	return children
+}
+
+// closeIdleChildren closes the children idle for IdleTimeout until the appender is closed
+func (appender *RoutingAppender) closeIdleChildren() {
+	defer close(appender.stopped)
+
+	interval := appender.config.IdleTimeout / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-appender.done:
+			return
+		}
+
+		appender.mu.Lock()
+		var idle []*routingChild
+		deadline := time.Now().Add(-appender.config.IdleTimeout)
+		for element := appender.lru.Back(); element != nil; {
+			child := element.Value.(*routingChild)
+			element = element.Prev()
+			if child.lastUsed.After(deadline) {
+				break
+			}
+			idle = append(idle, appender.remove(child))
+		}
+		appender.mu.Unlock()
+
+		warnExportError(closeRoutingChildren(idle))
+	}
+}
+
+// closeRoutingChildren closes the appenders of the children
+func closeRoutingChildren(children []*routingChild) error {
+	var errs []error
+	for _, child := range children {
+		child.mu.Lock()
+		if !child.closed {
+			child.closed = true
+			if err := child.appender.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("close appender of %s : %w", child.value, err))
+			}
+		}
+		child.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,330 @@
+package golog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// routingTestAppender records the written data
+type routingTestAppender struct {
+	mu     *sync.Mutex
+	data   []string
+	closed bool
+}
+
+func (appender *routingTestAppender) Write(data []byte) (int, error) {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	appender.data = append(appender.data, string(data))
+	return len(data), nil
+}
+
+func (appender *routingTestAppender) Close() error {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	appender.closed = true
+	return nil
+}
+
+// isClosed
+func (appender *routingTestAppender) isClosed() bool {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	return appender.closed
+}
+
+// routingTestFactory creates routingTestAppender and keeps them by value, the latest one is kept for a value
+type routingTestFactory struct {
+	mu        *sync.Mutex
+	appenders map[string]*routingTestAppender
+	calls     int
+}
+
+// newRoutingTestFactory
+func newRoutingTestFactory() *routingTestFactory {
+	return &routingTestFactory{mu: new(sync.Mutex), appenders: map[string]*routingTestAppender{}}
+}
+
+// create implements RoutingAppenderConfig.Factory
+func (factory *routingTestFactory) create(value string) (Appender, error) {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+	factory.calls++
+	appender := &routingTestAppender{mu: new(sync.Mutex)}
+	factory.appenders[value] = appender
+	return appender, nil
+}
+
+// appender returns the latest appender of the value
+func (factory *routingTestFactory) appender(value string) *routingTestAppender {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+	return factory.appenders[value]
+}
+
+// callCount
+func (factory *routingTestFactory) callCount() int {
+	factory.mu.Lock()
+	defer factory.mu.Unlock()
+	return factory.calls
+}
+
+func TestRoutingFileName(t *testing.T) {
+	assert.Equal(t, "logs/acme.log", RoutingFileName("logs/{tenant}.log", "acme"))
+	assert.Equal(t, "logs/___etc.log", RoutingFileName("logs/{tenant}.log", "../etc"))
+	assert.Equal(t, "logs/a_b_c.log", RoutingFileName("logs/{tenant}.log", "a\\b/c"))
+	assert.Equal(t, "logs/_.log", RoutingFileName("logs/{tenant}.log", ""))
+	assert.Equal(t, "logs/x/x.log", RoutingFileName("logs/{a}/{b}.log", "x"))
+}
+
+func TestRoutingAppender_key(t *testing.T) {
+	// field
+	func() {
+		factory := newRoutingTestFactory()
+		config := NewDefaultRoutingAppenderConfig()
+		config.Key = RoutingKey_FIELD
+		config.Field = "tenant_id"
+		config.Factory = factory.create
+		appender, err := NewRoutingAppender(config)
+		assert.Nil(t, err)
+
+		logger := NewLogger("api", LogLevel_INFO, appender)
+		acme := logger.With(F("tenant_id", "acme"))
+		acme.Info("acme 1")
+		globex := logger.With(F("tenant_id", "globex"))
+		globex.Info("globex 1")
+		acme.Info("acme 2")
+		logger.Info("no tenant")
+		assert.Nil(t, logger.Close())
+
+		assert.Equal(t, 3, factory.callCount())
+		assert.Equal(t, 2, len(factory.appender("acme").data))
+		assert.Contains(t, factory.appender("acme").data[0], "acme 1")
+		assert.Contains(t, factory.appender("acme").data[1], "acme 2")
+		assert.Equal(t, 1, len(factory.appender("globex").data))
+		assert.Contains(t, factory.appender("default").data[0], "no tenant")
+		for _, value := range []string{"acme", "globex", "default"} {
+			assert.True(t, factory.appender(value).isClosed(), value)
+		}
+	}()
+
+	// level
+	func() {
+		factory := newRoutingTestFactory()
+		config := NewDefaultRoutingAppenderConfig()
+		config.Key = RoutingKey_LEVEL
+		config.Factory = factory.create
+		appender, err := NewRoutingAppender(config)
+		assert.Nil(t, err)
+		defer appender.Close()
+
+		logger := NewLogger("api", LogLevel_DEBUG, appender)
+		logger.Info("info")
+		logger.Error("error")
+		logger.Info("info")
+		assert.Equal(t, 2, len(factory.appender("INFO").data))
+		assert.Equal(t, 1, len(factory.appender("ERROR").data))
+
+		// data written directly is INFO
+		_, err = appender.Write([]byte("raw"))
+		assert.Nil(t, err)
+		assert.Equal(t, "raw", factory.appender("INFO").data[2])
+	}()
+
+	// logger name
+	func() {
+		factory := newRoutingTestFactory()
+		config := NewDefaultRoutingAppenderConfig()
+		config.Factory = factory.create
+		appender, err := NewRoutingAppender(config)
+		assert.Nil(t, err)
+		defer appender.Close()
+
+		billing := NewLogger("billing", LogLevel_INFO, appender)
+		billing.SetMetadataConfig(&MetadataConfig{IsEnabledLoggerName: true})
+		billing.Info("charged")
+		anonymous := NewLogger("anonymous", LogLevel_INFO, appender)
+		anonymous.SetMetadataConfig(&MetadataConfig{})
+		anonymous.Info("no name")
+
+		assert.Contains(t, factory.appender("billing").data[0], "charged")
+		assert.Contains(t, factory.appender("default").data[0], "no name")
+	}()
+}
+
+func TestRoutingAppender_maxOpen(t *testing.T) {
+	factory := newRoutingTestFactory()
+	config := NewDefaultRoutingAppenderConfig()
+	config.Key = RoutingKey_FIELD
+	config.Field = "tenant_id"
+	config.MaxOpen = 2
+	config.Factory = factory.create
+	appender, err := NewRoutingAppender(config)
+	assert.Nil(t, err)
+	defer appender.Close()
+
+	write := func(tenant string) {
+		assert.Nil(t, appender.AppendEvent(LogLevel_INFO, &TextLogEvent{Event: tenant}, &LogEventMetadata{Fields: Fields{F("tenant_id", tenant)}}))
+	}
+
+	write("a")
+	write("b")
+	write("a")
+	// b is the least recently used
+	write("c")
+	assert.Equal(t, 3, factory.callCount())
+	assert.False(t, factory.appender("a").isClosed())
+	assert.True(t, factory.appender("b").isClosed())
+	assert.False(t, factory.appender("c").isClosed())
+
+	// b is opened again and a is closed
+	closedB := factory.appender("b")
+	write("b")
+	assert.Equal(t, 4, factory.callCount())
+	assert.NotEqual(t, closedB, factory.appender("b"))
+	assert.True(t, factory.appender("a").isClosed())
+	assert.Equal(t, 2, len(appender.openChildren()))
+}
+
+func TestRoutingAppender_idleTimeout(t *testing.T) {
+	factory := newRoutingTestFactory()
+	config := NewDefaultRoutingAppenderConfig()
+	config.Key = RoutingKey_LEVEL
+	config.IdleTimeout = time.Millisecond * 100
+	config.Factory = factory.create
+	appender, err := NewRoutingAppender(config)
+	assert.Nil(t, err)
+	defer appender.Close()
+
+	_, err = appender.Write([]byte("first"))
+	assert.Nil(t, err)
+	first := factory.appender("INFO")
+	assert.Eventually(t, first.isClosed, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, 0, len(appender.openChildren()))
+
+	// the next event opens the child again
+	_, err = appender.Write([]byte("second"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, factory.callCount())
+	assert.Equal(t, []string{"second"}, factory.appender("INFO").data)
+}
+
+func TestRoutingAppender_close(t *testing.T) {
+	factory := newRoutingTestFactory()
+	config := NewDefaultRoutingAppenderConfig()
+	config.Key = RoutingKey_LEVEL
+	config.IdleTimeout = -1
+	config.Factory = factory.create
+	appender, err := NewRoutingAppender(config)
+	assert.Nil(t, err)
+
+	_, err = appender.Write([]byte("message"))
+	assert.Nil(t, err)
+	assert.Nil(t, appender.Flush())
+	assert.Nil(t, appender.Close())
+	assert.True(t, factory.appender("INFO").isClosed())
+
+	assert.Equal(t, errAppenderClosed, appender.AppendEvent(LogLevel_INFO, &TextLogEvent{Event: "closed"}, nil))
+	_, err = appender.Write([]byte("closed"))
+	assert.Equal(t, errAppenderClosed, err)
+	assert.Nil(t, appender.Close())
+	assert.Equal(t, 1, factory.callCount())
+}
+
+func TestRoutingAppender_slowFactory(t *testing.T) {
+	factory := newRoutingTestFactory()
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	config := NewDefaultRoutingAppenderConfig()
+	config.Key = RoutingKey_LEVEL
+	config.IdleTimeout = -1
+	config.Factory = func(value string) (Appender, error) {
+		if value == "WARN" {
+			started <- struct{}{}
+			<-release
+		}
+		return factory.create(value)
+	}
+	appender, err := NewRoutingAppender(config)
+	assert.Nil(t, err)
+
+	// events of a value wait for the child being created
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, appender.AppendEvent(LogLevel_WARN, &TextLogEvent{Event: "slow"}, nil))
+		}()
+	}
+
+	// the other values are not blocked by the slow factory
+	<-started
+	assert.Nil(t, appender.AppendEvent(LogLevel_INFO, &TextLogEvent{Event: "fast"}, nil))
+	assert.Equal(t, []string{"fast"}, factory.appender("INFO").data)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []string{"slow", "slow"}, factory.appender("WARN").data)
+	assert.Equal(t, 2, factory.callCount())
+	assert.Nil(t, appender.Close())
+}
+
+func TestRoutingAppender_errors(t *testing.T) {
+	// factory error
+	func() {
+		config := NewDefaultRoutingAppenderConfig()
+		config.Factory = func(value string) (Appender, error) {
+			return nil, errors.New("no space left")
+		}
+		appender, err := NewRoutingAppender(config)
+		assert.Nil(t, err)
+		defer appender.Close()
+
+		_, err = appender.Write([]byte("message"))
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, len(appender.openChildren()))
+	}()
+
+	// configs
+	func() {
+		_, err := NewRoutingAppender(NewDefaultRoutingAppenderConfig())
+		assert.NotNil(t, err)
+
+		config := NewDefaultRoutingAppenderConfig()
+		config.Key = RoutingKey_FIELD
+		config.Factory = newRoutingTestFactory().create
+		_, err = NewRoutingAppender(config)
+		assert.NotNil(t, err)
+	}()
+}
+
+func TestRoutingAppender_files(t *testing.T) {
+	dir := t.TempDir()
+
+	config := NewDefaultRoutingAppenderConfig()
+	config.Key = RoutingKey_FIELD
+	config.Field = "tenant"
+	config.Factory = NewRoutingFileAppenderFactory(filepath.Join(dir, "{tenant}.log"))
+	appender, err := NewRoutingAppender(config)
+	assert.Nil(t, err)
+
+	logger := NewLogger("api", LogLevel_INFO, appender)
+	for _, tenant := range []string{"acme", "globex", "../escape"} {
+		tenantLogger := logger.With(F("tenant", tenant))
+		tenantLogger.Info("hello " + tenant)
+	}
+	assert.Nil(t, logger.Close())
+
+	for file, message := range map[string]string{"acme.log": "hello acme", "globex.log": "hello globex", "___escape.log": "hello ../escape"} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		assert.Nil(t, err, file)
+		assert.True(t, strings.Contains(string(data), message), file)
+	}
+}
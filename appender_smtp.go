@@ -0,0 +1,473 @@
+package golog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// DefaultSmtpSubjectTemplate
+const DefaultSmtpSubjectTemplate = `[{{.Level}}] {{.Count}} events on {{.Host}}`
+
+// DefaultSmtpTextTemplate
+const DefaultSmtpTextTemplate = `{{.Count}} events from {{.Start.Format "2006-01-02 15:04:05 MST"}} to {{.End.Format "2006-01-02 15:04:05 MST"}} on {{.Host}}
+{{- if .Dropped}}, {{.Dropped}} more events are not included{{end}}
+{{range .Events}}
+{{.Time.Format "15:04:05.000"}} [{{.Level}}] {{if .LoggerName}}{{.LoggerName}}: {{end}}{{.Message}}
+{{- if .SourceFile}} ({{.SourceFile}}:{{.SourceLine}}){{end}}
+{{- range $key, $value := .Fields}}
+    {{$key}}={{$value}}
+{{- end}}
+{{- if .StackTrace}}
+{{.StackTrace}}
+{{- end}}
+{{end}}`
+
+// DefaultSmtpHtmlTemplate
+const DefaultSmtpHtmlTemplate = `<html><body>
+<p>{{.Count}} events from {{.Start.Format "2006-01-02 15:04:05 MST"}} to {{.End.Format "2006-01-02 15:04:05 MST"}} on {{.Host}}
+{{- if .Dropped}}, {{.Dropped}} more events are not included{{end}}</p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Time</th><th>Level</th><th>Logger</th><th>Message</th><th>Source</th></tr>
+{{- range .Events}}
+<tr><td>{{.Time.Format "15:04:05.000"}}</td><td>{{.Level}}</td><td>{{.LoggerName}}</td><td><pre>{{.Message}}
+{{- range $key, $value := .Fields}}
+{{$key}}={{$value}}
+{{- end}}
+{{- if .StackTrace}}
+{{.StackTrace}}
+{{- end}}</pre></td><td>{{if .SourceFile}}{{.SourceFile}}:{{.SourceLine}}{{end}}</td></tr>
+{{- end}}
+</table>
+</body></html>`
+
+const defaultSmtpAddress = "localhost:587"
+
+const defaultSmtpWindow = time.Minute * 5
+
+const defaultSmtpMaxEvents = 100
+
+const defaultSmtpTimeout = time.Second * 30
+
+// SmtpAppenderConfig
+type SmtpAppenderConfig struct {
+	// Address of the SMTP server, host:port
+	Address string
+
+	// Username and Password are sent by AUTH PLAIN if Username is not empty,
+	// which net/smtp allows only over TLS or to localhost
+	Username string
+	Password string
+
+	// STARTTLS is used if the server supports it.
+	// RequireStartTls fails sending if it does not, TlsConfig defaults to the server name of Address.
+	RequireStartTls bool
+	TlsConfig       *tls.Config
+
+	From string
+	To   []string
+
+	// SubjectTemplate and TextTemplate are text/template and HtmlTemplate is html/template of a SmtpDigest.
+	// The email has both bodies as multipart/alternative, only the text body if HtmlTemplate is empty.
+	SubjectTemplate string
+	TextTemplate    string
+	HtmlTemplate    string
+
+	// Window is the period in which events are collected into a digest, it starts at the first event
+	Window time.Duration
+
+	// MaxEvents bounds the events in a digest, the rest is counted as Dropped
+	MaxEvents int
+
+	MaxRetries int
+	Timeout    time.Duration
+}
+
+// NewDefaultSmtpAppenderConfig
+func NewDefaultSmtpAppenderConfig() SmtpAppenderConfig {
+	return SmtpAppenderConfig{
+		Address:         defaultSmtpAddress,
+		RequireStartTls: true,
+		SubjectTemplate: DefaultSmtpSubjectTemplate,
+		TextTemplate:    DefaultSmtpTextTemplate,
+		HtmlTemplate:    DefaultSmtpHtmlTemplate,
+		Window:          defaultSmtpWindow,
+		MaxEvents:       defaultSmtpMaxEvents,
+		MaxRetries:      defaultMaxRetries,
+		Timeout:         defaultSmtpTimeout,
+	}
+}
+
+// SmtpDigest is the data of the templates
+type SmtpDigest struct {
+	// Host is the host name
+	Host string
+
+	// Level is the highest level of the events
+	Level string
+
+	// Count is the number of the events in the window, including Dropped
+	Count   int
+	Dropped int
+
+	Events []AlertEvent
+
+	// Start and End are the times of the first and the last events
+	Start time.Time
+	End   time.Time
+
+	level LogLevel
+}
+
+// SmtpAppender sends a digest of events by email.
+// It is meant for ERROR and FATAL events, set with SetAppenderWithLevels.
+// The first event starts a window, and the events of the window are sent in one email at its end.
+type SmtpAppender struct {
+	config   SmtpAppenderConfig
+	host     string
+	subject  *template.Template
+	text     *template.Template
+	html     *htmltemplate.Template
+	hostname string
+
+	mu      *sync.Mutex
+	digest  *SmtpDigest
+	timer   *time.Timer
+	closed  bool
+	pending *sync.WaitGroup
+
+	// closing is closed by Close to stop waiting for retries
+	closing chan struct{}
+}
+
+// NewSmtpAppender returns new SmtpAppender, or an error if a template can not be parsed
+func NewSmtpAppender(config SmtpAppenderConfig) (*SmtpAppender, error) {
+	if config.Address == "" {
+		config.Address = defaultSmtpAddress
+	}
+
+	if config.From == "" || len(config.To) == 0 {
+		return nil, errors.New("smtp appender requires From and To")
+	}
+
+	if config.SubjectTemplate == "" {
+		config.SubjectTemplate = DefaultSmtpSubjectTemplate
+	}
+
+	if config.TextTemplate == "" {
+		config.TextTemplate = DefaultSmtpTextTemplate
+	}
+
+	if config.Window <= 0 {
+		config.Window = defaultSmtpWindow
+	}
+
+	if config.MaxEvents <= 0 {
+		config.MaxEvents = defaultSmtpMaxEvents
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultSmtpTimeout
+	}
+
+	host, _, err := net.SplitHostPort(config.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	appender := &SmtpAppender{
+		config:  config,
+		host:    host,
+		mu:      new(sync.Mutex),
+		pending: new(sync.WaitGroup),
+		closing: make(chan struct{}),
+	}
+	appender.hostname, _ = os.Hostname()
+
+	if appender.subject, err = template.New("subject").Parse(config.SubjectTemplate); err != nil {
+		return nil, err
+	}
+	if appender.text, err = template.New("text").Parse(config.TextTemplate); err != nil {
+		return nil, err
+	}
+	if config.HtmlTemplate != "" {
+		if appender.html, err = htmltemplate.New("html").Parse(config.HtmlTemplate); err != nil {
+			return nil, err
+		}
+	}
+	return appender, nil
+}
+
+// AppendEvent implements EventAppender
+func (appender *SmtpAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	return appender.append(newEventRecord(level, logEvent, metadata))
+}
+
+// Write implements io.Writer
+// Data is sent as the message of an INFO event.
+func (appender *SmtpAppender) Write(data []byte) (n int, err error) {
+	if err := appender.append(newRawEventRecord(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush implements Syncer
+// The digest is sent without waiting for the end of the window.
+func (appender *SmtpAppender) Flush() error {
+	appender.mu.Lock()
+	digest := appender.takeDigest()
+	appender.mu.Unlock()
+
+	var err error
+	if digest != nil {
+		err = appender.send(*digest)
+		appender.pending.Done()
+	}
+	appender.pending.Wait()
+	return err
+}
+
+// Close implements io.Closer
+func (appender *SmtpAppender) Close() error {
+	appender.mu.Lock()
+	if appender.closed {
+		appender.mu.Unlock()
+		return nil
+	}
+	appender.closed = true
+	close(appender.closing)
+	appender.mu.Unlock()
+
+	return appender.Flush()
+}
+
+// append adds the event to the digest, starting a window if it is the first
+func (appender *SmtpAppender) append(record eventRecord) error {
+	event := newAlertEvent(record)
+
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	if appender.closed {
+		return errAppenderClosed
+	}
+
+	digest := appender.digest
+	if digest == nil {
+		digest = &SmtpDigest{
+			Host:  appender.hostname,
+			Level: event.Level,
+			Start: event.Time,
+			level: record.level,
+		}
+		appender.digest = digest
+		appender.timer = time.AfterFunc(appender.config.Window, func() { appender.endWindow(digest) })
+	}
+
+	digest.Count++
+	digest.End = event.Time
+	if record.level.TypeVal() > digest.level.TypeVal() {
+		digest.Level = event.Level
+		digest.level = record.level
+	}
+	if len(digest.Events) < appender.config.MaxEvents {
+		digest.Events = append(digest.Events, event)
+	} else {
+		digest.Dropped++
+	}
+	return nil
+}
+
+// endWindow sends the digest unless it has been sent by Flush
+func (appender *SmtpAppender) endWindow(digest *SmtpDigest) {
+	appender.mu.Lock()
+	if appender.digest != digest {
+		appender.mu.Unlock()
+		return
+	}
+	appender.takeDigest()
+	appender.mu.Unlock()
+
+	warnExportError(appender.send(*digest))
+	appender.pending.Done()
+}
+
+// takeDigest returns the digest of the window and ends it, appender.mu must be held.
+// pending is incremented for the digest, the caller calls Done after sending it.
+func (appender *SmtpAppender) takeDigest() *SmtpDigest {
+	digest := appender.digest
+	if digest == nil {
+		return nil
+	}
+	appender.digest = nil
+	appender.timer.Stop()
+	appender.pending.Add(1)
+	return digest
+}
+
+// send renders the digest and sends it, retrying up to MaxRetries times
+func (appender *SmtpAppender) send(digest SmtpDigest) error {
+	message, err := appender.render(digest)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = appender.sendMail(message)
+		if err == nil || attempt >= appender.config.MaxRetries {
+			return err
+		}
+		if smtpErr, ok := err.(*textproto.Error); ok && smtpErr.Code >= 500 {
+			// permanent failures are not retried
+			return err
+		}
+		if !waitRetry(retryBackoff(attempt), appender.closing) {
+			return err
+		}
+	}
+}
+
+// render returns the email of the digest
+func (appender *SmtpAppender) render(digest SmtpDigest) ([]byte, error) {
+	var subject, text, html bytes.Buffer
+	if err := appender.subject.Execute(&subject, digest); err != nil {
+		return nil, err
+	}
+	if err := appender.text.Execute(&text, digest); err != nil {
+		return nil, err
+	}
+	if appender.html != nil {
+		if err := appender.html.Execute(&html, digest); err != nil {
+			return nil, err
+		}
+	}
+
+	var message bytes.Buffer
+	header := func(name string, value string) {
+		message.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", appender.config.From)
+	header("To", strings.Join(appender.config.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject.String()), " ")))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+NewRequestId()+"@"+appender.hostname+">")
+	header("MIME-Version", "1.0")
+
+	if appender.html == nil {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		message.WriteString("\r\n")
+		if err := writeQuotedPrintable(&message, text.Bytes()); err != nil {
+			return nil, err
+		}
+		return message.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header("Content-Type", "multipart/alternative; boundary="+writer.Boundary())
+	message.WriteString("\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		data        []byte
+	}{{"text/plain; charset=utf-8", text.Bytes()}, {"text/html; charset=utf-8", html.Bytes()}} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(partWriter, part.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+// writeQuotedPrintable writes data with CRLF line endings in quoted-printable
+func writeQuotedPrintable(writer io.Writer, data []byte) error {
+	encoder := quotedprintable.NewWriter(writer)
+	if _, err := encoder.Write(bytes.ReplaceAll(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// sendMail sends the message over a new connection, upgraded by STARTTLS if supported
+func (appender *SmtpAppender) sendMail(message []byte) error {
+	conn, err := net.DialTimeout("tcp", appender.config.Address, appender.config.Timeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(appender.config.Timeout))
+
+	client, err := smtp.NewClient(conn, appender.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		tlsConfig := &tls.Config{ServerName: appender.host}
+		if appender.config.TlsConfig != nil {
+			tlsConfig = appender.config.TlsConfig.Clone()
+			if tlsConfig.ServerName == "" {
+				tlsConfig.ServerName = appender.host
+			}
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	} else if appender.config.RequireStartTls {
+		return fmt.Errorf("smtp server %s does not support STARTTLS", appender.config.Address)
+	}
+
+	if appender.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", appender.config.Username, appender.config.Password, appender.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(appender.config.From); err != nil {
+		return err
+	}
+	for _, to := range appender.config.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
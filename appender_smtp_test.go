@@ -0,0 +1,302 @@
+package golog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// smtpMail is a mail received by smtpServer
+type smtpMail struct {
+	from string
+	to   []string
+	data string
+	auth string
+	tls  bool
+}
+
+// smtpServer is a fake SMTP server supporting STARTTLS and AUTH PLAIN
+type smtpServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	mu        *sync.Mutex
+	mails     []smtpMail
+}
+
+// newSmtpServer starts the server, STARTTLS is offered if tlsConfig is not nil
+func newSmtpServer(t *testing.T, tlsConfig *tls.Config) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	server := &smtpServer{listener: listener, tlsConfig: tlsConfig, mu: new(sync.Mutex)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+// received returns the mails received so far
+func (server *smtpServer) received() []smtpMail {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]smtpMail(nil), server.mails...)
+}
+
+// serve
+func (server *smtpServer) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP")
+
+	var mail smtpMail
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch command {
+		case "EHLO", "HELO":
+			if server.tlsConfig != nil && !mail.tls {
+				reply("250-localhost")
+				reply("250-STARTTLS")
+			} else {
+				reply("250-localhost")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, server.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, reader = tlsConn, bufio.NewReader(tlsConn)
+			mail.tls = true
+		case "AUTH":
+			fields := strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			mail.auth = string(decoded)
+			reply("235 authenticated")
+		case "MAIL":
+			mail.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			reply("250 ok")
+		case "RCPT":
+			mail.to = append(mail.to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			mail.data = data.String()
+			server.mu.Lock()
+			server.mails = append(server.mails, mail)
+			server.mu.Unlock()
+			mail = smtpMail{tls: mail.tls}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// close
+func (server *smtpServer) close() {
+	server.listener.Close()
+}
+
+// parseSmtpMail returns the decoded subject and the bodies by content type
+func parseSmtpMail(t *testing.T, data string) (string, map[string]string) {
+	message, err := mail.ReadMessage(strings.NewReader(data))
+	assert.Nil(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	assert.Nil(t, err)
+
+	bodies := map[string]string{}
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	assert.Nil(t, err)
+	if mediaType != "multipart/alternative" {
+		body, _ := io.ReadAll(quotedprintable.NewReader(message.Body))
+		bodies[mediaType] = string(body)
+		return subject, bodies
+	}
+
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(part)
+		bodies[partType] = string(body)
+	}
+	return subject, bodies
+}
+
+// newTestSmtpConfig
+func newTestSmtpConfig(server *smtpServer) SmtpAppenderConfig {
+	config := NewDefaultSmtpAppenderConfig()
+	config.Address = server.listener.Addr().String()
+	config.From = "golog@example.com"
+	config.To = []string{"oncall@example.com", "dev@example.com"}
+	config.MaxRetries = 0
+	config.Timeout = time.Second * 5
+	return config
+}
+
+func TestSmtpAppender_digest(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil, 0)
+	certificate := newTestCertificate(t, "localhost", ca, x509.ExtKeyUsageServerAuth)
+	serverCertificate, err := tls.X509KeyPair(certificate.certPem, certificate.keyPem)
+	assert.Nil(t, err)
+
+	server := newSmtpServer(t, &tls.Config{Certificates: []tls.Certificate{serverCertificate}})
+	defer server.close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.certificate)
+	config := newTestSmtpConfig(server)
+	config.TlsConfig = &tls.Config{RootCAs: pool}
+	config.Username = "user"
+	config.Password = "secret"
+	config.MaxEvents = 2
+	config.SubjectTemplate = `[{{.Level}}] {{.Count}} events from {{(index .Events 0).LoggerName}}`
+	appender, err := NewSmtpAppender(config)
+	assert.Nil(t, err)
+
+	metadata := &LogEventMetadata{LoggerName: "api", SourceFile: "main.go", SourceLine: 10, Fields: Fields{F("user", "alice")}}
+	assert.Nil(t, appender.AppendEvent(LogLevel_ERROR, &TextLogEvent{Event: "payment <failed>"}, metadata))
+	assert.Nil(t, appender.AppendEvent(LogLevel_FATAL, &TextLogEvent{Event: "giving up"}, metadata))
+	assert.Nil(t, appender.AppendEvent(LogLevel_ERROR, &TextLogEvent{Event: "dropped"}, metadata))
+	assert.Nil(t, appender.Close())
+	assert.Equal(t, errAppenderClosed, appender.AppendEvent(LogLevel_ERROR, &TextLogEvent{Event: "closed"}, nil))
+
+	mails := server.received()
+	assert.Equal(t, 1, len(mails))
+	assert.True(t, mails[0].tls)
+	assert.Equal(t, "\x00user\x00secret", mails[0].auth)
+	assert.Equal(t, "golog@example.com", mails[0].from)
+	assert.Equal(t, []string{"oncall@example.com", "dev@example.com"}, mails[0].to)
+
+	subject, bodies := parseSmtpMail(t, mails[0].data)
+	assert.Equal(t, "[FATAL] 3 events from api", subject)
+
+	text := bodies["text/plain"]
+	assert.Contains(t, text, ", 1 more events are not included")
+	assert.Contains(t, text, "[ERROR] api: payment <failed> (main.go:10)\r\n    user=alice\r\n")
+	assert.Contains(t, text, "[FATAL] api: giving up (main.go:10)")
+	assert.NotContains(t, text, "dropped")
+
+	html := bodies["text/html"]
+	assert.Contains(t, html, "<pre>payment &lt;failed&gt;\r\nuser=alice</pre>")
+	assert.Contains(t, html, "<td>main.go:10</td>")
+}
+
+func TestSmtpAppender_window(t *testing.T) {
+	server := newSmtpServer(t, nil)
+	defer server.close()
+
+	config := newTestSmtpConfig(server)
+	config.RequireStartTls = false
+	config.HtmlTemplate = ""
+	config.Window = time.Millisecond * 200
+	appender, err := NewSmtpAppender(config)
+	assert.Nil(t, err)
+	defer appender.Close()
+
+	appender.Write([]byte("first"))
+	appender.Write([]byte("second"))
+	assert.Eventually(t, func() bool { return len(server.received()) == 1 }, time.Second*5, time.Millisecond*10)
+
+	mail := server.received()[0]
+	assert.False(t, mail.tls)
+	assert.Equal(t, "", mail.auth)
+	subject, bodies := parseSmtpMail(t, mail.data)
+	assert.True(t, strings.HasPrefix(subject, "[INFO] 2 events on "))
+	assert.Equal(t, []string{"text/plain"}, func() []string {
+		var types []string
+		for contentType := range bodies {
+			types = append(types, contentType)
+		}
+		return types
+	}())
+	assert.Contains(t, bodies["text/plain"], "[INFO] first\r\n")
+	assert.Contains(t, bodies["text/plain"], "[INFO] second\r\n")
+
+	// the next event starts a new window
+	appender.Write([]byte("third"))
+	assert.Nil(t, appender.Flush())
+	assert.Equal(t, 2, len(server.received()))
+	assert.Nil(t, appender.Flush())
+	assert.Equal(t, 2, len(server.received()))
+}
+
+func TestSmtpAppender_errors(t *testing.T) {
+	server := newSmtpServer(t, nil)
+	defer server.close()
+
+	// STARTTLS is required
+	func() {
+		appender, err := NewSmtpAppender(newTestSmtpConfig(server))
+		assert.Nil(t, err)
+		appender.Write([]byte("message"))
+		err = appender.Flush()
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "STARTTLS")
+		assert.Equal(t, 0, len(server.received()))
+	}()
+
+	// configs
+	func() {
+		config := newTestSmtpConfig(server)
+		config.To = nil
+		_, err := NewSmtpAppender(config)
+		assert.NotNil(t, err)
+
+		config = newTestSmtpConfig(server)
+		config.SubjectTemplate = "{{.Level"
+		_, err = NewSmtpAppender(config)
+		assert.NotNil(t, err)
+
+		config = newTestSmtpConfig(server)
+		config.Address = "localhost"
+		_, err = NewSmtpAppender(config)
+		assert.NotNil(t, err)
+	}()
+}
@@ -0,0 +1,311 @@
+package golog
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SocketFraming is the framing of events over stream transports, datagrams hold a single event
+type SocketFraming string
+
+// SocketFraming_NEWLINE terminates each event by a newline, for single line encodings like JsonLogEvent
+const SocketFraming_NEWLINE SocketFraming = "newline"
+
+// SocketFraming_LENGTH_PREFIXED prefixes each event by its length as 4 bytes in big endian
+const SocketFraming_LENGTH_PREFIXED SocketFraming = "length-prefixed"
+
+// SocketFraming_OCTET_COUNTING prefixes each event by its length in decimal and a space, see RFC 6587
+const SocketFraming_OCTET_COUNTING SocketFraming = "octet-counting"
+
+const defaultSocketTimeout = time.Second * 5
+
+const defaultSocketKeepAlive = time.Second * 30
+
+const defaultSocketReconnectMinDelay = time.Millisecond * 100
+
+const defaultSocketReconnectMaxDelay = time.Second * 30
+
+// errSocketReconnecting is returned for events written while waiting to reconnect
+var errSocketReconnecting = errors.New("socket is waiting to reconnect")
+
+// SocketAppenderConfig
+type SocketAppenderConfig struct {
+	// Endpoint is tcp://host:port, udp://host:port, unix:///path or unixgram:///path
+	Endpoint string
+
+	Framing SocketFraming
+
+	// TlsConfig enables TLS over tcp. The server name defaults to the host of the endpoint.
+	TlsConfig *tls.Config
+
+	// ClientCertFile and ClientKeyFile are the client certificate presented to the server,
+	// CaFile holds the certificates verifying the server instead of the system pool.
+	// Setting any of them enables TLS. The files are read on every connection, so that renewed certificates are used.
+	ClientCertFile string
+	ClientKeyFile  string
+	CaFile         string
+
+	// DialTimeout bounds connecting including the TLS handshake
+	DialTimeout time.Duration
+
+	// WriteTimeout bounds writing an event
+	WriteTimeout time.Duration
+
+	// KeepAlive is the period of TCP keep-alive probes, negative disables them
+	KeepAlive time.Duration
+
+	// ReconnectMinDelay and ReconnectMaxDelay bound the jittered backoff after a failed connection.
+	// Events written while waiting are dropped.
+	ReconnectMinDelay time.Duration
+	ReconnectMaxDelay time.Duration
+}
+
+// NewDefaultSocketAppenderConfig
+func NewDefaultSocketAppenderConfig() SocketAppenderConfig {
+	return SocketAppenderConfig{
+		Endpoint:          "tcp://localhost:5170",
+		Framing:           SocketFraming_NEWLINE,
+		DialTimeout:       defaultSocketTimeout,
+		WriteTimeout:      defaultSocketTimeout,
+		KeepAlive:         defaultSocketKeepAlive,
+		ReconnectMinDelay: defaultSocketReconnectMinDelay,
+		ReconnectMaxDelay: defaultSocketReconnectMaxDelay,
+	}
+}
+
+// SocketAppender writes encoded events to a tcp, udp or unix socket.
+// Over stream transports the events are framed, over datagram transports each event is sent as a datagram.
+// The connection is established on the first event. When a write fails the event is sent again once on a new connection,
+// and when connecting fails further attempts are delayed by a jittered exponential backoff.
+type SocketAppender struct {
+	config  SocketAppenderConfig
+	network string
+	address string
+	mu      *sync.Mutex
+	conn    net.Conn
+
+	failures  int
+	reconnect time.Time
+	lastErr   error
+}
+
+// NewSocketAppender returns new SocketAppender
+func NewSocketAppender(config SocketAppenderConfig) (*SocketAppender, error) {
+	network, address, err := parseSocketEndpoint(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Framing == "" {
+		config.Framing = SocketFraming_NEWLINE
+	}
+
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = defaultSocketTimeout
+	}
+
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = defaultSocketTimeout
+	}
+
+	if config.KeepAlive == 0 {
+		config.KeepAlive = defaultSocketKeepAlive
+	}
+
+	if config.ReconnectMinDelay <= 0 {
+		config.ReconnectMinDelay = defaultSocketReconnectMinDelay
+	}
+
+	if config.ReconnectMaxDelay <= 0 {
+		config.ReconnectMaxDelay = defaultSocketReconnectMaxDelay
+	}
+
+	if config.ReconnectMaxDelay < config.ReconnectMinDelay {
+		config.ReconnectMaxDelay = config.ReconnectMinDelay
+	}
+
+	return &SocketAppender{
+		config:  config,
+		network: network,
+		address: address,
+		mu:      new(sync.Mutex),
+	}, nil
+}
+
+// parseSocketEndpoint returns the network and the address of the endpoint
+func parseSocketEndpoint(endpoint string) (string, string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch parsed.Scheme {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+		if parsed.Host == "" {
+			return "", "", fmt.Errorf("socket endpoint %q has no address", endpoint)
+		}
+		return parsed.Scheme, parsed.Host, nil
+	case "unix", "unixgram":
+		// unix://relative/path has the first element as the host
+		path := parsed.Host + parsed.Path
+		if path == "" {
+			return "", "", fmt.Errorf("socket endpoint %q has no path", endpoint)
+		}
+		return parsed.Scheme, path, nil
+	default:
+		return "", "", fmt.Errorf("unsupported socket endpoint %q", endpoint)
+	}
+}
+
+// Write implements io.Writer
+// data is a single encoded event, a trailing newline is not sent.
+func (appender *SocketAppender) Write(data []byte) (n int, err error) {
+	frame := appender.frame(data)
+
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if appender.conn == nil {
+			if err = appender.dial(); err != nil {
+				return 0, err
+			}
+		}
+
+		appender.conn.SetWriteDeadline(time.Now().Add(appender.config.WriteTimeout))
+		if _, err = appender.conn.Write(frame); err == nil {
+			return len(data), nil
+		}
+		appender.conn.Close()
+		appender.conn = nil
+	}
+	return 0, err
+}
+
+// Close implements io.Closer
+func (appender *SocketAppender) Close() error {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	if appender.conn == nil {
+		return nil
+	}
+	err := appender.conn.Close()
+	appender.conn = nil
+	return err
+}
+
+// frame returns the event framed for the transport
+func (appender *SocketAppender) frame(data []byte) []byte {
+	for len(data) > 0 && data[len(data)-1] == '\n' {
+		data = data[:len(data)-1]
+	}
+
+	if !isStreamNetwork(appender.network) {
+		return append([]byte(nil), data...)
+	}
+
+	switch appender.config.Framing {
+	case SocketFraming_LENGTH_PREFIXED:
+		frame := make([]byte, 4, 4+len(data))
+		binary.BigEndian.PutUint32(frame, uint32(len(data)))
+		return append(frame, data...)
+	case SocketFraming_OCTET_COUNTING:
+		frame := make([]byte, 0, len(data)+12)
+		frame = strconv.AppendInt(frame, int64(len(data)), 10)
+		frame = append(frame, ' ')
+		return append(frame, data...)
+	default:
+		frame := make([]byte, 0, len(data)+1)
+		frame = append(frame, data...)
+		return append(frame, '\n')
+	}
+}
+
+// dial connects unless waiting for the backoff after a failed connection
+func (appender *SocketAppender) dial() error {
+	if time.Now().Before(appender.reconnect) {
+		return fmt.Errorf("%w: %v", errSocketReconnecting, appender.lastErr)
+	}
+
+	conn, err := appender.connect()
+	if err != nil {
+		appender.reconnect = time.Now().Add(jitteredBackoff(appender.failures, appender.config.ReconnectMinDelay, appender.config.ReconnectMaxDelay))
+		appender.failures++
+		appender.lastErr = err
+		return err
+	}
+
+	appender.conn = conn
+	appender.failures = 0
+	appender.lastErr = nil
+	return nil
+}
+
+// connect
+func (appender *SocketAppender) connect() (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   appender.config.DialTimeout,
+		KeepAlive: appender.config.KeepAlive,
+	}
+
+	tlsConfig, err := appender.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		return dialer.Dial(appender.network, appender.address)
+	}
+
+	if !isStreamNetwork(appender.network) || appender.network == "unix" {
+		return nil, fmt.Errorf("tls is not supported over %s", appender.network)
+	}
+	tlsDialer := &tls.Dialer{NetDialer: dialer, Config: tlsConfig}
+	ctx, cancel := context.WithTimeout(context.Background(), appender.config.DialTimeout)
+	defer cancel()
+	return tlsDialer.DialContext(ctx, appender.network, appender.address)
+}
+
+// tlsConfig returns the TLS config with the certificates loaded, or nil if TLS is not enabled
+func (appender *SocketAppender) tlsConfig() (*tls.Config, error) {
+	config := appender.config
+	if config.TlsConfig == nil && config.ClientCertFile == "" && config.ClientKeyFile == "" && config.CaFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if config.TlsConfig != nil {
+		tlsConfig = config.TlsConfig.Clone()
+	}
+
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if config.CaFile != "" {
+		pem, err := os.ReadFile(config.CaFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates are found in %s", config.CaFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,395 @@
+package golog
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCertificate is a certificate signed by the parent, or self-signed if parent is nil
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	certPem     []byte
+	keyPem      []byte
+}
+
+// newTestCertificate
+func newTestCertificate(t *testing.T, name string, parent *testCertificate, usage x509.ExtKeyUsage) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+		signer, signerKey = parent.certificate, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	return &testCertificate{
+		certificate: certificate,
+		key:         key,
+		certPem:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPem:      pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}
+}
+
+// newTestSocketAppender
+func newTestSocketAppender(t *testing.T, endpoint string, framing SocketFraming) *SocketAppender {
+	config := NewDefaultSocketAppenderConfig()
+	config.Endpoint = endpoint
+	config.Framing = framing
+	appender, err := NewSocketAppender(config)
+	assert.Nil(t, err)
+	return appender
+}
+
+func TestParseSocketEndpoint(t *testing.T) {
+	for endpoint, expected := range map[string][2]string{
+		"tcp://localhost:5170":      {"tcp", "localhost:5170"},
+		"udp://127.0.0.1:514":       {"udp", "127.0.0.1:514"},
+		"unix:///run/relay.sock":    {"unix", "/run/relay.sock"},
+		"unixgram://relay/log.sock": {"unixgram", "relay/log.sock"},
+		"tcp6://[::1]:5170":         {"tcp6", "[::1]:5170"},
+	} {
+		network, address, err := parseSocketEndpoint(endpoint)
+		assert.Nil(t, err, endpoint)
+		assert.Equal(t, expected, [2]string{network, address}, endpoint)
+	}
+
+	for _, endpoint := range []string{"", "http://localhost:80", "tcp://", "unix://"} {
+		_, _, err := parseSocketEndpoint(endpoint)
+		assert.NotNil(t, err, endpoint)
+	}
+}
+
+func TestSocketAppender_frame(t *testing.T) {
+
+	// newline
+	func() {
+		appender := newTestSocketAppender(t, "tcp://localhost:5170", SocketFraming_NEWLINE)
+		assert.Equal(t, "{\"a\":1}\n", string(appender.frame([]byte("{\"a\":1}\n"))))
+		assert.Equal(t, "message\n", string(appender.frame([]byte("message"))))
+	}()
+
+	// length prefixed
+	func() {
+		appender := newTestSocketAppender(t, "tcp://localhost:5170", SocketFraming_LENGTH_PREFIXED)
+		assert.Equal(t, []byte{0, 0, 0, 7, 'm', 'e', 's', 's', 'a', 'g', 'e'}, appender.frame([]byte("message\n")))
+	}()
+
+	// octet counting
+	func() {
+		appender := newTestSocketAppender(t, "unix:///tmp/relay.sock", SocketFraming_OCTET_COUNTING)
+		assert.Equal(t, "7 message", string(appender.frame([]byte("message"))))
+	}()
+
+	// datagrams are not framed
+	func() {
+		appender := newTestSocketAppender(t, "udp://localhost:514", SocketFraming_OCTET_COUNTING)
+		assert.Equal(t, "message", string(appender.frame([]byte("message\n"))))
+	}()
+
+	// the data shared with other appenders is not modified
+	func() {
+		appender := newTestSocketAppender(t, "tcp://localhost:5170", SocketFraming_NEWLINE)
+		data := make([]byte, 3, 10)
+		copy(data, "abc")
+		appender.frame(data)
+		assert.Equal(t, "abc", string(data[:3]))
+		assert.Equal(t, byte(0), data[:4][3])
+	}()
+}
+
+func TestSocketAppender_Write(t *testing.T) {
+
+	// newline delimited json over tcp
+	func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer listener.Close()
+
+		lines := make(chan string, 10)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+		}()
+
+		appender := newTestSocketAppender(t, "tcp://"+listener.Addr().String(), SocketFraming_NEWLINE)
+		logger := NewLogger("relay", LogLevel_INFO, appender)
+		logger.Infoj(struct {
+			Event string `json:"event"`
+		}{"login"})
+		logger.Infoj(struct {
+			Event string `json:"event"`
+		}{"logout"})
+		defer logger.Close()
+
+		for _, expected := range []string{"login", "logout"} {
+			select {
+			case line := <-lines:
+				var decoded map[string]interface{}
+				assert.Nil(t, json.Unmarshal([]byte(line), &decoded))
+				assert.Contains(t, line, `"event":"`+expected+`"`)
+				assert.Equal(t, "relay", decoded["loggerName"])
+			case <-time.After(time.Second * 5):
+				t.Fatal("event is not received")
+			}
+		}
+	}()
+
+	// length prefixed over unix
+	func() {
+		path := filepath.Join(t.TempDir(), "relay.sock")
+		listener, err := net.Listen("unix", path)
+		assert.Nil(t, err)
+		defer listener.Close()
+
+		messages := make(chan string, 10)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			for {
+				var size uint32
+				if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+					return
+				}
+				message := make([]byte, size)
+				if _, err := io.ReadFull(conn, message); err != nil {
+					return
+				}
+				messages <- string(message)
+			}
+		}()
+
+		appender := newTestSocketAppender(t, "unix://"+path, SocketFraming_LENGTH_PREFIXED)
+		defer appender.Close()
+		for _, message := range []string{"first\nline", "second"} {
+			n, err := appender.Write([]byte(message))
+			assert.Nil(t, err)
+			assert.Equal(t, len(message), n)
+		}
+
+		assert.Equal(t, "first\nline", <-messages)
+		assert.Equal(t, "second", <-messages)
+	}()
+
+	// datagrams over udp and unixgram
+	func() {
+		udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer udp.Close()
+
+		path := filepath.Join(t.TempDir(), "relay.sock")
+		unixgram, err := net.ListenPacket("unixgram", path)
+		assert.Nil(t, err)
+		defer unixgram.Close()
+
+		for endpoint, conn := range map[string]net.PacketConn{
+			"udp://" + udp.LocalAddr().String(): udp,
+			"unixgram://" + path:                unixgram,
+		} {
+			appender := newTestSocketAppender(t, endpoint, SocketFraming_NEWLINE)
+			_, err := appender.Write([]byte("message\n"))
+			assert.Nil(t, err)
+			appender.Close()
+
+			buffer := make([]byte, 1024)
+			conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+			n, _, err := conn.ReadFrom(buffer)
+			assert.Nil(t, err)
+			assert.Equal(t, "message", string(buffer[:n]), endpoint)
+		}
+	}()
+}
+
+func TestSocketAppender_Tls(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil, 0)
+	server := newTestCertificate(t, "localhost", ca, x509.ExtKeyUsageServerAuth)
+	client := newTestCertificate(t, "client", ca, x509.ExtKeyUsageClientAuth)
+
+	dir := t.TempDir()
+	files := map[string][]byte{"ca.pem": ca.certPem, "client.pem": client.certPem, "client-key.pem": client.keyPem}
+	for name, data := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), data, 0600))
+	}
+
+	serverCertificate, err := tls.X509KeyPair(server.certPem, server.keyPem)
+	assert.Nil(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.certificate)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCertificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	})
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	type received struct {
+		line   string
+		client string
+	}
+	lines := make(chan received, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				tlsConn := conn.(*tls.Conn)
+				if err := tlsConn.Handshake(); err != nil {
+					return
+				}
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- received{scanner.Text(), tlsConn.ConnectionState().PeerCertificates[0].Subject.CommonName}
+				}
+			}()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	// client certificate
+	func() {
+		config := NewDefaultSocketAppenderConfig()
+		config.Endpoint = "tcp://localhost:" + port
+		config.ClientCertFile = filepath.Join(dir, "client.pem")
+		config.ClientKeyFile = filepath.Join(dir, "client-key.pem")
+		config.CaFile = filepath.Join(dir, "ca.pem")
+		appender, err := NewSocketAppender(config)
+		assert.Nil(t, err)
+		defer appender.Close()
+
+		_, err = appender.Write([]byte("secured"))
+		assert.Nil(t, err)
+		select {
+		case line := <-lines:
+			assert.Equal(t, received{"secured", "client"}, line)
+		case <-time.After(time.Second * 5):
+			t.Fatal("event is not received")
+		}
+	}()
+
+	// server certificate is not trusted
+	func() {
+		config := NewDefaultSocketAppenderConfig()
+		config.Endpoint = "tcp://localhost:" + port
+		config.TlsConfig = &tls.Config{RootCAs: x509.NewCertPool()}
+		appender, err := NewSocketAppender(config)
+		assert.Nil(t, err)
+		defer appender.Close()
+
+		_, err = appender.Write([]byte("secured"))
+		assert.NotNil(t, err)
+	}()
+
+	// missing files
+	func() {
+		config := NewDefaultSocketAppenderConfig()
+		config.Endpoint = "tcp://localhost:" + port
+		config.CaFile = filepath.Join(dir, "missing.pem")
+		appender, err := NewSocketAppender(config)
+		assert.Nil(t, err)
+
+		_, err = appender.Write([]byte("secured"))
+		assert.True(t, errors.Is(err, os.ErrNotExist))
+	}()
+}
+
+func TestSocketAppender_reconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	config := NewDefaultSocketAppenderConfig()
+	config.Endpoint = "tcp://" + address
+	config.ReconnectMinDelay = time.Millisecond * 200
+	config.ReconnectMaxDelay = time.Millisecond * 200
+	appender, err := NewSocketAppender(config)
+	assert.Nil(t, err)
+	defer appender.Close()
+
+	// connecting fails
+	_, err = appender.Write([]byte("first"))
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, errSocketReconnecting))
+
+	// waiting for the backoff
+	_, err = appender.Write([]byte("second"))
+	assert.True(t, errors.Is(err, errSocketReconnecting))
+	assert.Equal(t, 1, appender.failures)
+
+	listener, err = net.Listen("tcp", address)
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	// connected again after the backoff
+	assert.Eventually(t, func() bool {
+		_, err := appender.Write([]byte("third"))
+		return err == nil
+	}, time.Second*5, time.Millisecond*50)
+	assert.Equal(t, "third", <-lines)
+	assert.Equal(t, 0, appender.failures)
+}
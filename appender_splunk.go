@@ -0,0 +1,321 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultSplunkHecEndpoint = "https://localhost:8088"
+
+// defaultSplunkHecMaxBatchBytes bounds the estimated size of a request
+const defaultSplunkHecMaxBatchBytes = 1024 * 1024
+
+const defaultSplunkHecAckTimeout = time.Second * 30
+
+const defaultSplunkHecAckPollInterval = time.Second
+
+// SplunkHecAppenderConfig
+type SplunkHecAppenderConfig struct {
+	// Endpoint is the url of the HTTP Event Collector, e.g. https://localhost:8088
+	Endpoint string
+
+	// Token is sent as the Splunk authorization
+	Token string
+
+	// Host defaults to the host name, Source, SourceType and Index are omitted if empty
+	Host       string
+	Source     string
+	SourceType string
+	Index      string
+
+	// UseAck waits for the indexer acknowledgement of every request on Channel,
+	// a random channel is used if empty. Requests not acknowledged within AckTimeout are sent again.
+	UseAck          bool
+	Channel         string
+	AckTimeout      time.Duration
+	AckPollInterval time.Duration
+
+	MaxBatchSize  int
+	MaxBatchBytes int
+	FlushInterval time.Duration
+	QueueSize     int
+	MaxRetries    int
+	Timeout       time.Duration
+
+	// HttpClient is used instead of a client with Timeout if specified
+	HttpClient *http.Client
+}
+
+// NewDefaultSplunkHecAppenderConfig
+func NewDefaultSplunkHecAppenderConfig() SplunkHecAppenderConfig {
+	return SplunkHecAppenderConfig{
+		Endpoint:        defaultSplunkHecEndpoint,
+		AckTimeout:      defaultSplunkHecAckTimeout,
+		AckPollInterval: defaultSplunkHecAckPollInterval,
+		MaxBatchSize:    defaultMaxBatchSize,
+		MaxBatchBytes:   defaultSplunkHecMaxBatchBytes,
+		FlushInterval:   defaultBatchFlushInterval,
+		QueueSize:       defaultBatchQueueSize,
+		MaxRetries:      defaultMaxRetries,
+		Timeout:         defaultHttpTimeout,
+	}
+}
+
+// SplunkHecAppender sends events to the Splunk HTTP Event Collector.
+// Events are queued and sent in batches from a background goroutine, each batch in one request
+// of HEC envelopes. The level, the logger, the trace context and the fields are sent as indexed fields.
+type SplunkHecAppender struct {
+	config    SplunkHecAppenderConfig
+	client    *http.Client
+	processor *batchProcessor
+}
+
+// NewSplunkHecAppender returns new SplunkHecAppender
+func NewSplunkHecAppender(config SplunkHecAppenderConfig) *SplunkHecAppender {
+	if config.Endpoint == "" {
+		config.Endpoint = defaultSplunkHecEndpoint
+	}
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+
+	if config.Host == "" {
+		config.Host, _ = os.Hostname()
+	}
+
+	if config.UseAck && config.Channel == "" {
+		config.Channel = newSplunkChannel()
+	}
+
+	if config.AckTimeout <= 0 {
+		config.AckTimeout = defaultSplunkHecAckTimeout
+	}
+
+	if config.AckPollInterval <= 0 {
+		config.AckPollInterval = defaultSplunkHecAckPollInterval
+	}
+
+	if config.MaxBatchBytes <= 0 {
+		config.MaxBatchBytes = defaultSplunkHecMaxBatchBytes
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHttpTimeout
+	}
+
+	client := config.HttpClient
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+
+	appender := &SplunkHecAppender{
+		config: config,
+		client: client,
+	}
+	appender.processor = newBatchProcessorWithMaxBytes(appender.export, config.MaxBatchSize,
+		config.MaxBatchBytes, estimateDocumentSize, config.FlushInterval, config.QueueSize)
+	return appender
+}
+
+// AppendEvent implements EventAppender
+func (appender *SplunkHecAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	return appender.processor.enqueue(newEventRecord(level, logEvent, metadata))
+}
+
+// Write implements io.Writer
+// Data is sent as the event of an INFO event.
+func (appender *SplunkHecAppender) Write(data []byte) (n int, err error) {
+	if err := appender.processor.enqueue(newRawEventRecord(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush implements Syncer
+func (appender *SplunkHecAppender) Flush() error {
+	return appender.processor.flush()
+}
+
+// Close implements io.Closer
+// Queued events are sent before it returns.
+func (appender *SplunkHecAppender) Close() error {
+	appender.processor.close()
+	return nil
+}
+
+// newSplunkChannel returns a random channel id in the GUID format
+func newSplunkChannel() string {
+	id := NewRequestId()
+	if len(id) != 32 {
+		return id
+	}
+	return id[0:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:32]
+}
+
+// splunkHecEnvelope
+type splunkHecEnvelope struct {
+	Time       json.Number       `json:"time"`
+	Host       string            `json:"host,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	SourceType string            `json:"sourcetype,omitempty"`
+	Index      string            `json:"index,omitempty"`
+	Event      interface{}       `json:"event"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+// splunkHecResponse is the response of the event and the ack endpoints
+type splunkHecResponse struct {
+	Text  string          `json:"text"`
+	Code  int             `json:"code"`
+	AckId *int64          `json:"ackId"`
+	Acks  map[string]bool `json:"acks"`
+}
+
+// export
+func (appender *SplunkHecAppender) export(records []eventRecord) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, record := range records {
+		if err := encoder.Encode(appender.envelope(record)); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		response, err := appender.post("/services/collector/event", body.Bytes())
+		if err != nil || !appender.config.UseAck {
+			return err
+		}
+		if response.AckId == nil {
+			return errors.New("indexer acknowledgement is not enabled for the token")
+		}
+
+		acked, err := appender.waitForAck(*response.AckId)
+		if err != nil || acked {
+			return err
+		}
+		if attempt >= appender.config.MaxRetries {
+			return fmt.Errorf("events are not acknowledged after %d attempts", attempt+1)
+		}
+		select {
+		case <-appender.processor.closing():
+			// events are not sent again while closing
+			return fmt.Errorf("events are not acknowledged before close after %d attempts", attempt+1)
+		default:
+		}
+	}
+}
+
+// waitForAck polls the ack endpoint until the request is acknowledged or AckTimeout elapses
+func (appender *SplunkHecAppender) waitForAck(ackId int64) (bool, error) {
+	body, err := json.Marshal(map[string][]int64{"acks": {ackId}})
+	if err != nil {
+		return false, err
+	}
+
+	deadline := time.Now().Add(appender.config.AckTimeout)
+	for {
+		response, err := appender.post("/services/collector/ack", body)
+		if err != nil {
+			return false, err
+		}
+		if response.Acks[strconv.FormatInt(ackId, 10)] {
+			return true, nil
+		}
+		if time.Now().Add(appender.config.AckPollInterval).After(deadline) ||
+			!waitRetry(appender.config.AckPollInterval, appender.processor.closing()) {
+			return false, nil
+		}
+	}
+}
+
+// post
+func (appender *SplunkHecAppender) post(path string, body []byte) (*splunkHecResponse, error) {
+	responseBody, err := doWithRetry(appender.client, appender.config.MaxRetries, appender.processor.closing(), func() (*http.Request, error) {
+		request, err := http.NewRequest(http.MethodPost, appender.config.Endpoint+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", "Splunk "+appender.config.Token)
+		if appender.config.Channel != "" {
+			request.Header.Set("X-Splunk-Request-Channel", appender.config.Channel)
+		}
+		return request, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &splunkHecResponse{}
+	if len(responseBody) > 0 {
+		if err := json.Unmarshal(responseBody, response); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// envelope returns the HEC envelope of the record.
+// A JSON object event is sent as is, other events are sent as a string with the stack trace.
+func (appender *SplunkHecAppender) envelope(record eventRecord) splunkHecEnvelope {
+	metadata := record.metadata
+	nanos := record.time.UnixNano()
+
+	envelope := splunkHecEnvelope{
+		Time:       json.Number(fmt.Sprintf("%d.%06d", nanos/int64(time.Second), nanos%int64(time.Second)/int64(time.Microsecond))),
+		Host:       appender.config.Host,
+		Source:     appender.config.Source,
+		SourceType: appender.config.SourceType,
+		Index:      appender.config.Index,
+		Fields:     map[string]string{"level": levelName(record.level)},
+	}
+
+	message := strings.TrimRight(record.message, "\n")
+	var object map[string]interface{}
+	if strings.HasPrefix(message, "{") && json.Unmarshal([]byte(message), &object) == nil {
+		if len(metadata.StackTrace) > 0 {
+			object["stackTrace"] = metadata.StackTrace.String()
+		}
+		envelope.Event = object
+	} else {
+		if len(metadata.StackTrace) > 0 {
+			message += "\n" + metadata.StackTrace.String()
+		}
+		envelope.Event = message
+	}
+
+	if metadata.LoggerName != "" {
+		envelope.Fields["logger"] = metadata.LoggerName
+	}
+	if metadata.TraceId != "" {
+		envelope.Fields["trace_id"] = metadata.TraceId
+		envelope.Fields["span_id"] = metadata.SpanId
+	}
+	for _, field := range metadata.Fields {
+		envelope.Fields[splunkFieldName(field.Key)] = fmt.Sprint(otlpAttributeValue(field.Value))
+	}
+	return envelope
+}
+
+// splunkReservedFieldNames are the names of the indexed fields of the metadata
+var splunkReservedFieldNames = map[string]bool{
+	"level":    true,
+	"logger":   true,
+	"trace_id": true,
+	"span_id":  true,
+}
+
+// splunkFieldName returns the key as an indexed field name.
+// Reserved names are followed by _ so that fields can not overwrite the metadata.
+func splunkFieldName(key string) string {
+	if splunkReservedFieldNames[key] {
+		return key + "_"
+	}
+	return key
+}
@@ -0,0 +1,191 @@
+package golog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// hecServer is a local HTTP Event Collector
+type hecServer struct {
+	mu sync.Mutex
+	// unavailable is the number of requests answered with 503
+	unavailable int
+	// pending is the number of polls before a request is acknowledged
+	pending   int
+	ack       bool
+	unacked   map[int]bool
+	requests  []*http.Request
+	envelopes [][]map[string]interface{}
+	polls     int
+}
+
+func (server *hecServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Splunk token1" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.URL.Path == "/services/collector/ack" {
+		server.polls++
+		var request struct {
+			Acks []int64 `json:"acks"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		acks := map[string]bool{}
+		for _, id := range request.Acks {
+			acks[fmt.Sprint(id)] = server.polls > server.pending && !server.unacked[int(id)]
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"acks": acks})
+		return
+	}
+
+	server.requests = append(server.requests, r)
+	if server.unavailable > 0 {
+		server.unavailable--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	var envelopes []map[string]interface{}
+	for decoder.More() {
+		envelope := map[string]interface{}{}
+		_ = decoder.Decode(&envelope)
+		envelopes = append(envelopes, envelope)
+	}
+	server.envelopes = append(server.envelopes, envelopes)
+
+	response := map[string]interface{}{"text": "Success", "code": 0}
+	if server.ack {
+		response["ackId"] = len(server.envelopes) - 1
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func TestSplunkHecAppender(t *testing.T) {
+
+	stub := &hecServer{unavailable: 1}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	config := NewDefaultSplunkHecAppenderConfig()
+	config.Endpoint = server.URL + "/"
+	config.Token = "token1"
+	config.Host = "host1"
+	config.Source = "app"
+	config.SourceType = "_json"
+	config.Index = "security"
+	appender := NewSplunkHecAppender(config)
+
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.SetMetadataConfig(&MetadataConfig{IsEnabledLoggerName: true})
+	before := time.Now()
+	alice := logger.With(F("user", "alice"), F("count", 3))
+	alice.Info("login")
+	logger.Info(`{"action":"logout"}`)
+	// retries are not waited for on close
+	assert.Nil(t, appender.Flush())
+	assert.Nil(t, logger.Close())
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+
+	// the first request is retried on 503
+	assert.Equal(t, 2, len(stub.requests))
+	assert.Equal(t, "", stub.requests[0].Header.Get("X-Splunk-Request-Channel"))
+	assert.Equal(t, 1, len(stub.envelopes))
+	envelopes := stub.envelopes[0]
+	assert.Equal(t, 2, len(envelopes))
+
+	envelope := envelopes[0]
+	assert.Equal(t, "host1", envelope["host"])
+	assert.Equal(t, "app", envelope["source"])
+	assert.Equal(t, "_json", envelope["sourcetype"])
+	assert.Equal(t, "security", envelope["index"])
+	assert.Equal(t, "login", envelope["event"])
+	assert.Equal(t, map[string]interface{}{"level": "INFO", "logger": "testLogger", "user": "alice", "count": "3"}, envelope["fields"])
+
+	seconds := envelope["time"].(float64)
+	assert.True(t, seconds > float64(before.Unix()))
+	assert.True(t, seconds < float64(time.Now().Unix()+1))
+	assert.NotEqual(t, float64(int64(seconds)), seconds)
+}
+
+func TestSplunkHecAppender_envelope(t *testing.T) {
+
+	appender := NewSplunkHecAppender(NewDefaultSplunkHecAppenderConfig())
+	defer appender.Close()
+
+	// json events are sent as objects
+	record := newEventRecord(LogLevel_WARN, &JsonLogEvent{event: map[string]interface{}{"name": "value"}}, &LogEventMetadata{
+		UnixNano: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC).UnixNano(),
+		TraceId:  "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanId:   "00f067aa0ba902b7",
+		Fields:   Fields{F("trace_id", "field")},
+	})
+	envelope := appender.envelope(record)
+	assert.Equal(t, "value", envelope.Event.(map[string]interface{})["name"])
+	assert.Equal(t, json.Number("1704164645.123456"), envelope.Time)
+	// fields of the names of the metadata are renamed
+	assert.Equal(t, map[string]string{"level": "WARN", "trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "span_id": "00f067aa0ba902b7", "trace_id_": "field"}, envelope.Fields)
+
+	// stack traces are appended to string events
+	envelope = appender.envelope(newEventRecord(LogLevel_ERROR, &TextLogEvent{Event: "failed"}, &LogEventMetadata{StackTrace: NewStackTrace(0)}))
+	assert.True(t, strings.HasPrefix(envelope.Event.(string), "failed\n"))
+}
+
+func TestSplunkHecAppender_Ack(t *testing.T) {
+
+	stub := &hecServer{ack: true, pending: 2}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	config := NewDefaultSplunkHecAppenderConfig()
+	config.Endpoint = server.URL
+	config.Token = "token1"
+	config.UseAck = true
+	config.AckPollInterval = time.Millisecond * 10
+	appender := NewSplunkHecAppender(config)
+
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	logger.Info("message")
+	assert.Nil(t, appender.Flush())
+
+	func() {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		assert.Equal(t, 1, len(stub.envelopes))
+		assert.Equal(t, 3, stub.polls)
+		assert.Regexp(t, "^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", stub.requests[0].Header.Get("X-Splunk-Request-Channel"))
+	}()
+
+	// requests not acknowledged in time are sent again
+	func() {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		stub.unacked = map[int]bool{1: true}
+	}()
+	appender.config.AckTimeout = time.Millisecond * 50
+	logger.Info("message")
+	assert.Nil(t, appender.Flush())
+
+	func() {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		assert.Equal(t, 3, len(stub.envelopes))
+	}()
+	assert.Nil(t, logger.Close())
+}
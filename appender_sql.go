@@ -0,0 +1,387 @@
+package golog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SqlColumnSource is the value of a column
+type SqlColumnSource string
+
+const SqlColumnSource_TIME SqlColumnSource = "time"
+const SqlColumnSource_LEVEL SqlColumnSource = "level"
+const SqlColumnSource_LOGGER SqlColumnSource = "logger"
+const SqlColumnSource_MESSAGE SqlColumnSource = "message"
+const SqlColumnSource_SOURCE_FILE SqlColumnSource = "source_file"
+const SqlColumnSource_SOURCE_LINE SqlColumnSource = "source_line"
+const SqlColumnSource_TRACE_ID SqlColumnSource = "trace_id"
+const SqlColumnSource_SPAN_ID SqlColumnSource = "span_id"
+const SqlColumnSource_STACK_TRACE SqlColumnSource = "stack_trace"
+
+// SqlColumnSource_FIELDS is all the fields as a JSON object
+const SqlColumnSource_FIELDS SqlColumnSource = "fields"
+
+// SqlColumnSource_FIELD is the value of the field named by SqlColumn.Field, NULL if the event does not have it
+const SqlColumnSource_FIELD SqlColumnSource = "field"
+
+// SqlPlaceholder is the style of the bind parameters of the driver
+type SqlPlaceholder string
+
+// SqlPlaceholder_QUESTION is ? of MySQL and SQLite
+const SqlPlaceholder_QUESTION SqlPlaceholder = "question"
+
+// SqlPlaceholder_DOLLAR is $1 of PostgreSQL
+const SqlPlaceholder_DOLLAR SqlPlaceholder = "dollar"
+
+// SqlPlaceholder_AT is @p1 of SQL Server
+const SqlPlaceholder_AT SqlPlaceholder = "at"
+
+const defaultSqlTable = "log_events"
+
+// defaultSqlMaxParameters fits the bind parameters of a statement into the limit of SQLite before 3.32
+const defaultSqlMaxParameters = 999
+
+const defaultSqlTimeout = time.Second * 30
+
+// sqlIdentifier matches table and column names, which are not quoted
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SqlColumn maps a column to a value of events
+type SqlColumn struct {
+	Name   string
+	Source SqlColumnSource
+
+	// Field is the key of the field for SqlColumnSource_FIELD
+	Field string
+
+	// Type is the column type in CreateTable, defaults to a type of the source
+	Type string
+}
+
+// SqlAppenderConfig
+type SqlAppenderConfig struct {
+	Db *sql.DB
+
+	// Table may be qualified by a schema
+	Table string
+
+	// Columns default to time, level, logger, message, trace_id, span_id and fields
+	Columns []SqlColumn
+
+	Placeholder SqlPlaceholder
+
+	// MaxParameters bounds the bind parameters of an INSERT, a batch is inserted by several statements if it has more
+	MaxParameters int
+
+	MaxBatchSize  int
+	FlushInterval time.Duration
+	QueueSize     int
+	MaxRetries    int
+
+	// Timeout bounds the transaction of a batch
+	Timeout time.Duration
+}
+
+// NewDefaultSqlAppenderConfig
+func NewDefaultSqlAppenderConfig() SqlAppenderConfig {
+	return SqlAppenderConfig{
+		Table:         defaultSqlTable,
+		Columns:       NewDefaultSqlColumns(),
+		Placeholder:   SqlPlaceholder_QUESTION,
+		MaxParameters: defaultSqlMaxParameters,
+		MaxBatchSize:  defaultMaxBatchSize,
+		FlushInterval: defaultBatchFlushInterval,
+		QueueSize:     defaultBatchQueueSize,
+		MaxRetries:    defaultMaxRetries,
+		Timeout:       defaultSqlTimeout,
+	}
+}
+
+// NewDefaultSqlColumns returns the columns of time, level, logger, message, trace_id, span_id and fields
+func NewDefaultSqlColumns() []SqlColumn {
+	return []SqlColumn{
+		{Name: "time", Source: SqlColumnSource_TIME},
+		{Name: "level", Source: SqlColumnSource_LEVEL},
+		{Name: "logger", Source: SqlColumnSource_LOGGER},
+		{Name: "message", Source: SqlColumnSource_MESSAGE},
+		{Name: "trace_id", Source: SqlColumnSource_TRACE_ID},
+		{Name: "span_id", Source: SqlColumnSource_SPAN_ID},
+		{Name: "fields", Source: SqlColumnSource_FIELDS},
+	}
+}
+
+// SqlAppender inserts events into a table through database/sql.
+// Events are queued and inserted in batches from a background goroutine, each batch by multi-row INSERTs
+// in a transaction, which is retried as a whole if it fails.
+type SqlAppender struct {
+	config    SqlAppenderConfig
+	processor *batchProcessor
+}
+
+// NewSqlAppender returns new SqlAppender, or an error if the table or a column is not valid
+func NewSqlAppender(config SqlAppenderConfig) (*SqlAppender, error) {
+	if config.Db == nil {
+		return nil, errors.New("sql appender requires Db")
+	}
+
+	if config.Table == "" {
+		config.Table = defaultSqlTable
+	}
+
+	if len(config.Columns) == 0 {
+		config.Columns = NewDefaultSqlColumns()
+	}
+
+	if config.Placeholder == "" {
+		config.Placeholder = SqlPlaceholder_QUESTION
+	}
+
+	if config.MaxParameters <= 0 {
+		config.MaxParameters = defaultSqlMaxParameters
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultSqlTimeout
+	}
+
+	if !sqlIdentifier.MatchString(config.Table) {
+		return nil, fmt.Errorf("invalid table name %q", config.Table)
+	}
+	for _, column := range config.Columns {
+		if !sqlIdentifier.MatchString(column.Name) || strings.Contains(column.Name, ".") {
+			return nil, fmt.Errorf("invalid column name %q", column.Name)
+		}
+		if _, ok := sqlColumnTypes[column.Source]; !ok {
+			return nil, fmt.Errorf("unknown source %q of column %s", column.Source, column.Name)
+		}
+		if column.Source == SqlColumnSource_FIELD && column.Field == "" {
+			return nil, fmt.Errorf("column %s has no field", column.Name)
+		}
+	}
+	if len(config.Columns) > config.MaxParameters {
+		return nil, fmt.Errorf("%d columns exceed %d parameters", len(config.Columns), config.MaxParameters)
+	}
+
+	appender := &SqlAppender{config: config}
+	appender.processor = newBatchProcessor(appender.export, config.MaxBatchSize, config.FlushInterval, config.QueueSize)
+	return appender, nil
+}
+
+// sqlColumnTypes are the default column types of the sources
+var sqlColumnTypes = map[SqlColumnSource]string{
+	SqlColumnSource_TIME:        "TIMESTAMP NOT NULL",
+	SqlColumnSource_LEVEL:       "VARCHAR(8) NOT NULL",
+	SqlColumnSource_LOGGER:      "VARCHAR(255)",
+	SqlColumnSource_MESSAGE:     "TEXT",
+	SqlColumnSource_SOURCE_FILE: "VARCHAR(1024)",
+	SqlColumnSource_SOURCE_LINE: "INTEGER",
+	SqlColumnSource_TRACE_ID:    "CHAR(32)",
+	SqlColumnSource_SPAN_ID:     "CHAR(16)",
+	SqlColumnSource_STACK_TRACE: "TEXT",
+	SqlColumnSource_FIELDS:      "TEXT",
+	SqlColumnSource_FIELD:       "TEXT",
+}
+
+// CreateTableStatement returns CREATE TABLE IF NOT EXISTS of the table with the columns
+func (appender *SqlAppender) CreateTableStatement() string {
+	definitions := make([]string, 0, len(appender.config.Columns))
+	for _, column := range appender.config.Columns {
+		columnType := column.Type
+		if columnType == "" {
+			columnType = sqlColumnTypes[column.Source]
+		}
+		definitions = append(definitions, column.Name+" "+columnType)
+	}
+	return "CREATE TABLE IF NOT EXISTS " + appender.config.Table + " (\n  " + strings.Join(definitions, ",\n  ") + "\n)"
+}
+
+// CreateTable creates the table if it does not exist
+func (appender *SqlAppender) CreateTable(ctx context.Context) error {
+	_, err := appender.config.Db.ExecContext(ctx, appender.CreateTableStatement())
+	return err
+}
+
+// AppendEvent implements EventAppender
+func (appender *SqlAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	return appender.processor.enqueue(newEventRecord(level, logEvent, metadata))
+}
+
+// Write implements io.Writer
+// Data is inserted as the message of an INFO event.
+func (appender *SqlAppender) Write(data []byte) (n int, err error) {
+	if err := appender.processor.enqueue(newRawEventRecord(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush implements Syncer
+func (appender *SqlAppender) Flush() error {
+	return appender.processor.flush()
+}
+
+// Close implements io.Closer
+// The queued events are inserted, the Db is not closed.
+func (appender *SqlAppender) Close() error {
+	appender.processor.close()
+	return nil
+}
+
+// export inserts the records in a transaction
+func (appender *SqlAppender) export(records []eventRecord) error {
+	rows := make([][]interface{}, 0, len(records))
+	for _, record := range records {
+		row, err := appender.row(record)
+		if err != nil {
+			// e.g. a NaN field, the other events of the batch are still inserted
+			warnExportError(fmt.Errorf("drop event which can not be encoded : %w", err))
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := appender.insert(rows)
+		if err == nil || attempt >= appender.config.MaxRetries || !waitRetry(retryBackoff(attempt), appender.processor.closing()) {
+			return err
+		}
+	}
+}
+
+// insert
+func (appender *SqlAppender) insert(rows [][]interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), appender.config.Timeout)
+	defer cancel()
+
+	tx, err := appender.config.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	rowsPerStatement := appender.config.MaxParameters / len(appender.config.Columns)
+	for start := 0; start < len(rows); start += rowsPerStatement {
+		end := start + rowsPerStatement
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		args := make([]interface{}, 0, (end-start)*len(appender.config.Columns))
+		for _, row := range rows[start:end] {
+			args = append(args, row...)
+		}
+		if _, err := tx.ExecContext(ctx, appender.insertStatement(end-start), args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// insertStatement returns INSERT of the rows
+func (appender *SqlAppender) insertStatement(rows int) string {
+	columns := appender.config.Columns
+
+	var statement strings.Builder
+	statement.WriteString("INSERT INTO " + appender.config.Table + " (")
+	for i, column := range columns {
+		if i > 0 {
+			statement.WriteString(", ")
+		}
+		statement.WriteString(column.Name)
+	}
+	statement.WriteString(") VALUES ")
+
+	parameter := 0
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			statement.WriteString(", ")
+		}
+		statement.WriteByte('(')
+		for i := range columns {
+			if i > 0 {
+				statement.WriteString(", ")
+			}
+			parameter++
+			switch appender.config.Placeholder {
+			case SqlPlaceholder_DOLLAR:
+				statement.WriteString("$" + strconv.Itoa(parameter))
+			case SqlPlaceholder_AT:
+				statement.WriteString("@p" + strconv.Itoa(parameter))
+			default:
+				statement.WriteByte('?')
+			}
+		}
+		statement.WriteByte(')')
+	}
+	return statement.String()
+}
+
+// row returns the values of the columns, empty strings of the metadata are NULL
+func (appender *SqlAppender) row(record eventRecord) ([]interface{}, error) {
+	metadata := record.metadata
+	nullable := func(value string) interface{} {
+		if value == "" {
+			return nil
+		}
+		return value
+	}
+
+	row := make([]interface{}, 0, len(appender.config.Columns))
+	for _, column := range appender.config.Columns {
+		var value interface{}
+		switch column.Source {
+		case SqlColumnSource_TIME:
+			value = record.time.UTC()
+		case SqlColumnSource_LEVEL:
+			value = levelName(record.level)
+		case SqlColumnSource_LOGGER:
+			value = nullable(metadata.LoggerName)
+		case SqlColumnSource_MESSAGE:
+			value = strings.TrimRight(record.message, "\n")
+		case SqlColumnSource_SOURCE_FILE:
+			value = nullable(metadata.SourceFile)
+		case SqlColumnSource_SOURCE_LINE:
+			if metadata.SourceLine > 0 {
+				value = int64(metadata.SourceLine)
+			}
+		case SqlColumnSource_TRACE_ID:
+			value = nullable(metadata.TraceId)
+		case SqlColumnSource_SPAN_ID:
+			value = nullable(metadata.SpanId)
+		case SqlColumnSource_STACK_TRACE:
+			if len(metadata.StackTrace) > 0 {
+				value = metadata.StackTrace.String()
+			}
+		case SqlColumnSource_FIELDS:
+			if len(metadata.Fields) > 0 {
+				fields := make(map[string]interface{}, len(metadata.Fields))
+				for _, field := range metadata.Fields {
+					fields[field.Key] = otlpAttributeValue(field.Value)
+				}
+				encoded, err := json.Marshal(fields)
+				if err != nil {
+					return nil, err
+				}
+				value = string(encoded)
+			}
+		case SqlColumnSource_FIELD:
+			for _, field := range metadata.Fields {
+				if field.Key == column.Field {
+					value = otlpAttributeValue(field.Value)
+				}
+			}
+		}
+		row = append(row, value)
+	}
+	return row, nil
+}
@@ -0,0 +1,284 @@
+package golog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The appender is tested against this fake driver rather than SQLite,
+// since no pure Go SQLite driver can be fetched in the environment of the tests.
+
+// tableStore keeps the rows inserted through tableDriver, rows of a transaction are kept on commit
+type tableStore struct {
+	mu         *sync.Mutex
+	statements []string
+	rows       [][]driver.Value
+	commits    int
+	rollbacks  int
+
+	// failures is the number of inserts to fail
+	failures int
+}
+
+// tableConnector
+type tableConnector struct {
+	store *tableStore
+}
+
+// Connect implements driver.Connector
+func (connector tableConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return &tableConn{store: connector.store}, nil
+}
+
+// Driver implements driver.Connector
+func (connector tableConnector) Driver() driver.Driver {
+	return nil
+}
+
+// tableConn
+type tableConn struct {
+	store   *tableStore
+	pending [][]driver.Value
+	inTx    bool
+}
+
+func (conn *tableConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (conn *tableConn) Close() error {
+	return nil
+}
+
+func (conn *tableConn) Begin() (driver.Tx, error) {
+	conn.inTx = true
+	return conn, nil
+}
+
+func (conn *tableConn) Commit() error {
+	conn.store.mu.Lock()
+	defer conn.store.mu.Unlock()
+	conn.store.rows = append(conn.store.rows, conn.pending...)
+	conn.store.commits++
+	conn.pending, conn.inTx = nil, false
+	return nil
+}
+
+func (conn *tableConn) Rollback() error {
+	conn.store.mu.Lock()
+	defer conn.store.mu.Unlock()
+	conn.store.rollbacks++
+	conn.pending, conn.inTx = nil, false
+	return nil
+}
+
+// ExecContext records the rows of INSERT, each row has as many values as the columns
+func (conn *tableConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	conn.store.mu.Lock()
+	defer conn.store.mu.Unlock()
+	conn.store.statements = append(conn.store.statements, query)
+
+	if !strings.HasPrefix(query, "INSERT") {
+		return driver.RowsAffected(0), nil
+	}
+	if conn.store.failures > 0 {
+		conn.store.failures--
+		return nil, errors.New("deadlock detected")
+	}
+
+	columns := strings.Count(query[:strings.Index(query, ")")], ",") + 1
+	for i := 0; i+columns <= len(args); i += columns {
+		row := make([]driver.Value, columns)
+		for j := range row {
+			row[j] = args[i+j].Value
+		}
+		if conn.inTx {
+			conn.pending = append(conn.pending, row)
+		} else {
+			conn.store.rows = append(conn.store.rows, row)
+		}
+	}
+	return driver.RowsAffected(len(args) / columns), nil
+}
+
+// newTableDb
+func newTableDb() (*sql.DB, *tableStore) {
+	store := &tableStore{mu: new(sync.Mutex)}
+	return sql.OpenDB(tableConnector{store: store}), store
+}
+
+func TestSqlAppender_statements(t *testing.T) {
+	db, _ := newTableDb()
+	defer db.Close()
+
+	config := NewDefaultSqlAppenderConfig()
+	config.Db = db
+	config.Table = "audit.events"
+	config.Columns = []SqlColumn{
+		{Name: "at", Source: SqlColumnSource_TIME, Type: "TIMESTAMPTZ NOT NULL"},
+		{Name: "level", Source: SqlColumnSource_LEVEL},
+		{Name: "user_id", Source: SqlColumnSource_FIELD, Field: "user"},
+	}
+	config.Placeholder = SqlPlaceholder_DOLLAR
+	appender, err := NewSqlAppender(config)
+	assert.Nil(t, err)
+	defer appender.Close()
+
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS audit.events (\n  at TIMESTAMPTZ NOT NULL,\n  level VARCHAR(8) NOT NULL,\n  user_id TEXT\n)", appender.CreateTableStatement())
+	assert.Equal(t, "INSERT INTO audit.events (at, level, user_id) VALUES ($1, $2, $3), ($4, $5, $6)", appender.insertStatement(2))
+
+	appender.config.Placeholder = SqlPlaceholder_AT
+	assert.Equal(t, "INSERT INTO audit.events (at, level, user_id) VALUES (@p1, @p2, @p3)", appender.insertStatement(1))
+	appender.config.Placeholder = SqlPlaceholder_QUESTION
+	assert.Equal(t, "INSERT INTO audit.events (at, level, user_id) VALUES (?, ?, ?), (?, ?, ?)", appender.insertStatement(2))
+}
+
+func TestSqlAppender_row(t *testing.T) {
+	db, _ := newTableDb()
+	defer db.Close()
+
+	config := NewDefaultSqlAppenderConfig()
+	config.Db = db
+	config.Columns = append(NewDefaultSqlColumns(),
+		SqlColumn{Name: "source_file", Source: SqlColumnSource_SOURCE_FILE},
+		SqlColumn{Name: "source_line", Source: SqlColumnSource_SOURCE_LINE},
+		SqlColumn{Name: "stack_trace", Source: SqlColumnSource_STACK_TRACE},
+		SqlColumn{Name: "amount", Source: SqlColumnSource_FIELD, Field: "amount"},
+		SqlColumn{Name: "missing", Source: SqlColumnSource_FIELD, Field: "missing"},
+	)
+	appender, err := NewSqlAppender(config)
+	assert.Nil(t, err)
+	defer appender.Close()
+
+	eventTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("JST", 9*60*60))
+	row, err := appender.row(eventRecord{
+		level:   LogLevel_WARN,
+		message: "refund\n",
+		time:    eventTime,
+		metadata: LogEventMetadata{
+			LoggerName: "billing",
+			SourceFile: "refund.go",
+			SourceLine: 12,
+			TraceId:    "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanId:     "00f067aa0ba902b7",
+			Fields:     Fields{F("amount", 1200), F("error", errors.New("declined"))},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{
+		eventTime.UTC(), "WARN", "billing", "refund", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7",
+		`{"amount":1200,"error":"declined"}`, "refund.go", int64(12), nil, int64(1200), nil,
+	}, row)
+
+	// metadata is disabled
+	row, err = appender.row(newRawEventRecord([]byte("raw")))
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"INFO", nil, "raw", nil, nil, nil, nil, nil, nil, nil, nil}, row[1:])
+}
+
+func TestSqlAppender_export(t *testing.T) {
+	db, store := newTableDb()
+	defer db.Close()
+
+	config := NewDefaultSqlAppenderConfig()
+	config.Db = db
+	config.MaxParameters = 14
+	config.MaxRetries = 1
+	appender, err := NewSqlAppender(config)
+	assert.Nil(t, err)
+	assert.Nil(t, appender.CreateTable(context.Background()))
+
+	// the first insert fails and the transaction is retried
+	store.mu.Lock()
+	store.failures = 1
+	store.mu.Unlock()
+
+	logger := NewLogger("audit", LogLevel_INFO, appender)
+	logger.SetMetadataConfig(&MetadataConfig{IsEnabledLoggerName: true})
+	for _, user := range []string{"alice", "bob", "carol", "dave", "eve"} {
+		userLogger := logger.With(F("user", user))
+		userLogger.Info("login")
+	}
+	assert.Nil(t, logger.Flush())
+
+	store.mu.Lock()
+	assert.True(t, strings.HasPrefix(store.statements[0], "CREATE TABLE IF NOT EXISTS log_events (\n  time TIMESTAMP NOT NULL,"))
+	assert.Equal(t, 1, store.rollbacks)
+	assert.Equal(t, 1, store.commits)
+	assert.Equal(t, 5, len(store.rows))
+	// 2 rows of 7 columns fit into 14 parameters
+	assert.Equal(t, 1+1+3, len(store.statements))
+	for i, user := range []string{"alice", "bob", "carol", "dave", "eve"} {
+		assert.Equal(t, []driver.Value{"INFO", "audit", "login", nil, nil, `{"user":"` + user + `"}`}, store.rows[i][1:])
+	}
+	store.mu.Unlock()
+
+	// the transaction fails after the retries
+	store.mu.Lock()
+	store.failures = 2
+	store.mu.Unlock()
+	logger.Info("lost")
+	assert.NotNil(t, appender.Flush())
+	assert.Nil(t, logger.Close())
+
+	store.mu.Lock()
+	assert.Equal(t, 5, len(store.rows))
+	assert.Equal(t, 3, store.rollbacks)
+	store.mu.Unlock()
+}
+
+func TestSqlAppender_export_unencodable(t *testing.T) {
+	db, store := newTableDb()
+	defer db.Close()
+
+	config := NewDefaultSqlAppenderConfig()
+	config.Db = db
+	appender, err := NewSqlAppender(config)
+	assert.Nil(t, err)
+
+	// an event whose fields can not be encoded is dropped, the others are inserted
+	logger := NewLogger("audit", LogLevel_INFO, appender)
+	logger.SetMetadataConfig(&MetadataConfig{})
+	nan := logger.With(F("ratio", math.NaN()))
+	nan.Info("dropped")
+	logger.Info("inserted")
+	assert.Nil(t, logger.Close())
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	assert.Equal(t, 1, len(store.rows))
+	assert.Equal(t, "inserted", store.rows[0][3])
+}
+
+func TestNewSqlAppender(t *testing.T) {
+	db, _ := newTableDb()
+	defer db.Close()
+
+	for name, change := range map[string]func(config *SqlAppenderConfig){
+		"no db": func(config *SqlAppenderConfig) { config.Db = nil },
+		"table": func(config *SqlAppenderConfig) { config.Table = "events; DROP TABLE users" },
+		"column": func(config *SqlAppenderConfig) {
+			config.Columns = []SqlColumn{{Name: "a.b", Source: SqlColumnSource_TIME}}
+		},
+		"source": func(config *SqlAppenderConfig) { config.Columns = []SqlColumn{{Name: "a", Source: "host"}} },
+		"field": func(config *SqlAppenderConfig) {
+			config.Columns = []SqlColumn{{Name: "a", Source: SqlColumnSource_FIELD}}
+		},
+		"max parameters": func(config *SqlAppenderConfig) { config.MaxParameters = 3 },
+	} {
+		config := NewDefaultSqlAppenderConfig()
+		config.Db = db
+		change(&config)
+		_, err := NewSqlAppender(config)
+		assert.NotNil(t, err, name)
+	}
+}
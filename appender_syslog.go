@@ -0,0 +1,406 @@
+package golog
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// SyslogFormat
+type SyslogFormat string
+
+const SyslogFormat_RFC5424 SyslogFormat = "rfc5424"
+const SyslogFormat_RFC3164 SyslogFormat = "rfc3164"
+
+// SyslogFraming is the framing of messages over stream transports, see RFC 6587
+type SyslogFraming string
+
+const SyslogFraming_OCTET_COUNTING SyslogFraming = "octet-counting"
+const SyslogFraming_NON_TRANSPARENT SyslogFraming = "non-transparent"
+
+// SyslogFacility
+type SyslogFacility int
+
+const (
+	SyslogFacility_KERN   SyslogFacility = 0
+	SyslogFacility_USER   SyslogFacility = 1
+	SyslogFacility_MAIL   SyslogFacility = 2
+	SyslogFacility_DAEMON SyslogFacility = 3
+	SyslogFacility_AUTH   SyslogFacility = 4
+	SyslogFacility_SYSLOG SyslogFacility = 5
+	SyslogFacility_LOCAL0 SyslogFacility = 16
+	SyslogFacility_LOCAL1 SyslogFacility = 17
+	SyslogFacility_LOCAL2 SyslogFacility = 18
+	SyslogFacility_LOCAL3 SyslogFacility = 19
+	SyslogFacility_LOCAL4 SyslogFacility = 20
+	SyslogFacility_LOCAL5 SyslogFacility = 21
+	SyslogFacility_LOCAL6 SyslogFacility = 22
+	SyslogFacility_LOCAL7 SyslogFacility = 23
+)
+
+const defaultSyslogStructuredDataId = "golog@32473"
+
+const defaultSyslogMaxMessageSize = 8192
+
+const defaultSyslogTimeout = time.Second * 5
+
+// localSyslogPaths are tried in order when no network is specified
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogAppenderConfig
+type SyslogAppenderConfig struct {
+	// Network is udp, tcp, unix or unixgram.
+	// If empty, the local syslog socket at Address or at /dev/log is used.
+	Network string
+	Address string
+
+	Format  SyslogFormat
+	Framing SyslogFraming
+
+	Facility SyslogFacility
+
+	// Hostname, AppName and ProcId default to the host name, the executable name and the process id
+	Hostname string
+	AppName  string
+	ProcId   string
+	MsgId    string
+
+	// StructuredDataId is the SD-ID of the SD-ELEMENT holding the fields in RFC 5424
+	StructuredDataId string
+
+	// MaxMessageSize truncates longer messages, without the framing
+	MaxMessageSize int
+
+	// Timeout bounds connecting and writing
+	Timeout time.Duration
+}
+
+// NewDefaultSyslogAppenderConfig
+func NewDefaultSyslogAppenderConfig() SyslogAppenderConfig {
+	return SyslogAppenderConfig{
+		Format:           SyslogFormat_RFC5424,
+		Framing:          SyslogFraming_OCTET_COUNTING,
+		Facility:         SyslogFacility_USER,
+		StructuredDataId: defaultSyslogStructuredDataId,
+		MaxMessageSize:   defaultSyslogMaxMessageSize,
+		Timeout:          defaultSyslogTimeout,
+	}
+}
+
+// SyslogAppender sends events to a syslog server in RFC 5424 or RFC 3164 format.
+// The severity is mapped from LogLevel. In RFC 5424 fields and the trace context
+// are sent as parameters of a single SD-ELEMENT, in RFC 3164 they follow the message.
+// Over tcp and unix the messages are framed by octet counting unless configured otherwise.
+// The connection is established on the first event and re-established once when a write fails.
+// While the server is unreachable, events fail fast with the last dial error and the dial is retried with backoff.
+type SyslogAppender struct {
+	config SyslogAppenderConfig
+	mu     *sync.Mutex
+	conn   net.Conn
+	stream bool
+
+	// dialErr is returned until dialAt after dialAttempts failed dials
+	dialErr      error
+	dialAt       time.Time
+	dialAttempts int
+}
+
+// NewSyslogAppender returns new SyslogAppender
+func NewSyslogAppender(config SyslogAppenderConfig) *SyslogAppender {
+	if config.Format == "" {
+		config.Format = SyslogFormat_RFC5424
+	}
+
+	if config.Framing == "" {
+		config.Framing = SyslogFraming_OCTET_COUNTING
+	}
+
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+
+	if config.AppName == "" {
+		config.AppName = filepath.Base(os.Args[0])
+	}
+
+	if config.ProcId == "" {
+		config.ProcId = strconv.Itoa(os.Getpid())
+	}
+
+	if config.StructuredDataId == "" {
+		config.StructuredDataId = defaultSyslogStructuredDataId
+	}
+
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = defaultSyslogMaxMessageSize
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultSyslogTimeout
+	}
+
+	return &SyslogAppender{
+		config: config,
+		mu:     new(sync.Mutex),
+	}
+}
+
+// AppendEvent implements EventAppender
+func (appender *SyslogAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	return appender.send(appender.format(newEventRecord(level, logEvent, metadata)))
+}
+
+// Write implements io.Writer
+// Data is sent as the message of an INFO event.
+func (appender *SyslogAppender) Write(data []byte) (n int, err error) {
+	if err := appender.send(appender.format(newRawEventRecord(data))); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Close implements io.Closer
+func (appender *SyslogAppender) Close() error {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	if appender.conn == nil {
+		return nil
+	}
+	err := appender.conn.Close()
+	appender.conn = nil
+	return err
+}
+
+// SyslogSeverity returns the syslog severity of the level
+func SyslogSeverity(level LogLevel) int {
+	switch level {
+	case LogLevel_TRACE, LogLevel_DEBUG:
+		return 7
+	case LogLevel_INFO:
+		return 6
+	case LogLevel_WARN:
+		return 4
+	case LogLevel_ERROR:
+		return 3
+	case LogLevel_FATAL:
+		return 2
+	default:
+		return 5
+	}
+}
+
+// send writes the message, reconnecting once if the write fails
+func (appender *SyslogAppender) send(message []byte) error {
+	if len(message) > appender.config.MaxMessageSize {
+		limit := appender.config.MaxMessageSize
+		for limit > 0 && !utf8.RuneStart(message[limit]) {
+			limit--
+		}
+		message = message[:limit]
+	}
+
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if appender.conn == nil {
+			if err = appender.redial(); err != nil {
+				return err
+			}
+		}
+
+		appender.conn.SetWriteDeadline(time.Now().Add(appender.config.Timeout))
+		if _, err = appender.conn.Write(appender.frame(message)); err == nil {
+			return nil
+		}
+		appender.conn.Close()
+		appender.conn = nil
+	}
+	return err
+}
+
+// redial dials unless the last dial failed within the backoff, so that events are not blocked by each dial
+func (appender *SyslogAppender) redial() error {
+	if appender.dialErr != nil && time.Now().Before(appender.dialAt) {
+		return appender.dialErr
+	}
+
+	if err := appender.dial(); err != nil {
+		appender.dialErr = err
+		appender.dialAt = time.Now().Add(retryBackoff(appender.dialAttempts))
+		appender.dialAttempts++
+		return err
+	}
+	appender.dialErr = nil
+	appender.dialAttempts = 0
+	return nil
+}
+
+// dial
+func (appender *SyslogAppender) dial() error {
+	if appender.config.Network != "" {
+		conn, err := net.DialTimeout(appender.config.Network, appender.config.Address, appender.config.Timeout)
+		if err != nil {
+			return err
+		}
+		appender.conn = conn
+		appender.stream = isStreamNetwork(appender.config.Network)
+		return nil
+	}
+
+	paths := localSyslogPaths
+	if appender.config.Address != "" {
+		paths = []string{appender.config.Address}
+	}
+	for _, path := range paths {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.DialTimeout(network, path, appender.config.Timeout)
+			if err == nil {
+				appender.conn = conn
+				appender.stream = network == "unix"
+				return nil
+			}
+		}
+	}
+	return errors.New("local syslog socket is not found")
+}
+
+// isStreamNetwork
+func isStreamNetwork(network string) bool {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return true
+	default:
+		return false
+	}
+}
+
+// frame returns the message framed for stream transports
+func (appender *SyslogAppender) frame(message []byte) []byte {
+	if !appender.stream {
+		return message
+	}
+	if appender.config.Framing == SyslogFraming_NON_TRANSPARENT {
+		return append(message, '\n')
+	}
+	return append([]byte(strconv.Itoa(len(message))+" "), message...)
+}
+
+// format returns the syslog message of the record
+func (appender *SyslogAppender) format(record eventRecord) []byte {
+	priority := int(appender.config.Facility)*8 + SyslogSeverity(record.level)
+
+	message := strings.TrimRight(record.message, "\n")
+	if len(record.metadata.StackTrace) > 0 {
+		message += "\n" + record.metadata.StackTrace.String()
+	}
+
+	var builder strings.Builder
+	builder.WriteString("<" + strconv.Itoa(priority) + ">")
+
+	if appender.config.Format == SyslogFormat_RFC3164 {
+		builder.WriteString(record.time.Format(time.Stamp))
+		builder.WriteString(" " + syslogHeaderField(appender.config.Hostname, 255))
+		builder.WriteString(" " + syslogHeaderField(appender.config.AppName, 32))
+		builder.WriteString("[" + syslogHeaderField(appender.config.ProcId, 128) + "]: ")
+		builder.WriteString(message)
+		if params := syslogParams(record.metadata); len(params) > 0 {
+			builder.WriteString(" " + params.String())
+		}
+		return []byte(builder.String())
+	}
+
+	builder.WriteString("1 ")
+	builder.WriteString(record.time.Format("2006-01-02T15:04:05.000000Z07:00"))
+	builder.WriteString(" " + syslogHeaderField(appender.config.Hostname, 255))
+	builder.WriteString(" " + syslogHeaderField(appender.config.AppName, 48))
+	builder.WriteString(" " + syslogHeaderField(appender.config.ProcId, 128))
+	builder.WriteString(" " + syslogHeaderField(appender.config.MsgId, 32))
+	builder.WriteString(" " + appender.structuredData(record.metadata))
+	if message != "" {
+		builder.WriteString(" " + message)
+	}
+	return []byte(builder.String())
+}
+
+// structuredData returns the SD-ELEMENT of the fields and the trace context, - if none
+func (appender *SyslogAppender) structuredData(metadata LogEventMetadata) string {
+	params := syslogParams(metadata)
+	if len(params) == 0 {
+		return "-"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("[" + appender.config.StructuredDataId)
+	for _, param := range params {
+		builder.WriteString(" " + syslogParamName(param.Key) + "=\"")
+		builder.WriteString(syslogParamValue(param.Value))
+		builder.WriteString("\"")
+	}
+	builder.WriteString("]")
+	return builder.String()
+}
+
+// syslogParams returns the trace context followed by the fields
+func syslogParams(metadata LogEventMetadata) Fields {
+	var params Fields
+	if metadata.TraceId != "" {
+		params = append(params, F("trace_id", metadata.TraceId), F("span_id", metadata.SpanId))
+		if metadata.TraceFlags != "" {
+			params = append(params, F("trace_flags", metadata.TraceFlags))
+		}
+	}
+	return append(params, metadata.Fields...)
+}
+
+// syslogHeaderField returns the printable ascii value truncated to max, - if empty
+func syslogHeaderField(value string, max int) string {
+	if value == "" {
+		return "-"
+	}
+
+	field := []byte(value)
+	if len(field) > max {
+		field = field[:max]
+	}
+	for i, c := range field {
+		if c <= ' ' || c > '~' {
+			field[i] = '_'
+		}
+	}
+	return string(field)
+}
+
+// syslogParamName returns the key as a valid PARAM-NAME of RFC 5424
+func syslogParamName(key string) string {
+	name := []byte(syslogHeaderField(key, 32))
+	for i, c := range name {
+		if c == '=' || c == ']' || c == '"' {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// syslogParamValue escapes '"', '\' and ']' of the value
+func syslogParamValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	default:
+		s = fmt.Sprint(v)
+	}
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
@@ -0,0 +1,199 @@
+package golog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSyslogConfig returns a config with fixed header fields
+func newTestSyslogConfig(network string, address string) SyslogAppenderConfig {
+	config := NewDefaultSyslogAppenderConfig()
+	config.Network = network
+	config.Address = address
+	config.Facility = SyslogFacility_LOCAL0
+	config.Hostname = "host"
+	config.AppName = "app"
+	config.ProcId = "42"
+	config.MsgId = "ID1"
+	return config
+}
+
+func TestSyslogAppender_format(t *testing.T) {
+
+	record := eventRecord{
+		level:   LogLevel_ERROR,
+		message: "failed\n",
+		time:    time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC),
+		metadata: LogEventMetadata{
+			TraceId:    "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanId:     "00f067aa0ba902b7",
+			TraceFlags: "01",
+			Fields:     Fields{F("user id", "a\"b]c"), F("error", errors.New(`c:\tmp`))},
+		},
+	}
+
+	// rfc5424
+	func() {
+		appender := NewSyslogAppender(newTestSyslogConfig("udp", ""))
+		assert.Equal(t,
+			`<131>1 2024-01-02T03:04:05.123456Z host app 42 ID1 [golog@32473 trace_id="4bf92f3577b34da6a3ce929d0e0e4736" span_id="00f067aa0ba902b7" trace_flags="01" user_id="a\"b\]c" error="c:\\tmp"] failed`,
+			string(appender.format(record)))
+
+		assert.Equal(t, "<134>1 2024-01-02T03:04:05.123456Z host app 42 ID1 - message",
+			string(appender.format(eventRecord{level: LogLevel_INFO, message: "message", time: record.time})))
+	}()
+
+	// rfc3164
+	func() {
+		config := newTestSyslogConfig("udp", "")
+		config.Format = SyslogFormat_RFC3164
+		appender := NewSyslogAppender(config)
+		assert.Equal(t,
+			`<131>Jan  2 03:04:05 host app[42]: failed trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01 user id="a\"b]c" error=c:\tmp`,
+			string(appender.format(record)))
+	}()
+
+	// header fields
+	assert.Equal(t, "-", syslogHeaderField("", 48))
+	assert.Equal(t, "my_app", syslogHeaderField("my app", 48))
+	assert.Equal(t, "abc", syslogHeaderField("abcdef", 3))
+	assert.Equal(t, "a_b_c", syslogParamName("a=b\"c"))
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	assert.Equal(t, 7, SyslogSeverity(LogLevel_TRACE))
+	assert.Equal(t, 7, SyslogSeverity(LogLevel_DEBUG))
+	assert.Equal(t, 6, SyslogSeverity(LogLevel_INFO))
+	assert.Equal(t, 4, SyslogSeverity(LogLevel_WARN))
+	assert.Equal(t, 3, SyslogSeverity(LogLevel_ERROR))
+	assert.Equal(t, 2, SyslogSeverity(LogLevel_FATAL))
+}
+
+func TestSyslogAppender_AppendEvent(t *testing.T) {
+
+	// udp
+	func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		appender := NewSyslogAppender(newTestSyslogConfig("udp", conn.LocalAddr().String()))
+		defer appender.Close()
+		logger := NewLogger("syslogLogger", LogLevel_TRACE, appender)
+		logger = logger.With(F("request_id", "abc"))
+		logger.Warn("message")
+
+		buffer := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		n, _, err := conn.ReadFrom(buffer)
+		assert.Nil(t, err)
+		received := string(buffer[:n])
+		assert.True(t, strings.HasPrefix(received, "<132>1 "))
+		assert.True(t, strings.HasSuffix(received, ` host app 42 ID1 [golog@32473 request_id="abc"] message`))
+	}()
+
+	// tcp with octet counting
+	func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer listener.Close()
+
+		appender := NewSyslogAppender(newTestSyslogConfig("tcp", listener.Addr().String()))
+		defer appender.Close()
+		logger := NewLogger("syslogLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		logger.Info("first")
+		logger.Error("second\nline")
+
+		conn, err := listener.Accept()
+		assert.Nil(t, err)
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		reader := bufio.NewReader(conn)
+
+		for _, expected := range []string{"first", "second\nline"} {
+			length, err := reader.ReadString(' ')
+			assert.Nil(t, err)
+			size, err := strconv.Atoi(strings.TrimSpace(length))
+			assert.Nil(t, err)
+			message := make([]byte, size)
+			_, err = io.ReadFull(reader, message)
+			assert.Nil(t, err)
+			assert.True(t, strings.HasSuffix(string(message), " host app 42 ID1 - "+expected))
+		}
+	}()
+
+	// local unixgram socket, raw writes
+	func() {
+		path := filepath.Join(t.TempDir(), "log")
+		conn, err := net.ListenPacket("unixgram", path)
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		config := newTestSyslogConfig("", path)
+		config.Format = SyslogFormat_RFC3164
+		appender := NewSyslogAppender(config)
+		defer appender.Close()
+		_, err = appender.Write([]byte("raw"))
+		assert.Nil(t, err)
+
+		buffer := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		n, _, err := conn.ReadFrom(buffer)
+		assert.Nil(t, err)
+		received := string(buffer[:n])
+		assert.True(t, strings.HasPrefix(received, "<134>"))
+		assert.True(t, strings.HasSuffix(received, " host app[42]: raw"))
+	}()
+
+	// messages are truncated at a rune boundary
+	func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		config := newTestSyslogConfig("udp", conn.LocalAddr().String())
+		config.MaxMessageSize = 64
+		appender := NewSyslogAppender(config)
+		defer appender.Close()
+		_, err = appender.Write([]byte(strings.Repeat("あ", 64)))
+		assert.Nil(t, err)
+
+		buffer := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		n, _, err := conn.ReadFrom(buffer)
+		assert.Nil(t, err)
+		assert.True(t, n <= 64)
+		assert.True(t, utf8.Valid(buffer[:n]))
+		assert.True(t, strings.HasSuffix(string(buffer[:n]), "あ"))
+	}()
+
+	// no listener
+	func() {
+		appender := NewSyslogAppender(newTestSyslogConfig("", filepath.Join(t.TempDir(), "missing")))
+		_, err := appender.Write([]byte("raw"))
+		assert.NotNil(t, err)
+
+	}()
+
+	// the dial is not retried within the backoff
+	func() {
+		appender := NewSyslogAppender(newTestSyslogConfig("unix", filepath.Join(t.TempDir(), "missing")))
+		_, err := appender.Write([]byte("raw"))
+		assert.NotNil(t, err)
+
+		appender.config.Address = filepath.Join(t.TempDir(), "other")
+		_, retryErr := appender.Write([]byte("raw"))
+		assert.Equal(t, err, retryErr)
+	}()
+}
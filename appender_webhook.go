@@ -0,0 +1,415 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// DefaultWebhookTemplate posts the text of the message, which Slack and Teams incoming webhooks accept
+const DefaultWebhookTemplate = `{"text":{{json .Text}}}`
+
+const defaultWebhookWindow = time.Minute
+
+const defaultWebhookMaxPerWindow = 1
+
+const defaultWebhookQueueSize = 64
+
+// webhookMaxText bounds the message of an event in the text of a webhook message
+const webhookMaxText = 1000
+
+// WebhookAppenderConfig
+type WebhookAppenderConfig struct {
+	// Url of the incoming webhook
+	Url string
+
+	// Template renders the JSON payload of a WebhookMessage with text/template.
+	// The json function encodes a value as JSON, e.g. {"text":{{json .Text}}}.
+	Template string
+
+	// Headers are added to every request, e.g. Authorization
+	Headers map[string]string
+
+	// Window is the period in which events are aggregated
+	Window time.Duration
+
+	// MaxPerWindow events are sent as soon as they are appended in a window, the following events
+	// of the window are aggregated into a single message sent at the end of the window. 0 aggregates all events.
+	MaxPerWindow int
+
+	// QueueSize bounds the messages waiting to be sent
+	QueueSize  int
+	MaxRetries int
+	Timeout    time.Duration
+
+	// HttpClient is used instead of a client with Timeout if specified
+	HttpClient *http.Client
+}
+
+// NewDefaultWebhookAppenderConfig
+func NewDefaultWebhookAppenderConfig() WebhookAppenderConfig {
+	return WebhookAppenderConfig{
+		Template:     DefaultWebhookTemplate,
+		Window:       defaultWebhookWindow,
+		MaxPerWindow: defaultWebhookMaxPerWindow,
+		QueueSize:    defaultWebhookQueueSize,
+		MaxRetries:   defaultMaxRetries,
+		Timeout:      defaultHttpTimeout,
+	}
+}
+
+// AlertEvent is an event in an alert such as a WebhookMessage
+type AlertEvent struct {
+	Level      string
+	Time       time.Time
+	LoggerName string
+	Message    string
+	SourceFile string
+	SourceLine int
+	TraceId    string
+	SpanId     string
+	Fields     map[string]interface{}
+	StackTrace string
+}
+
+// WebhookMessage is the data of the template.
+// A message of a single event has Count 1 and the event as both First and Last.
+type WebhookMessage struct {
+	// Level is the highest level of the events
+	Level string
+
+	// Count is the number of the events
+	Count int
+
+	First AlertEvent
+	Last  AlertEvent
+
+	// Aggregated is true for the message sent at the end of a window
+	Aggregated bool
+
+	// Start and End are the times of the first and the last events
+	Start time.Time
+	End   time.Time
+
+	level LogLevel
+}
+
+// Text returns a summary of the message in plain text
+func (message WebhookMessage) Text() string {
+	if message.Count <= 1 {
+		return message.First.text()
+	}
+	return fmt.Sprintf("[%s] %d events in %s\nfirst: %s\nlast: %s",
+		message.Level, message.Count, message.End.Sub(message.Start).Round(time.Second), message.First.text(), message.Last.text())
+}
+
+// text
+func (event AlertEvent) text() string {
+	message := event.Message
+	if len(message) > webhookMaxText {
+		message = message[:webhookMaxText] + "..."
+	}
+
+	text := "[" + event.Level + "] "
+	if event.LoggerName != "" {
+		text += event.LoggerName + ": "
+	}
+	text += message
+	if event.SourceFile != "" {
+		text += fmt.Sprintf(" (%s:%d)", event.SourceFile, event.SourceLine)
+	}
+	return text
+}
+
+// WebhookAppender posts alerts to a webhook such as Slack or Microsoft Teams.
+// It is meant for ERROR and FATAL events, set with SetAppenderWithLevels.
+// A window starts at the first event, the first MaxPerWindow events of the window are posted as they come
+// and the rest are aggregated into one message with their count and the first and the last events.
+// While a burst continues a new window starts at the end of the previous one,
+// so that a burst is reported by one message per window.
+// Messages are posted from a background goroutine.
+type WebhookAppender struct {
+	config   WebhookAppenderConfig
+	client   *http.Client
+	template *template.Template
+
+	mu          *sync.Mutex
+	windowStart time.Time
+	sent        int
+	aggregate   *WebhookMessage
+	timer       *time.Timer
+	closed      bool
+
+	queue   chan []byte
+	pending *sync.WaitGroup
+	done    chan struct{}
+
+	// closing is closed by Close to stop waiting for retries
+	closing chan struct{}
+}
+
+// NewWebhookAppender returns new WebhookAppender, or an error if the template can not be parsed
+func NewWebhookAppender(config WebhookAppenderConfig) (*WebhookAppender, error) {
+	if config.Template == "" {
+		config.Template = DefaultWebhookTemplate
+	}
+
+	if config.Window <= 0 {
+		config.Window = defaultWebhookWindow
+	}
+
+	if config.MaxPerWindow < 0 {
+		config.MaxPerWindow = 0
+	}
+
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultWebhookQueueSize
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHttpTimeout
+	}
+
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": webhookJson}).Parse(config.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	client := config.HttpClient
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+
+	appender := &WebhookAppender{
+		config:   config,
+		client:   client,
+		template: tmpl,
+		mu:       new(sync.Mutex),
+		queue:    make(chan []byte, config.QueueSize),
+		pending:  new(sync.WaitGroup),
+		done:     make(chan struct{}),
+		closing:  make(chan struct{}),
+	}
+	go appender.run()
+	return appender, nil
+}
+
+// webhookJson encodes the value as JSON for templates
+func webhookJson(value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+// AppendEvent implements EventAppender
+func (appender *WebhookAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	return appender.append(newEventRecord(level, logEvent, metadata))
+}
+
+// Write implements io.Writer
+// Data is sent as the message of an INFO event.
+func (appender *WebhookAppender) Write(data []byte) (n int, err error) {
+	if err := appender.append(newRawEventRecord(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush implements Syncer
+// The aggregated events are sent without waiting for the end of the window.
+func (appender *WebhookAppender) Flush() error {
+	appender.mu.Lock()
+	err := appender.sendAggregate()
+	appender.mu.Unlock()
+
+	appender.pending.Wait()
+	return err
+}
+
+// Close implements io.Closer
+func (appender *WebhookAppender) Close() error {
+	appender.mu.Lock()
+	if appender.closed {
+		appender.mu.Unlock()
+		return nil
+	}
+	appender.closed = true
+	close(appender.closing)
+	if appender.timer != nil {
+		appender.timer.Stop()
+	}
+	err := appender.sendAggregate()
+	close(appender.queue)
+	appender.mu.Unlock()
+
+	<-appender.done
+	return err
+}
+
+// append sends the event or adds it to the aggregate of the window
+func (appender *WebhookAppender) append(record eventRecord) error {
+	event := newAlertEvent(record)
+	level := record.level
+
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	if appender.closed {
+		return errAppenderClosed
+	}
+
+	if appender.windowStart.IsZero() {
+		appender.startWindow(0)
+	}
+
+	if appender.sent < appender.config.MaxPerWindow {
+		appender.sent++
+		return appender.send(WebhookMessage{
+			Level: event.Level,
+			Count: 1,
+			First: event,
+			Last:  event,
+			Start: event.Time,
+			End:   event.Time,
+			level: level,
+		})
+	}
+
+	if appender.aggregate == nil {
+		appender.aggregate = &WebhookMessage{
+			Level:      event.Level,
+			First:      event,
+			Aggregated: true,
+			Start:      event.Time,
+			level:      level,
+		}
+	}
+	aggregate := appender.aggregate
+	aggregate.Count++
+	aggregate.Last = event
+	aggregate.End = event.Time
+	if level.TypeVal() > aggregate.level.TypeVal() {
+		aggregate.Level = event.Level
+		aggregate.level = level
+	}
+	return nil
+}
+
+// startWindow starts a window in which sent events have already been sent
+func (appender *WebhookAppender) startWindow(sent int) {
+	appender.windowStart = time.Now()
+	appender.sent = sent
+	appender.timer = time.AfterFunc(appender.config.Window, appender.endWindow)
+}
+
+// endWindow sends the aggregate. If the window had aggregated events the burst is assumed to continue
+// and the next window starts without sending events as they come.
+func (appender *WebhookAppender) endWindow() {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+
+	if appender.closed {
+		return
+	}
+
+	if appender.aggregate == nil {
+		appender.windowStart = time.Time{}
+		return
+	}
+	appender.sendAggregate()
+	appender.startWindow(appender.config.MaxPerWindow)
+}
+
+// sendAggregate sends the aggregated events, appender.mu must be held
+func (appender *WebhookAppender) sendAggregate() error {
+	if appender.aggregate == nil {
+		return nil
+	}
+	aggregate := *appender.aggregate
+	appender.aggregate = nil
+	return appender.send(aggregate)
+}
+
+// send renders the message and queues it, appender.mu must be held
+func (appender *WebhookAppender) send(message WebhookMessage) error {
+	payload, err := appender.render(message)
+	if err != nil {
+		return err
+	}
+
+	appender.pending.Add(1)
+	select {
+	case appender.queue <- payload:
+		return nil
+	default:
+		appender.pending.Done()
+		return errQueueFull
+	}
+}
+
+// render executes the template, the payload must be JSON
+func (appender *WebhookAppender) render(message WebhookMessage) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := appender.template.Execute(&buffer, message); err != nil {
+		return nil, err
+	}
+	if !json.Valid(buffer.Bytes()) {
+		return nil, errors.New("webhook template does not render JSON: " + buffer.String())
+	}
+	return buffer.Bytes(), nil
+}
+
+// run posts the queued payloads until the queue is closed
+func (appender *WebhookAppender) run() {
+	defer close(appender.done)
+
+	for payload := range appender.queue {
+		if err := appender.post(payload); err != nil {
+			warnExportError(err)
+		}
+		appender.pending.Done()
+	}
+}
+
+// post
+func (appender *WebhookAppender) post(payload []byte) error {
+	_, err := doWithRetry(appender.client, appender.config.MaxRetries, appender.closing, func() (*http.Request, error) {
+		request, err := http.NewRequest(http.MethodPost, appender.config.Url, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", "application/json")
+		for name, value := range appender.config.Headers {
+			request.Header.Set(name, value)
+		}
+		return request, nil
+	})
+	return err
+}
+
+// newAlertEvent
+func newAlertEvent(record eventRecord) AlertEvent {
+	metadata := record.metadata
+	event := AlertEvent{
+		Level:      levelName(record.level),
+		Time:       record.time,
+		LoggerName: metadata.LoggerName,
+		Message:    strings.TrimRight(record.message, "\n"),
+		SourceFile: metadata.SourceFile,
+		SourceLine: metadata.SourceLine,
+		TraceId:    metadata.TraceId,
+		SpanId:     metadata.SpanId,
+	}
+	if len(metadata.Fields) > 0 {
+		event.Fields = metadata.Fields.Map()
+	}
+	if len(metadata.StackTrace) > 0 {
+		event.StackTrace = metadata.StackTrace.String()
+	}
+	return event
+}
@@ -0,0 +1,185 @@
+package golog
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookServer records the payloads posted to it
+type webhookServer struct {
+	*httptest.Server
+	mu       *sync.Mutex
+	payloads []map[string]interface{}
+	headers  []http.Header
+}
+
+// newWebhookServer
+func newWebhookServer() *webhookServer {
+	server := &webhookServer{mu: new(sync.Mutex)}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		server.payloads = append(server.payloads, payload)
+		server.headers = append(server.headers, request.Header)
+	}))
+	return server
+}
+
+// received returns the payloads posted so far
+func (server *webhookServer) received() []map[string]interface{} {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]map[string]interface{}(nil), server.payloads...)
+}
+
+// newTestWebhookAppender
+func newTestWebhookAppender(t *testing.T, server *webhookServer, window time.Duration, template string) *WebhookAppender {
+	config := NewDefaultWebhookAppenderConfig()
+	config.Url = server.URL
+	config.Window = window
+	if template != "" {
+		config.Template = template
+	}
+	appender, err := NewWebhookAppender(config)
+	assert.Nil(t, err)
+	return appender
+}
+
+// appendWebhookEvent
+func appendWebhookEvent(t *testing.T, appender *WebhookAppender, level LogLevel, message string, fields ...Field) {
+	metadata := &LogEventMetadata{LoggerName: "api", SourceFile: "main.go", SourceLine: 10, Fields: fields}
+	assert.Nil(t, appender.AppendEvent(level, &TextLogEvent{Event: message}, metadata))
+}
+
+func TestWebhookMessage_Text(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	first := AlertEvent{Level: "ERROR", LoggerName: "api", Message: "db is down", SourceFile: "db.go", SourceLine: 42}
+	last := AlertEvent{Level: "FATAL", Message: "giving up"}
+
+	assert.Equal(t, "[ERROR] api: db is down (db.go:42)", WebhookMessage{Level: "ERROR", Count: 1, First: first, Last: first}.Text())
+	assert.Equal(t, "[FATAL] 12 events in 1m30s\nfirst: [ERROR] api: db is down (db.go:42)\nlast: [FATAL] giving up",
+		WebhookMessage{Level: "FATAL", Count: 12, First: first, Last: last, Start: start, End: start.Add(time.Second * 90)}.Text())
+}
+
+func TestWebhookAppender_template(t *testing.T) {
+	server := newWebhookServer()
+	defer server.Close()
+
+	// default template
+	func() {
+		appender := newTestWebhookAppender(t, server, time.Minute, "")
+		appendWebhookEvent(t, appender, LogLevel_ERROR, "payment \"failed\"\n")
+		assert.Nil(t, appender.Close())
+
+		payloads := server.received()
+		assert.Equal(t, 1, len(payloads))
+		assert.Equal(t, map[string]interface{}{"text": "[ERROR] api: payment \"failed\" (main.go:10)"}, payloads[0])
+		assert.Equal(t, "application/json", server.headers[0].Get("Content-Type"))
+	}()
+
+	// metadata and fields
+	func() {
+		config := NewDefaultWebhookAppenderConfig()
+		config.Url = server.URL
+		config.Headers = map[string]string{"Authorization": "Bearer token"}
+		config.Template = `{"title":{{json .First.Message}},"user":{{json (index .First.Fields "user")}},"logger":{{json .First.LoggerName}},"count":{{.Count}}}`
+		appender, err := NewWebhookAppender(config)
+		assert.Nil(t, err)
+		appendWebhookEvent(t, appender, LogLevel_ERROR, "login failed", F("user", "alice"))
+		assert.Nil(t, appender.Close())
+
+		payloads := server.received()
+		assert.Equal(t, map[string]interface{}{"title": "login failed", "user": "alice", "logger": "api", "count": float64(1)}, payloads[len(payloads)-1])
+		assert.Equal(t, "Bearer token", server.headers[len(payloads)-1].Get("Authorization"))
+	}()
+
+	// templates which do not parse or render JSON
+	func() {
+		config := NewDefaultWebhookAppenderConfig()
+		config.Url = server.URL
+		config.Template = `{"text":{{json .Text}`
+		_, err := NewWebhookAppender(config)
+		assert.NotNil(t, err)
+
+		appender := newTestWebhookAppender(t, server, time.Minute, `{"text":{{.Text}}}`)
+		defer appender.Close()
+		err = appender.AppendEvent(LogLevel_ERROR, &TextLogEvent{Event: "failed"}, nil)
+		assert.NotNil(t, err)
+	}()
+}
+
+func TestWebhookAppender_window(t *testing.T) {
+	server := newWebhookServer()
+	defer server.Close()
+
+	window := time.Millisecond * 300
+	appender := newTestWebhookAppender(t, server, window, `{"count":{{.Count}},"level":{{json .Level}},"first":{{json .First.Message}},"last":{{json .Last.Message}},"aggregated":{{.Aggregated}}}`)
+	defer appender.Close()
+
+	// the first event is sent at once, the burst is aggregated until the end of the window
+	appendWebhookEvent(t, appender, LogLevel_ERROR, "e1")
+	appendWebhookEvent(t, appender, LogLevel_ERROR, "e2")
+	appendWebhookEvent(t, appender, LogLevel_FATAL, "e3")
+	appendWebhookEvent(t, appender, LogLevel_ERROR, "e4")
+	assert.Eventually(t, func() bool { return len(server.received()) == 1 }, time.Second, time.Millisecond*10)
+	assert.Equal(t, map[string]interface{}{"count": float64(1), "level": "ERROR", "first": "e1", "last": "e1", "aggregated": false}, server.received()[0])
+
+	assert.Eventually(t, func() bool { return len(server.received()) == 2 }, time.Second*2, time.Millisecond*10)
+	assert.Equal(t, map[string]interface{}{"count": float64(3), "level": "FATAL", "first": "e2", "last": "e4", "aggregated": true}, server.received()[1])
+
+	// the burst continues in the next window
+	appendWebhookEvent(t, appender, LogLevel_ERROR, "e5")
+	appendWebhookEvent(t, appender, LogLevel_ERROR, "e6")
+	assert.Eventually(t, func() bool { return len(server.received()) == 3 }, time.Second*2, time.Millisecond*10)
+	assert.Equal(t, map[string]interface{}{"count": float64(2), "level": "ERROR", "first": "e5", "last": "e6", "aggregated": true}, server.received()[2])
+
+	// a quiet window ends the burst
+	time.Sleep(window * 2)
+	appendWebhookEvent(t, appender, LogLevel_ERROR, "e7")
+	assert.Eventually(t, func() bool { return len(server.received()) == 4 }, time.Second, time.Millisecond*10)
+	assert.Equal(t, "e7", server.received()[3]["first"])
+	assert.Equal(t, false, server.received()[3]["aggregated"])
+}
+
+func TestWebhookAppender_MaxPerWindow(t *testing.T) {
+	server := newWebhookServer()
+	defer server.Close()
+
+	config := NewDefaultWebhookAppenderConfig()
+	config.Url = server.URL
+	config.MaxPerWindow = 2
+	config.Template = `{"count":{{.Count}},"first":{{json .First.Message}}}`
+	appender, err := NewWebhookAppender(config)
+	assert.Nil(t, err)
+
+	for _, message := range []string{"e1", "e2", "e3", "e4", "e5"} {
+		appendWebhookEvent(t, appender, LogLevel_ERROR, message)
+	}
+	assert.Nil(t, appender.Flush())
+	assert.Equal(t, []map[string]interface{}{
+		{"count": float64(1), "first": "e1"},
+		{"count": float64(1), "first": "e2"},
+		{"count": float64(3), "first": "e3"},
+	}, server.received())
+
+	// aggregated events are sent on close
+	appendWebhookEvent(t, appender, LogLevel_ERROR, "e6")
+	assert.Nil(t, appender.Close())
+	assert.Equal(t, map[string]interface{}{"count": float64(1), "first": "e6"}, server.received()[3])
+	assert.Equal(t, errAppenderClosed, appender.AppendEvent(LogLevel_ERROR, &TextLogEvent{Event: "e7"}, nil))
+	assert.Nil(t, appender.Close())
+}
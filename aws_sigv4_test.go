@@ -0,0 +1,45 @@
+package golog
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAwsRequest(t *testing.T) {
+
+	// examples of the Signature Version 4 test suite
+	credentials := awsCredentials{
+		accessKeyId:     "AKIDEXAMPLE",
+		secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	func() {
+		request, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+		signAwsRequest(request, nil, credentials, "us-east-1", "service", now)
+		assert.Equal(t, "20150830T123600Z", request.Header.Get("X-Amz-Date"))
+		assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+			"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+			request.Header.Get("Authorization"))
+	}()
+
+	func() {
+		request, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/?Param2=value2&Param1=value1", nil)
+		signAwsRequest(request, nil, credentials, "us-east-1", "service", now)
+		assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+			"SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+			request.Header.Get("Authorization"))
+	}()
+
+	// the session token is signed
+	func() {
+		credentials.sessionToken = "token"
+		request, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+		signAwsRequest(request, nil, credentials, "us-east-1", "service", now)
+		assert.Equal(t, "token", request.Header.Get("X-Amz-Security-Token"))
+		assert.Contains(t, request.Header.Get("Authorization"), "SignedHeaders=host;x-amz-date;x-amz-security-token,")
+	}()
+}
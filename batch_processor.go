@@ -0,0 +1,255 @@
+package golog
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultMaxBatchSize = 512
+
+const defaultBatchFlushInterval = time.Second * 5
+
+const defaultBatchQueueSize = 4096
+
+// errQueueFull is returned when an event can not be queued without blocking the caller
+var errQueueFull = errors.New("appender queue is full")
+
+// errAppenderClosed
+var errAppenderClosed = errors.New("appender is closed")
+
+// eventRecord is an event captured for the appenders which export events asynchronously
+type eventRecord struct {
+	level LogLevel
+
+	// message is the event encoded without metadata
+	message string
+
+	// metadata is a copy of the event metadata, empty if metadata is disabled
+	metadata LogEventMetadata
+
+	// time is the time of the event, the time it was appended if metadata has no time
+	time time.Time
+
+	// raw is true for data written through io.Writer, which may have been encoded with metadata
+	raw bool
+}
+
+// newEventRecord
+func newEventRecord(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) eventRecord {
+	record := eventRecord{
+		level:   level,
+		message: string(logEvent.Encode(nil)),
+		time:    time.Now(),
+	}
+
+	if metadata != nil {
+		record.metadata = *metadata
+		if metadata.UnixNano != 0 {
+			record.time = time.Unix(0, metadata.UnixNano)
+		}
+	}
+	return record
+}
+
+// newRawEventRecord captures data written through io.Writer, which carries no level
+func newRawEventRecord(data []byte) eventRecord {
+	return eventRecord{
+		level:   LogLevel_INFO,
+		message: string(data),
+		time:    time.Now(),
+		raw:     true,
+	}
+}
+
+// batchProcessor queues records and exports them in batches from a background goroutine.
+// A batch is exported when it reaches maxBatchSize records or maxBatchBytes bytes,
+// when flushInterval elapses, on flush and on close.
+// Records dropped as the queue is full are counted and reported every flushInterval.
+type batchProcessor struct {
+	export        func(records []eventRecord) error
+	maxBatchSize  int
+	maxBatchBytes int
+	sizeOf        func(record eventRecord) int
+	flushInterval time.Duration
+	queue         chan eventRecord
+	flushes       chan chan error
+	closed        chan struct{}
+	done          chan struct{}
+	closeOnce     *sync.Once
+
+	// dropped is the number of records dropped since the last report
+	dropped *atomic.Int64
+}
+
+// newBatchProcessor returns new batchProcessor and starts its goroutine
+func newBatchProcessor(export func(records []eventRecord) error, maxBatchSize int, flushInterval time.Duration, queueSize int) *batchProcessor {
+	return newBatchProcessorWithMaxBytes(export, maxBatchSize, 0, nil, flushInterval, queueSize)
+}
+
+// newBatchProcessorWithMaxBytes returns new batchProcessor whose batches are also bounded by maxBatchBytes
+// as measured by sizeOf. A record larger than maxBatchBytes is exported alone.
+func newBatchProcessorWithMaxBytes(export func(records []eventRecord) error, maxBatchSize int, maxBatchBytes int, sizeOf func(record eventRecord) int, flushInterval time.Duration, queueSize int) *batchProcessor {
+	if maxBatchSize <= 0 {
+		maxBatchSize = defaultMaxBatchSize
+	}
+
+	if flushInterval <= 0 {
+		flushInterval = defaultBatchFlushInterval
+	}
+
+	if queueSize <= 0 {
+		queueSize = defaultBatchQueueSize
+	}
+
+	if sizeOf == nil {
+		maxBatchBytes = 0
+	}
+
+	processor := &batchProcessor{
+		export:        export,
+		maxBatchSize:  maxBatchSize,
+		maxBatchBytes: maxBatchBytes,
+		sizeOf:        sizeOf,
+		flushInterval: flushInterval,
+		queue:         make(chan eventRecord, queueSize),
+		flushes:       make(chan chan error),
+		closed:        make(chan struct{}),
+		done:          make(chan struct{}),
+		closeOnce:     new(sync.Once),
+		dropped:       new(atomic.Int64),
+	}
+
+	go processor.run()
+	return processor
+}
+
+// enqueue queues the record without blocking
+func (processor *batchProcessor) enqueue(record eventRecord) error {
+	select {
+	case <-processor.closed:
+		return errAppenderClosed
+	default:
+	}
+
+	select {
+	case processor.queue <- record:
+		return nil
+	default:
+		processor.dropped.Add(1)
+		return errQueueFull
+	}
+}
+
+// closing returns a channel closed when close is called.
+// Exports wait for retries until it is closed, so that close does not wait for the backoff.
+func (processor *batchProcessor) closing() <-chan struct{} {
+	return processor.closed
+}
+
+// flush exports the queued records and waits for the export
+func (processor *batchProcessor) flush() error {
+	result := make(chan error, 1)
+	select {
+	case processor.flushes <- result:
+	case <-processor.done:
+		return nil
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-processor.done:
+		return nil
+	}
+}
+
+// close exports the queued records and stops the goroutine
+func (processor *batchProcessor) close() {
+	processor.closeOnce.Do(func() {
+		close(processor.closed)
+	})
+	<-processor.done
+}
+
+// run
+func (processor *batchProcessor) run() {
+	defer close(processor.done)
+
+	ticker := time.NewTicker(processor.flushInterval)
+	defer ticker.Stop()
+
+	var batch []eventRecord
+	batchBytes := 0
+
+	exportBatch := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := processor.export(batch)
+		batch = nil
+		batchBytes = 0
+		return err
+	}
+
+	add := func(record eventRecord) error {
+		var err error
+		if processor.maxBatchBytes > 0 {
+			size := processor.sizeOf(record)
+			if len(batch) > 0 && batchBytes+size > processor.maxBatchBytes {
+				err = exportBatch()
+			}
+			batchBytes += size
+		}
+
+		batch = append(batch, record)
+		if len(batch) >= processor.maxBatchSize || (processor.maxBatchBytes > 0 && batchBytes >= processor.maxBatchBytes) {
+			err = errors.Join(err, exportBatch())
+		}
+		return err
+	}
+
+	drain := func() error {
+		var errs []error
+		for {
+			select {
+			case record := <-processor.queue:
+				errs = append(errs, add(record))
+			default:
+				return errors.Join(append(errs, exportBatch())...)
+			}
+		}
+	}
+
+	for {
+		select {
+		case record := <-processor.queue:
+			warnExportError(add(record))
+		case <-ticker.C:
+			warnExportError(exportBatch())
+			processor.warnDropped()
+		case result := <-processor.flushes:
+			result <- drain()
+		case <-processor.closed:
+			warnExportError(drain())
+			processor.warnDropped()
+			return
+		}
+	}
+}
+
+// warnDropped reports the records dropped since the last report.
+// The logger ignores the errors of appenders, so this is the only trace of them.
+func (processor *batchProcessor) warnDropped() {
+	if dropped := processor.dropped.Swap(0); dropped > 0 {
+		warnLogger.Warnf("%d events are dropped , error : %s", dropped, errQueueFull.Error())
+	}
+}
+
+// warnExportError reports errors of background exports, which have no caller to return to
+func warnExportError(err error) {
+	if err != nil {
+		warnLogger.Warnf("export events is failed , error : %s", err.Error())
+	}
+}
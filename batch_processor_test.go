@@ -0,0 +1,113 @@
+package golog
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// batchRecorder records exported batches
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (recorder *batchRecorder) export(records []eventRecord) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	var batch []string
+	for _, record := range records {
+		batch = append(batch, record.message)
+	}
+	recorder.batches = append(recorder.batches, batch)
+	return nil
+}
+
+func (recorder *batchRecorder) get() [][]string {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.batches
+}
+
+func TestBatchProcessor(t *testing.T) {
+
+	// max batch size and close
+	func() {
+		recorder := &batchRecorder{}
+		processor := newBatchProcessor(recorder.export, 2, time.Hour, 10)
+		for _, message := range []string{"a", "b", "c"} {
+			assert.Nil(t, processor.enqueue(newRawEventRecord([]byte(message))))
+		}
+		processor.close()
+		assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, recorder.get())
+		assert.Equal(t, errAppenderClosed, processor.enqueue(newRawEventRecord([]byte("d"))))
+	}()
+
+	// max batch bytes and flush
+	func() {
+		recorder := &batchRecorder{}
+		sizeOf := func(record eventRecord) int {
+			return len(record.message)
+		}
+		processor := newBatchProcessorWithMaxBytes(recorder.export, 10, 4, sizeOf, time.Hour, 10)
+		defer processor.close()
+		for _, message := range []string{"ab", "c", "de", "fghij"} {
+			assert.Nil(t, processor.enqueue(newRawEventRecord([]byte(message))))
+		}
+		assert.Nil(t, processor.flush())
+		assert.Equal(t, [][]string{{"ab", "c"}, {"de"}, {"fghij"}}, recorder.get())
+	}()
+
+	// flush interval
+	func() {
+		recorder := &batchRecorder{}
+		processor := newBatchProcessor(recorder.export, 10, 10*time.Millisecond, 10)
+		defer processor.close()
+		processor.enqueue(newRawEventRecord([]byte("a")))
+		assert.Eventually(t, func() bool {
+			return len(recorder.get()) == 1
+		}, time.Second, 5*time.Millisecond)
+	}()
+	// records are dropped and counted when the queue is full
+	func() {
+		release := make(chan struct{})
+		processor := newBatchProcessor(func(records []eventRecord) error {
+			<-release
+			return nil
+		}, 1, time.Hour, 1)
+
+		dropped := 0
+		for i := 0; i < 10; i++ {
+			if err := processor.enqueue(newRawEventRecord([]byte("a"))); err != nil {
+				assert.Equal(t, errQueueFull, err)
+				dropped++
+			}
+		}
+		assert.True(t, dropped >= 8)
+		assert.Equal(t, int64(dropped), processor.dropped.Load())
+
+		processor.warnDropped()
+		assert.Equal(t, int64(0), processor.dropped.Load())
+		close(release)
+		processor.close()
+	}()
+
+	// close does not wait for the backoff of retries
+	func() {
+		var processor *batchProcessor
+		processor = newBatchProcessor(func(records []eventRecord) error {
+			if waitRetry(time.Hour, processor.closing()) {
+				return nil
+			}
+			return errors.New("export is failed")
+		}, 1, time.Hour, 10)
+		assert.Nil(t, processor.enqueue(newRawEventRecord([]byte("a"))))
+
+		start := time.Now()
+		processor.close()
+		assert.Less(t, time.Since(start), 5*time.Second)
+	}()
+}
@@ -0,0 +1,50 @@
+package golog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// loggerKey
+type loggerKey struct{}
+
+// requestIdKey
+type requestIdKey struct{}
+
+// ContextWithLogger returns a copy of ctx which carries the logger, e.g. a request scoped logger
+func ContextWithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext returns the logger set by ContextWithLogger
+func LoggerFromContext(ctx context.Context) (Logger, bool) {
+	if ctx == nil {
+		return Logger{}, false
+	}
+	logger, ok := ctx.Value(loggerKey{}).(Logger)
+	return logger, ok
+}
+
+// ContextWithRequestId returns a copy of ctx which carries the request id
+func ContextWithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+// RequestIdFromContext returns the request id set by ContextWithRequestId
+func RequestIdFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	requestId, ok := ctx.Value(requestIdKey{}).(string)
+	return requestId, ok && requestId != ""
+}
+
+// NewRequestId returns a random request id of 32 hex characters
+func NewRequestId() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,150 @@
+package golog
+
+import (
+	"reflect"
+	"strings"
+)
+
+// maxErrorDepth limits how deep error chains are unwrapped
+const maxErrorDepth = 32
+
+// ErrorDetail is the structured form of an error.
+// Wrapped errors (fmt.Errorf with %w, errors.Join) are unwrapped into Causes.
+type ErrorDetail struct {
+	Message    string        `json:"message"`
+	Causes     []ErrorDetail `json:"causes,omitempty"`
+	StackTrace StackTrace    `json:"stackTrace,omitempty"`
+}
+
+// Err returns Field which holds the detail of err under the "error" key
+func Err(err error) Field {
+	return F("error", NewErrorDetail(err))
+}
+
+// NewErrorDetail unwraps err and collects the stack traces carried by the chain
+func NewErrorDetail(err error) ErrorDetail {
+	return newErrorDetail(err, 0)
+}
+
+// newErrorDetail
+func newErrorDetail(err error, depth int) ErrorDetail {
+	if err == nil {
+		return ErrorDetail{}
+	}
+
+	// the stack of WithStack belongs to the wrapped error itself
+	if withStack, ok := err.(*stackError); ok {
+		detail := newErrorDetail(withStack.err, depth)
+		if detail.StackTrace == nil {
+			detail.StackTrace = withStack.stackTrace
+		}
+		return detail
+	}
+
+	detail := ErrorDetail{
+		Message:    err.Error(),
+		StackTrace: stackTraceOf(err),
+	}
+
+	if depth >= maxErrorDepth {
+		return detail
+	}
+
+	switch wrapper := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, cause := range wrapper.Unwrap() {
+			if cause != nil {
+				detail.Causes = append(detail.Causes, newErrorDetail(cause, depth+1))
+			}
+		}
+	case interface{ Unwrap() error }:
+		if cause := wrapper.Unwrap(); cause != nil {
+			detail.Causes = append(detail.Causes, newErrorDetail(cause, depth+1))
+		}
+	}
+
+	return detail
+}
+
+// String implements stringer
+func (detail ErrorDetail) String() string {
+	return detail.Message
+}
+
+// stackTraceBlock returns the stack traces of the chain as multi-line blocks
+func (detail ErrorDetail) stackTraceBlock() string {
+	var builder strings.Builder
+	detail.writeStackTraceBlock(&builder)
+	return builder.String()
+}
+
+// writeStackTraceBlock
+func (detail ErrorDetail) writeStackTraceBlock(builder *strings.Builder) {
+	if len(detail.StackTrace) > 0 {
+		builder.WriteString("\n")
+		builder.WriteString(detail.Message)
+		builder.WriteString("\n")
+		builder.WriteString(detail.StackTrace.String())
+	}
+	for _, cause := range detail.Causes {
+		cause.writeStackTraceBlock(builder)
+	}
+}
+
+// stackError is an error which carries the stack of where it was wrapped
+type stackError struct {
+	err        error
+	stackTrace StackTrace
+}
+
+// WithStack returns err annotated with the stack of the caller.
+// It returns nil if err is nil.
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &stackError{err: err, stackTrace: NewStackTrace(1)}
+}
+
+// Error implements error
+func (err *stackError) Error() string {
+	return err.err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (err *stackError) Unwrap() error {
+	return err.err
+}
+
+// StackTrace returns the stack of where the error was wrapped
+func (err *stackError) StackTrace() StackTrace {
+	return err.stackTrace
+}
+
+// stackTraceOf returns the stack carried by err.
+// Besides golog's StackTrace, errors with a StackTrace method returning
+// program counters (e.g. github.com/pkg/errors) and a Callers method are supported.
+func stackTraceOf(err error) StackTrace {
+	switch tracer := err.(type) {
+	case interface{ StackTrace() StackTrace }:
+		return tracer.StackTrace()
+	case interface{ Callers() []uintptr }:
+		return newStackTraceFromPCs(tracer.Callers())
+	}
+
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return nil
+	}
+
+	frames := method.Call(nil)[0]
+	if frames.Kind() != reflect.Slice || frames.Type().Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+
+	pcs := make([]uintptr, frames.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(frames.Index(i).Uint())
+	}
+	return newStackTraceFromPCs(pcs)
+}
@@ -0,0 +1,101 @@
+package golog
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pcFrame mimics github.com/pkg/errors.Frame
+type pcFrame uintptr
+
+// pcStackError mimics an error of github.com/pkg/errors
+type pcStackError struct {
+	pcs []pcFrame
+}
+
+func (err *pcStackError) Error() string {
+	return "pc stack error"
+}
+
+func (err *pcStackError) StackTrace() []pcFrame {
+	return err.pcs
+}
+
+func newPCStackError() error {
+	pcs := make([]uintptr, 8)
+	n := runtime.Callers(1, pcs)
+	err := &pcStackError{}
+	for _, pc := range pcs[:n] {
+		err.pcs = append(err.pcs, pcFrame(pc))
+	}
+	return err
+}
+
+func TestNewErrorDetail(t *testing.T) {
+
+	// %w chain
+	func() {
+		base := errors.New("base")
+		detail := NewErrorDetail(fmt.Errorf("wrapped: %w", base))
+		assert.Equal(t, ErrorDetail{
+			Message: "wrapped: base",
+			Causes:  []ErrorDetail{{Message: "base"}},
+		}, detail)
+	}()
+
+	// errors.Join
+	func() {
+		detail := NewErrorDetail(errors.Join(errors.New("a"), errors.New("b")))
+		assert.Equal(t, ErrorDetail{
+			Message: "a\nb",
+			Causes:  []ErrorDetail{{Message: "a"}, {Message: "b"}},
+		}, detail)
+	}()
+
+	// WithStack
+	func() {
+		detail := NewErrorDetail(fmt.Errorf("wrapped: %w", WithStack(errors.New("base"))))
+		assert.Equal(t, "wrapped: base", detail.Message)
+		assert.Nil(t, detail.StackTrace)
+		assert.Equal(t, "base", detail.Causes[0].Message)
+		assert.Equal(t, "github.com/ajainc/golog.TestNewErrorDetail.func3", detail.Causes[0].StackTrace[0].Function)
+	}()
+
+	// program counters
+	func() {
+		detail := NewErrorDetail(newPCStackError())
+		assert.Equal(t, "github.com/ajainc/golog.newPCStackError", detail.StackTrace[0].Function)
+	}()
+}
+
+func TestErr(t *testing.T) {
+
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		errorLogger := logger.With(Err(WithStack(errors.New("failed"))))
+		errorLogger.Error("error")
+
+		lines := strings.Split(appender.String(), "\n")
+		require.GreaterOrEqual(t, len(lines), 3)
+		assert.Equal(t, "   () error error=failed", lines[0])
+		assert.Equal(t, "failed", lines[1])
+		assert.Equal(t, "\tgithub.com/ajainc/golog.TestErr.func1", lines[2])
+	}()
+
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		errorLogger := logger.With(Err(errors.New("failed")))
+		errorLogger.Errorj(struct{}{})
+		assert.Equal(t, `{"EventData":{},"fields":{"error":{"message":"failed"}}}`+"\n", appender.String())
+	}()
+}
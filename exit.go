@@ -0,0 +1,136 @@
+package golog
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ExitFunc terminates the process, os.Exit by default
+type ExitFunc = func(code int)
+
+// SetExitFunc replaces os.Exit called by Fatal, Fatalf, Fatalj and SFatal.
+// It makes code paths which log at FATAL testable.
+// The appenders are flushed but not closed before exitFunc is called, so the logger keeps working when exitFunc returns.
+func (logger *Logger) SetExitFunc(exitFunc ExitFunc) {
+	logger.exitFunc = exitFunc
+}
+
+// AddPreExitHook adds a hook called by Fatal, Fatalf, Fatalj and SFatal
+// before the appenders are closed and the process exits.
+// Hooks added to a logger returned by With are not called by the parent or the other children.
+func (logger *Logger) AddPreExitHook(hook func()) {
+	// loggers returned by With share the slice, append to a copy
+	hooks := make([]func(), 0, len(logger.preExitHooks)+1)
+	hooks = append(hooks, logger.preExitHooks...)
+	logger.preExitHooks = append(hooks, hook)
+}
+
+// EnableCrashDump writes a crash dump to fileName before exiting on FATAL.
+// The dump contains the build info, the last numberOfEvents events and the stacks of all goroutines.
+func (logger *Logger) EnableCrashDump(fileName string, numberOfEvents int) {
+	if numberOfEvents <= 0 {
+		numberOfEvents = 1
+	}
+
+	logger.crashDump = &crashDump{
+		fileName: fileName,
+		mu:       new(sync.Mutex),
+		ring:     &eventRing{size: numberOfEvents},
+	}
+}
+
+// exit runs the pre exit hooks, writes the crash dump, closes the appenders and exits.
+// With an exit func the appenders are only flushed, as the exit func may return.
+func (logger *Logger) exit() {
+	for _, hook := range logger.preExitHooks {
+		hook()
+	}
+
+	if logger.crashDump != nil {
+		if err := logger.crashDump.write(logger.Name); err != nil {
+			warnLogger.Warnf("write crash dump is failed , error : %s", err.Error())
+		}
+	}
+
+	if logger.exitFunc != nil {
+		if err := logger.Flush(); err != nil {
+			warnLogger.Warnf("flush appender is failed , error : %s", err.Error())
+		}
+	} else {
+		logger.Close()
+	}
+	logger.osExit(1)
+}
+
+// osExit calls the exit func
+func (logger *Logger) osExit(code int) {
+	if logger.exitFunc != nil {
+		logger.exitFunc(code)
+		return
+	}
+	os.Exit(code)
+}
+
+// crashDump keeps the last events for the crash dump
+type crashDump struct {
+	fileName string
+	mu       *sync.Mutex
+	ring     *eventRing
+}
+
+// record
+func (dump *crashDump) record(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) {
+	// the metadata is owned by the caller, keep a copy
+	var recorded *LogEventMetadata
+	if metadata != nil {
+		copied := *metadata
+		recorded = &copied
+	}
+
+	dump.mu.Lock()
+	defer dump.mu.Unlock()
+	dump.ring.push(recordedEvent{level: level, logEvent: snapshotLogEvent(logEvent), metadata: recorded})
+}
+
+// write
+func (dump *crashDump) write(loggerName string) error {
+	buffer := new(bytes.Buffer)
+	buffer.WriteString("golog crash dump\n")
+	buffer.WriteString("time: " + time.Now().Format(time.RFC3339) + "\n")
+	buffer.WriteString("logger: " + loggerName + "\n")
+	buffer.WriteString("pid: " + strconv.Itoa(os.Getpid()) + "\n")
+
+	buffer.WriteString("\n== build info ==\n")
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		buffer.WriteString(buildInfo.String())
+	} else {
+		buffer.WriteString("unavailable\n")
+	}
+
+	buffer.WriteString("\n== last events ==\n")
+	dump.mu.Lock()
+	events := dump.ring.drain()
+	dump.mu.Unlock()
+	for _, event := range events {
+		buffer.Write(event.logEvent.Encode(event.metadata))
+		buffer.WriteString("\n")
+	}
+
+	buffer.WriteString("\n== goroutines ==\n")
+	stacks := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(stacks, true)
+		if n < len(stacks) {
+			buffer.Write(stacks[:n])
+			break
+		}
+		stacks = make([]byte, len(stacks)*2)
+	}
+
+	return os.WriteFile(dump.fileName, buffer.Bytes(), 0666)
+}
@@ -0,0 +1,87 @@
+package golog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_SetExitFunc(t *testing.T) {
+
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+
+		var calls []string
+		logger.AddPreExitHook(func() {
+			calls = append(calls, "hook")
+		})
+		logger.SetExitFunc(func(code int) {
+			calls = append(calls, "exit")
+			assert.Equal(t, 1, code)
+		})
+
+		logger.Fatal("fatal")
+		logger.Fatalf("%s", "fatalf")
+		assert.Equal(t, []string{"hook", "exit", "hook", "exit"}, calls)
+		assert.Equal(t, "   () fatal\n   () fatalf\n", appender.String())
+
+		// the appenders are not closed as the exit func returns
+		assert.False(t, appender.isClosed())
+		logger.Info("after fatal")
+		assert.Equal(t, "   () fatal\n   () fatalf\n   () after fatal\n", appender.String())
+	}()
+
+	// hooks added to a child are not called by the parent or the siblings
+	func() {
+		logger := NewLogger("testLogger", LogLevel_TRACE, newBufferAppender())
+		logger.SetMetadataConfig(&MetadataConfig{})
+		logger.SetExitFunc(func(code int) {})
+
+		var calls []string
+		// three hooks leave spare capacity in the slice shared with the children
+		for i := 0; i < 3; i++ {
+			logger.AddPreExitHook(func() { calls = append(calls, "parent") })
+		}
+		child1 := logger.With(F("child", 1))
+		child2 := logger.With(F("child", 2))
+		child1.AddPreExitHook(func() { calls = append(calls, "child1") })
+		child2.AddPreExitHook(func() { calls = append(calls, "child2") })
+
+		logger.Fatal("parent")
+		assert.Equal(t, []string{"parent", "parent", "parent"}, calls)
+
+		calls = nil
+		child1.Fatal("child1")
+		assert.Equal(t, []string{"parent", "parent", "parent", "child1"}, calls)
+
+		calls = nil
+		child2.Fatal("child2")
+		assert.Equal(t, []string{"parent", "parent", "parent", "child2"}, calls)
+	}()
+}
+
+func TestLogger_EnableCrashDump(t *testing.T) {
+
+	func() {
+		fileName := filepath.Join(t.TempDir(), "crash")
+		logger := NewLogger("testLogger", LogLevel_TRACE, newBufferAppender())
+		logger.SetMetadataConfig(&MetadataConfig{})
+		logger.SetExitFunc(func(code int) {})
+		logger.EnableCrashDump(fileName, 2)
+
+		logger.Info("info1")
+		logger.Info("info2")
+		logger.Fatal("fatal")
+
+		dump, err := os.ReadFile(fileName)
+		assert.Nil(t, err)
+		assert.Equal(t, true, strings.Contains(string(dump), "== last events ==\n   () info2\n   () fatal\n"))
+		assert.Equal(t, true, strings.Contains(string(dump), "golog.TestLogger_EnableCrashDump"))
+		assert.Equal(t, true, strings.Contains(string(dump), "== build info =="))
+	}()
+}
@@ -0,0 +1,81 @@
+package golog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field is a structured key/value pair attached to a log event
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Fields
+type Fields []Field
+
+// F returns new Field
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Get returns the value of the last field with the given key
+func (fields Fields) Get(key string) (interface{}, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == key {
+			return fields[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// Map returns fields as a map, suitable for json encoding.
+// Later fields override earlier fields with the same key.
+func (fields Fields) Map() map[string]interface{} {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	m := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if err, ok := field.Value.(error); ok {
+			m[field.Key] = err.Error()
+			continue
+		}
+		m[field.Key] = field.Value
+	}
+	return m
+}
+
+// String returns fields formatted as space separated key=value pairs
+func (fields Fields) String() string {
+	var builder strings.Builder
+	for i, field := range fields {
+		if i > 0 {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(field.Key)
+		builder.WriteString("=")
+		builder.WriteString(formatFieldValue(field.Value))
+	}
+	return builder.String()
+}
+
+// formatFieldValue
+func formatFieldValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s == "" || strings.ContainsAny(s, " =\"\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
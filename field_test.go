@@ -0,0 +1,52 @@
+package golog
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFields_String(t *testing.T) {
+
+	cases := []struct {
+		input    Fields
+		expected string
+	}{
+		{input: Fields{}, expected: ""},
+		{input: Fields{F("a", 1), F("b", "x")}, expected: "a=1 b=x"},
+		{input: Fields{F("msg", "a b"), F("empty", "")}, expected: `msg="a b" empty=""`},
+		{input: Fields{F("error", errors.New("failed"))}, expected: "error=failed"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, c.input.String())
+	}
+}
+
+func TestFields_Get(t *testing.T) {
+
+	func() {
+		fields := Fields{F("a", 1), F("a", 2)}
+		value, ok := fields.Get("a")
+		assert.Equal(t, true, ok)
+		assert.Equal(t, 2, value)
+
+		_, ok = fields.Get("b")
+		assert.Equal(t, false, ok)
+	}()
+}
+
+func TestLogger_With(t *testing.T) {
+
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		child := logger.With(F("a", 1))
+		grandchild := child.With(F("b", 2))
+		grandchild.Info("child")
+		logger.Info("parent")
+		assert.Equal(t, "   () child a=1 b=2\n   () parent\n", appender.String())
+	}()
+}
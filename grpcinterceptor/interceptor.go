@@ -0,0 +1,356 @@
+// Package grpcinterceptor provides gRPC interceptors which log each RPC through golog.
+package grpcinterceptor
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ajainc/golog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const defaultRequestIdMetadataKey = "x-request-id"
+
+const traceparentMetadataKey = "traceparent"
+
+// Options
+type Options struct {
+	// RequestIdMetadataKey is read from incoming and set on outgoing metadata. x-request-id is used if empty.
+	RequestIdMetadataKey string
+
+	// NewRequestId generates the request id when the client sends none. golog.NewRequestId is used if nil.
+	NewRequestId func() string
+
+	// CodeToLevel returns the level of the event of a finished RPC. DefaultCodeToLevel is used if nil.
+	CodeToLevel func(code codes.Code) golog.LogLevel
+}
+
+// NewDefaultOptions
+func NewDefaultOptions() Options {
+	return Options{
+		RequestIdMetadataKey: defaultRequestIdMetadataKey,
+		NewRequestId:         golog.NewRequestId,
+		CodeToLevel:          DefaultCodeToLevel,
+	}
+}
+
+// withDefaults
+func (options Options) withDefaults() Options {
+	if options.RequestIdMetadataKey == "" {
+		options.RequestIdMetadataKey = defaultRequestIdMetadataKey
+	}
+	if options.NewRequestId == nil {
+		options.NewRequestId = golog.NewRequestId
+	}
+	if options.CodeToLevel == nil {
+		options.CodeToLevel = DefaultCodeToLevel
+	}
+	return options
+}
+
+// DefaultCodeToLevel maps OK to INFO, client errors to WARN and server errors to ERROR
+func DefaultCodeToLevel(code codes.Code) golog.LogLevel {
+	switch code {
+	case codes.OK:
+		return golog.LogLevel_INFO
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return golog.LogLevel_WARN
+	default:
+		return golog.LogLevel_ERROR
+	}
+}
+
+// UnaryServerInterceptor logs each unary RPC and puts a request scoped logger into the context,
+// see golog.LoggerFromContext
+func UnaryServerInterceptor(logger golog.Logger, options Options) grpc.UnaryServerInterceptor {
+	options = options.withDefaults()
+
+	return func(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, requestLogger := newServerContext(ctx, logger, options, info.FullMethod)
+
+		response, err := handler(ctx, request)
+
+		logFinished(requestLogger, options, "finished unary call", start, err)
+		return response, err
+	}
+}
+
+// StreamServerInterceptor logs each streaming RPC with its message counts and puts
+// a request scoped logger into the context, see golog.LoggerFromContext
+func StreamServerInterceptor(logger golog.Logger, options Options) grpc.StreamServerInterceptor {
+	options = options.withDefaults()
+
+	return func(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, requestLogger := newServerContext(stream.Context(), logger, options, info.FullMethod)
+
+		counted := &countingServerStream{ServerStream: stream, ctx: ctx}
+		err := handler(server, counted)
+
+		requestLogger = requestLogger.With(
+			golog.F("grpc.recv_count", counted.received),
+			golog.F("grpc.sent_count", counted.sent))
+		logFinished(requestLogger, options, "finished streaming call", start, err)
+		return err
+	}
+}
+
+// newServerContext returns the context with the request id and the request scoped logger
+func newServerContext(ctx context.Context, logger golog.Logger, options Options, fullMethod string) (context.Context, golog.Logger) {
+	incoming, _ := metadata.FromIncomingContext(ctx)
+
+	requestId := firstValue(incoming, options.RequestIdMetadataKey)
+	if requestId == "" {
+		requestId = options.NewRequestId()
+	}
+	ctx = golog.ContextWithRequestId(ctx, requestId)
+
+	if _, ok := golog.SpanContextFromContext(ctx); !ok {
+		if traceparent := firstValue(incoming, traceparentMetadataKey); traceparent != "" {
+			ctx = golog.ContextWithTraceparent(ctx, traceparent)
+		}
+	}
+
+	fields := []golog.Field{
+		golog.F("request_id", requestId),
+		golog.F("grpc.method", fullMethod),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields = append(fields, golog.F("peer.address", p.Addr.String()))
+	}
+
+	requestLogger := logger.With(fields...)
+	requestLogger = requestLogger.WithContext(ctx)
+	return golog.ContextWithLogger(ctx, requestLogger), requestLogger
+}
+
+// UnaryClientInterceptor logs each outgoing unary RPC and propagates the request id of the context
+func UnaryClientInterceptor(logger golog.Logger, options Options) grpc.UnaryClientInterceptor {
+	options = options.withDefaults()
+
+	return func(ctx context.Context, method string, request, reply interface{}, conn *grpc.ClientConn, invoker grpc.UnaryInvoker, callOptions ...grpc.CallOption) error {
+		start := time.Now()
+		ctx, requestLogger := newClientContext(ctx, logger, options, method)
+
+		p := &peer.Peer{}
+		err := invoker(ctx, method, request, reply, conn, append(callOptions, grpc.Peer(p))...)
+
+		if p.Addr != nil {
+			requestLogger = requestLogger.With(golog.F("peer.address", p.Addr.String()))
+		}
+		logFinished(requestLogger, options, "finished client unary call", start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor logs each outgoing streaming RPC with its message counts
+// when the stream ends, and propagates the request id of the context.
+// As grpc requires to release a stream, callers must receive until an error or cancel the context of the call.
+// A stream abandoned with a context which is never cancelled is not logged, and its watching goroutine is not ended.
+func StreamClientInterceptor(logger golog.Logger, options Options) grpc.StreamClientInterceptor {
+	options = options.withDefaults()
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, conn *grpc.ClientConn, method string, streamer grpc.Streamer, callOptions ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx, requestLogger := newClientContext(ctx, logger, options, method)
+
+		stream, err := streamer(ctx, desc, conn, method, callOptions...)
+		if err != nil {
+			logFinished(requestLogger, options, "finished client streaming call", start, err)
+			return nil, err
+		}
+
+		clientStream := &countingClientStream{
+			ClientStream:  stream,
+			serverStreams: desc.ServerStreams,
+			finish: func(received, sent int, err error) {
+				finishedLogger := requestLogger.With(
+					golog.F("grpc.recv_count", received),
+					golog.F("grpc.sent_count", sent))
+				logFinished(finishedLogger, options, "finished client streaming call", start, err)
+			},
+			once:     new(sync.Once),
+			finished: make(chan struct{}),
+		}
+		go clientStream.watch(ctx)
+		return clientStream, nil
+	}
+}
+
+// newClientContext returns the context with the outgoing request id and the logger of the call
+func newClientContext(ctx context.Context, logger golog.Logger, options Options, method string) (context.Context, golog.Logger) {
+	if requestLogger, ok := golog.LoggerFromContext(ctx); ok {
+		logger = requestLogger
+	} else {
+		logger = logger.WithContext(ctx)
+	}
+
+	if requestId, ok := golog.RequestIdFromContext(ctx); ok {
+		outgoing, _ := metadata.FromOutgoingContext(ctx)
+		if len(outgoing.Get(options.RequestIdMetadataKey)) == 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, options.RequestIdMetadataKey, requestId)
+		}
+	}
+
+	return ctx, logger.With(golog.F("grpc.method", method))
+}
+
+// logFinished logs the code and the duration of the RPC at the level of its code
+func logFinished(logger golog.Logger, options Options, message string, start time.Time, err error) {
+	code := status.Code(err)
+	logger = logger.With(
+		golog.F("grpc.code", code.String()),
+		golog.F("grpc.duration_ms", float64(time.Since(start).Microseconds())/1000))
+	if err != nil {
+		logger = logger.With(golog.Err(err))
+	}
+
+	switch options.CodeToLevel(code) {
+	case golog.LogLevel_TRACE:
+		logger.Trace(message)
+	case golog.LogLevel_DEBUG:
+		logger.Debug(message)
+	case golog.LogLevel_INFO:
+		logger.Info(message)
+	case golog.LogLevel_WARN:
+		logger.Warn(message)
+	default:
+		// FATAL would exit the process
+		logger.Error(message)
+	}
+}
+
+// firstValue
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// countingServerStream counts messages and carries the context with the request scoped logger
+type countingServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	received int
+	sent     int
+}
+
+// Context implements grpc.ServerStream
+func (stream *countingServerStream) Context() context.Context {
+	return stream.ctx
+}
+
+// RecvMsg implements grpc.ServerStream
+func (stream *countingServerStream) RecvMsg(message interface{}) error {
+	err := stream.ServerStream.RecvMsg(message)
+	if err == nil {
+		stream.received++
+	}
+	return err
+}
+
+// SendMsg implements grpc.ServerStream
+func (stream *countingServerStream) SendMsg(message interface{}) error {
+	err := stream.ServerStream.SendMsg(message)
+	if err == nil {
+		stream.sent++
+	}
+	return err
+}
+
+// countingClientStream counts messages and calls finish once the stream ends.
+// The stream ends with the error or EOF of RecvMsg, with the single response of a stream
+// the server does not stream, with an error of Header or CloseSend, or when the context is done.
+type countingClientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	finish        func(received, sent int, err error)
+	once          *sync.Once
+	finished      chan struct{}
+	mu            sync.Mutex
+	received      int
+	sent          int
+}
+
+// RecvMsg implements grpc.ClientStream
+func (stream *countingClientStream) RecvMsg(message interface{}) error {
+	err := stream.ClientStream.RecvMsg(message)
+
+	stream.mu.Lock()
+	if err == nil {
+		stream.received++
+	}
+	stream.mu.Unlock()
+
+	if err == io.EOF {
+		stream.end(nil)
+	} else if err != nil || !stream.serverStreams {
+		stream.end(err)
+	}
+	return err
+}
+
+// SendMsg implements grpc.ClientStream
+func (stream *countingClientStream) SendMsg(message interface{}) error {
+	err := stream.ClientStream.SendMsg(message)
+	if err == nil {
+		stream.mu.Lock()
+		stream.sent++
+		stream.mu.Unlock()
+	}
+	return err
+}
+
+// Header implements grpc.ClientStream
+func (stream *countingClientStream) Header() (metadata.MD, error) {
+	md, err := stream.ClientStream.Header()
+	if err != nil {
+		stream.end(err)
+	}
+	return md, err
+}
+
+// CloseSend implements grpc.ClientStream
+func (stream *countingClientStream) CloseSend() error {
+	err := stream.ClientStream.CloseSend()
+	if err != nil {
+		stream.end(err)
+	}
+	return err
+}
+
+// watch ends the stream when ctx is done before the stream ends, e.g. the caller abandons it.
+// It returns when the stream ends, so it does not leak as long as the caller receives until an error or cancels ctx.
+func (stream *countingClientStream) watch(ctx context.Context) {
+	if ctx.Done() == nil {
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+		stream.end(status.FromContextError(ctx.Err()).Err())
+	case <-stream.finished:
+	}
+}
+
+// end calls finish with the message counts once
+func (stream *countingClientStream) end(err error) {
+	stream.once.Do(func() {
+		stream.mu.Lock()
+		received, sent := stream.received, stream.sent
+		stream.mu.Unlock()
+
+		close(stream.finished)
+		stream.finish(received, sent, err)
+	})
+}
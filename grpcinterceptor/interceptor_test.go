@@ -0,0 +1,360 @@
+package grpcinterceptor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ajainc/golog"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// bufferAppender writes one event per line like golog.ByteBufferAppender,
+// and is safe for the server and the client goroutines
+type bufferAppender struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+// Write implements io.Writer
+func (appender *bufferAppender) Write(data []byte) (int, error) {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	appender.buffer.Write(data)
+	appender.buffer.WriteString("\n")
+	return len(data), nil
+}
+
+// Close implements io.Closer
+func (appender *bufferAppender) Close() error {
+	return nil
+}
+
+// String
+func (appender *bufferAppender) String() string {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	return appender.buffer.String()
+}
+
+// echoServiceDesc describes a service with an unary method, and bidirectional, client and server streaming methods
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Unary",
+		Handler: func(server interface{}, ctx context.Context, decode func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			request := &wrapperspb.StringValue{}
+			if err := decode(request); err != nil {
+				return nil, err
+			}
+			info := &grpc.UnaryServerInfo{Server: server, FullMethod: "/test.Echo/Unary"}
+			return interceptor(ctx, request, info, func(ctx context.Context, request interface{}) (interface{}, error) {
+				logger, ok := golog.LoggerFromContext(ctx)
+				if !ok {
+					return nil, status.Error(codes.FailedPrecondition, "no logger")
+				}
+				logger.Info("handler")
+
+				value := request.(*wrapperspb.StringValue).Value
+				if value == "internal" {
+					return nil, status.Error(codes.Internal, "broken")
+				}
+				return wrapperspb.String(value), nil
+			})
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Stream",
+		ServerStreams: true,
+		ClientStreams: true,
+		Handler: func(server interface{}, stream grpc.ServerStream) error {
+			for {
+				request := &wrapperspb.StringValue{}
+				if err := stream.RecvMsg(request); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				if err := stream.SendMsg(request); err != nil {
+					return err
+				}
+				if err := stream.SendMsg(request); err != nil {
+					return err
+				}
+			}
+		},
+	}, {
+		StreamName:    "Collect",
+		ClientStreams: true,
+		Handler: func(server interface{}, stream grpc.ServerStream) error {
+			var values []string
+			for {
+				request := &wrapperspb.StringValue{}
+				if err := stream.RecvMsg(request); err == io.EOF {
+					return stream.SendMsg(wrapperspb.String(strings.Join(values, ",")))
+				} else if err != nil {
+					return err
+				}
+				values = append(values, request.Value)
+			}
+		},
+	}, {
+		StreamName:    "Expand",
+		ServerStreams: true,
+		Handler: func(server interface{}, stream grpc.ServerStream) error {
+			request := &wrapperspb.StringValue{}
+			if err := stream.RecvMsg(request); err != nil {
+				return err
+			}
+			for _, value := range strings.Split(request.Value, ",") {
+				if err := stream.SendMsg(wrapperspb.String(value)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}},
+}
+
+// startEchoServer returns a client connection to an in-process server and the function stopping both
+func startEchoServer(t *testing.T, serverLogger golog.Logger, clientLogger golog.Logger) (*grpc.ClientConn, func()) {
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(serverLogger, NewDefaultOptions())),
+		grpc.StreamInterceptor(StreamServerInterceptor(serverLogger, NewDefaultOptions())))
+	server.RegisterService(&echoServiceDesc, struct{}{})
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(clientLogger, NewDefaultOptions())),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(clientLogger, NewDefaultOptions())))
+	assert.Nil(t, err)
+
+	return conn, func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+
+	// OK at INFO, propagates the request id
+	func() {
+		serverAppender := &bufferAppender{}
+		serverLogger := golog.NewLogger("server", golog.LogLevel_TRACE, serverAppender)
+		serverLogger.SetMetadataConfig(&golog.MetadataConfig{})
+		clientAppender := &bufferAppender{}
+		clientLogger := golog.NewLogger("client", golog.LogLevel_TRACE, clientAppender)
+		clientLogger.SetMetadataConfig(&golog.MetadataConfig{IsEnabledLogLevel: true})
+
+		conn, stop := startEchoServer(t, serverLogger, clientLogger)
+		defer stop()
+
+		ctx := golog.ContextWithRequestId(context.Background(), "abc")
+		reply := &wrapperspb.StringValue{}
+		err := conn.Invoke(ctx, "/test.Echo/Unary", wrapperspb.String("hello"), reply)
+		assert.Nil(t, err)
+		assert.Equal(t, "hello", reply.Value)
+
+		lines := strings.Split(serverAppender.String(), "\n")
+		assert.True(t, strings.HasPrefix(lines[0], "   () handler request_id=abc grpc.method=/test.Echo/Unary peer.address="))
+		assert.Contains(t, lines[1], "finished unary call request_id=abc grpc.method=/test.Echo/Unary")
+		assert.Contains(t, lines[1], "grpc.code=OK grpc.duration_ms=")
+
+		client := clientAppender.String()
+		assert.True(t, strings.HasPrefix(client, "[INFO]   () finished client unary call grpc.method=/test.Echo/Unary peer.address="))
+		assert.Contains(t, client, "grpc.code=OK")
+	}()
+
+	// Internal at ERROR, generates the request id
+	func() {
+		serverAppender := &bufferAppender{}
+		serverLogger := golog.NewLogger("server", golog.LogLevel_TRACE, serverAppender)
+		serverLogger.SetMetadataConfig(&golog.MetadataConfig{IsEnabledLogLevel: true})
+		clientAppender := &bufferAppender{}
+		clientLogger := golog.NewLogger("client", golog.LogLevel_TRACE, clientAppender)
+		clientLogger.SetMetadataConfig(&golog.MetadataConfig{IsEnabledLogLevel: true})
+
+		conn, stop := startEchoServer(t, serverLogger, clientLogger)
+		defer stop()
+
+		err := conn.Invoke(context.Background(), "/test.Echo/Unary", wrapperspb.String("internal"), &wrapperspb.StringValue{})
+		assert.Equal(t, codes.Internal, status.Code(err))
+
+		lines := strings.Split(serverAppender.String(), "\n")
+		assert.Regexp(t, `^\[INFO\]   \(\) handler request_id=[0-9a-f]{32} `, lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "[ERROR]   () finished unary call"))
+		assert.Contains(t, lines[1], "grpc.code=Internal")
+		assert.Contains(t, lines[1], `error="rpc error: code = Internal desc = broken"`)
+
+		assert.True(t, strings.HasPrefix(clientAppender.String(), "[ERROR]   () finished client unary call"))
+	}()
+
+	// the outgoing request id is taken from the context, the traceparent is propagated
+	func() {
+		serverAppender := &bufferAppender{}
+		serverLogger := golog.NewLogger("server", golog.LogLevel_TRACE, serverAppender)
+		serverLogger.SetMetadataConfig(&golog.MetadataConfig{})
+
+		conn, stop := startEchoServer(t, serverLogger, golog.NewLogger("client", golog.LogLevel_TRACE, &bufferAppender{}))
+		defer stop()
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(),
+			"x-request-id", "explicit",
+			"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		ctx = golog.ContextWithRequestId(ctx, "ignored")
+		err := conn.Invoke(ctx, "/test.Echo/Unary", wrapperspb.String("hello"), &wrapperspb.StringValue{})
+		assert.Nil(t, err)
+
+		assert.True(t, strings.HasPrefix(serverAppender.String(),
+			"   () handler trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01 request_id=explicit "))
+	}()
+}
+
+func TestStreamInterceptor(t *testing.T) {
+
+	serverAppender := &bufferAppender{}
+	serverLogger := golog.NewLogger("server", golog.LogLevel_TRACE, serverAppender)
+	serverLogger.SetMetadataConfig(&golog.MetadataConfig{IsEnabledLogLevel: true})
+	clientAppender := &bufferAppender{}
+	clientLogger := golog.NewLogger("client", golog.LogLevel_TRACE, clientAppender)
+	clientLogger.SetMetadataConfig(&golog.MetadataConfig{IsEnabledLogLevel: true})
+
+	conn, stop := startEchoServer(t, serverLogger, clientLogger)
+	defer stop()
+
+	ctx := golog.ContextWithRequestId(context.Background(), "abc")
+	stream, err := conn.NewStream(ctx, &echoServiceDesc.Streams[0], "/test.Echo/Stream")
+	assert.Nil(t, err)
+
+	for _, value := range []string{"a", "b", "c"} {
+		assert.Nil(t, stream.SendMsg(wrapperspb.String(value)))
+	}
+	assert.Nil(t, stream.CloseSend())
+
+	received := 0
+	for {
+		if err := stream.RecvMsg(&wrapperspb.StringValue{}); err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+		received++
+	}
+	assert.Equal(t, 6, received)
+
+	server := serverAppender.String()
+	assert.True(t, strings.HasPrefix(server, "[INFO]   () finished streaming call request_id=abc grpc.method=/test.Echo/Stream"))
+	assert.Contains(t, server, "grpc.recv_count=3 grpc.sent_count=6 grpc.code=OK")
+
+	client := clientAppender.String()
+	assert.True(t, strings.HasPrefix(client, "[INFO]   () finished client streaming call grpc.method=/test.Echo/Stream"))
+	assert.Contains(t, client, "grpc.recv_count=6 grpc.sent_count=3 grpc.code=OK")
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+
+	// client streaming ends with the response
+	func() {
+		clientAppender := &bufferAppender{}
+		clientLogger := golog.NewLogger("client", golog.LogLevel_TRACE, clientAppender)
+		clientLogger.SetMetadataConfig(&golog.MetadataConfig{})
+
+		conn, stop := startEchoServer(t, golog.NewLogger("server", golog.LogLevel_TRACE, &bufferAppender{}), clientLogger)
+		defer stop()
+
+		stream, err := conn.NewStream(context.Background(), &echoServiceDesc.Streams[1], "/test.Echo/Collect")
+		assert.Nil(t, err)
+		for _, value := range []string{"a", "b", "c"} {
+			assert.Nil(t, stream.SendMsg(wrapperspb.String(value)))
+		}
+		assert.Nil(t, stream.CloseSend())
+		reply := &wrapperspb.StringValue{}
+		assert.Nil(t, stream.RecvMsg(reply))
+		assert.Equal(t, "a,b,c", reply.Value)
+
+		client := clientAppender.String()
+		assert.True(t, strings.HasPrefix(client, "   () finished client streaming call grpc.method=/test.Echo/Collect"))
+		assert.Contains(t, client, "grpc.recv_count=1 grpc.sent_count=3 grpc.code=OK")
+		assert.Equal(t, 1, strings.Count(client, "\n"))
+	}()
+
+	// server streaming ends with EOF
+	func() {
+		clientAppender := &bufferAppender{}
+		clientLogger := golog.NewLogger("client", golog.LogLevel_TRACE, clientAppender)
+		clientLogger.SetMetadataConfig(&golog.MetadataConfig{})
+
+		conn, stop := startEchoServer(t, golog.NewLogger("server", golog.LogLevel_TRACE, &bufferAppender{}), clientLogger)
+		defer stop()
+
+		stream, err := conn.NewStream(context.Background(), &echoServiceDesc.Streams[2], "/test.Echo/Expand")
+		assert.Nil(t, err)
+		assert.Nil(t, stream.SendMsg(wrapperspb.String("a,b")))
+		assert.Nil(t, stream.CloseSend())
+
+		received := 0
+		for {
+			if err := stream.RecvMsg(&wrapperspb.StringValue{}); err != nil {
+				assert.Equal(t, io.EOF, err)
+				break
+			}
+			received++
+			// the stream is not finished by a message of a server stream
+			if received == 1 {
+				assert.Equal(t, "", clientAppender.String())
+			}
+		}
+		assert.Equal(t, 2, received)
+
+		client := clientAppender.String()
+		assert.True(t, strings.HasPrefix(client, "   () finished client streaming call grpc.method=/test.Echo/Expand"))
+		assert.Contains(t, client, "grpc.recv_count=2 grpc.sent_count=1 grpc.code=OK")
+	}()
+
+	// an abandoned stream ends when the context is cancelled
+	func() {
+		clientAppender := &bufferAppender{}
+		clientLogger := golog.NewLogger("client", golog.LogLevel_TRACE, clientAppender)
+		clientLogger.SetMetadataConfig(&golog.MetadataConfig{})
+
+		conn, stop := startEchoServer(t, golog.NewLogger("server", golog.LogLevel_TRACE, &bufferAppender{}), clientLogger)
+		defer stop()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := conn.NewStream(ctx, &echoServiceDesc.Streams[0], "/test.Echo/Stream")
+		assert.Nil(t, err)
+		assert.Nil(t, stream.SendMsg(wrapperspb.String("a")))
+		cancel()
+
+		assert.Eventually(t, func() bool {
+			return strings.Contains(clientAppender.String(), "grpc.recv_count=0 grpc.sent_count=1 grpc.code=Canceled")
+		}, 5*time.Second, 10*time.Millisecond)
+	}()
+}
+
+func TestDefaultCodeToLevel(t *testing.T) {
+	assert.Equal(t, golog.LogLevel_INFO, DefaultCodeToLevel(codes.OK))
+	assert.Equal(t, golog.LogLevel_WARN, DefaultCodeToLevel(codes.NotFound))
+	assert.Equal(t, golog.LogLevel_WARN, DefaultCodeToLevel(codes.InvalidArgument))
+	assert.Equal(t, golog.LogLevel_ERROR, DefaultCodeToLevel(codes.Internal))
+	assert.Equal(t, golog.LogLevel_ERROR, DefaultCodeToLevel(codes.Unavailable))
+	assert.Equal(t, golog.LogLevel_ERROR, DefaultCodeToLevel(codes.Unknown))
+}
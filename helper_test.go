@@ -0,0 +1,47 @@
+package golog
+
+import (
+	"bytes"
+	"sync"
+)
+
+// bufferAppender keeps the written data followed by a newline like ByteBufferAppender.
+// Tests read events back through it, as the sync.Pool of ByteBufferAppender may drop the data.
+type bufferAppender struct {
+	mu     *sync.Mutex
+	buffer bytes.Buffer
+	closed bool
+}
+
+// newBufferAppender
+func newBufferAppender() *bufferAppender {
+	return &bufferAppender{mu: new(sync.Mutex)}
+}
+
+func (appender *bufferAppender) Write(data []byte) (int, error) {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	appender.buffer.Write(data)
+	appender.buffer.WriteString("\n")
+	return len(data), nil
+}
+
+func (appender *bufferAppender) Close() error {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	appender.closed = true
+	return nil
+}
+
+// isClosed
+func (appender *bufferAppender) isClosed() bool {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	return appender.closed
+}
+
+func (appender *bufferAppender) String() string {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	return appender.buffer.String()
+}
@@ -0,0 +1,237 @@
+package golog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const defaultMaxLoggedBodySize = 4096
+
+// redactedValue replaces redacted query values
+const redactedValue = "REDACTED"
+
+// LoggingRoundTripperOptions
+type LoggingRoundTripperOptions struct {
+	// RequestIdHeader is set from RequestIdFromContext on outgoing requests. X-Request-Id is used if empty.
+	RequestIdHeader string
+
+	// AllowedQueryParams are logged as is, the values of other query parameters are redacted
+	AllowedQueryParams []string
+
+	// MaxBodySize bounds the request and response bodies logged at TRACE
+	MaxBodySize int
+
+	// MaxRetries retries idempotent requests on network errors and on 429, 502, 503 and 504
+	MaxRetries int
+}
+
+// NewDefaultLoggingRoundTripperOptions
+func NewDefaultLoggingRoundTripperOptions() LoggingRoundTripperOptions {
+	return LoggingRoundTripperOptions{
+		RequestIdHeader: defaultRequestIdHeader,
+		MaxBodySize:     defaultMaxLoggedBodySize,
+	}
+}
+
+// LoggingRoundTripper is a http.RoundTripper which logs every outbound request and response.
+// The request scoped logger of the request context is used if present, see LoggerFromContext.
+type LoggingRoundTripper struct {
+	next    http.RoundTripper
+	logger  Logger
+	options LoggingRoundTripperOptions
+}
+
+// NewLoggingRoundTripper returns new LoggingRoundTripper.
+// http.DefaultTransport is used if next is nil.
+func NewLoggingRoundTripper(logger Logger, next http.RoundTripper, options LoggingRoundTripperOptions) *LoggingRoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if options.RequestIdHeader == "" {
+		options.RequestIdHeader = defaultRequestIdHeader
+	}
+
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = defaultMaxLoggedBodySize
+	}
+
+	return &LoggingRoundTripper{
+		next:    next,
+		logger:  logger,
+		options: options,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (tripper *LoggingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+	logger, ok := LoggerFromContext(ctx)
+	if !ok {
+		logger = tripper.logger.WithContext(ctx)
+	}
+
+	// a RoundTripper must not modify the request
+	request = request.Clone(ctx)
+	if requestId, ok := RequestIdFromContext(ctx); ok && request.Header.Get(tripper.options.RequestIdHeader) == "" {
+		request.Header.Set(tripper.options.RequestIdHeader, requestId)
+	}
+
+	redactedUrl := redactQuery(request.URL, tripper.options.AllowedQueryParams)
+	tracing := logger.isLevelEnabled(LogLevel_TRACE)
+	if tracing && request.Body != nil && request.Body != http.NoBody {
+		body, err := tripper.peekRequestBody(request)
+		if err != nil {
+			return nil, err
+		}
+		logger.Tracef("request body %s %s : %s", request.Method, redactedUrl, body)
+	}
+
+	retryable := tripper.options.MaxRetries > 0 && isIdempotent(request) &&
+		(request.Body == nil || request.Body == http.NoBody || request.GetBody != nil)
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		response, err := tripper.next.RoundTrip(request)
+		latency := time.Since(start)
+
+		attemptLogger := logger.With(
+			F("http.method", request.Method),
+			F("url", redactedUrl),
+			F("latency_ms", float64(latency.Microseconds())/1000),
+			F("attempt", attempt))
+
+		if err != nil {
+			attemptLogger.Errorf("%s %s failed : %s", request.Method, redactedUrl, err.Error())
+		} else {
+			statusLogger := attemptLogger.With(F("status", response.StatusCode))
+			message := fmt.Sprintf("%s %s %d", request.Method, redactedUrl, response.StatusCode)
+			switch defaultAccessLogLevel(response.StatusCode) {
+			case LogLevel_ERROR:
+				statusLogger.Error(message)
+			case LogLevel_WARN:
+				statusLogger.Warn(message)
+			default:
+				statusLogger.Info(message)
+			}
+		}
+
+		canRetry := retryable && attempt <= tripper.options.MaxRetries &&
+			(err != nil || isRetryableStatus(response.StatusCode))
+		if !canRetry {
+			if err == nil && tracing {
+				tripper.traceResponseBody(logger, request, redactedUrl, response)
+			}
+			return response, err
+		}
+
+		delay := retryBackoff(attempt - 1)
+		if err == nil {
+			if retryAfter, ok := retryAfterDelay(response.Header.Get("Retry-After"), time.Now()); ok {
+				delay = retryAfter
+			}
+			io.Copy(io.Discard, io.LimitReader(response.Body, int64(tripper.options.MaxBodySize)))
+			response.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			request.Body = body
+		}
+	}
+}
+
+// peekRequestBody returns the head of the request body and restores the body
+func (tripper *LoggingRoundTripper) peekRequestBody(request *http.Request) (string, error) {
+	head, err := io.ReadAll(io.LimitReader(request.Body, int64(tripper.options.MaxBodySize)))
+	if err != nil {
+		return "", err
+	}
+	request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), request.Body), request.Body}
+	return string(head), nil
+}
+
+// traceResponseBody logs the head of the response body and restores the body
+func (tripper *LoggingRoundTripper) traceResponseBody(logger Logger, request *http.Request, redactedUrl string, response *http.Response) {
+	head, err := io.ReadAll(io.LimitReader(response.Body, int64(tripper.options.MaxBodySize)))
+	response.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), errorReader{err}), response.Body}
+	logger.Tracef("response body %s %s : %s", request.Method, redactedUrl, string(head))
+}
+
+// errorReader returns err once the head of a body is read, io.EOF if err is nil
+type errorReader struct {
+	err error
+}
+
+// Read implements io.Reader
+func (reader errorReader) Read(p []byte) (int, error) {
+	if reader.err == nil {
+		return 0, io.EOF
+	}
+	return 0, reader.err
+}
+
+// isIdempotent
+func isIdempotent(request *http.Request) bool {
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// redactQuery returns the url without user info whose query values are redacted, except allowed parameters
+func redactQuery(u *url.URL, allowed []string) string {
+	redacted := *u
+	redacted.User = nil
+
+	if redacted.RawQuery != "" {
+		query := redacted.Query()
+		for key, values := range query {
+			if contains(allowed, key) {
+				continue
+			}
+			for i := range values {
+				values[i] = redactedValue
+			}
+		}
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.String()
+}
+
+// contains
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isLevelEnabled reports whether the logger has appenders for the level
+func (logger *Logger) isLevelEnabled(level LogLevel) bool {
+	appenders, ok := logger.levelAppender[level]
+	return ok && len(appenders) > 0
+}
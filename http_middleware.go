@@ -0,0 +1,298 @@
+package golog
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccessLogFormat
+type AccessLogFormat string
+
+const AccessLogFormat_COMBINED AccessLogFormat = "combined"
+const AccessLogFormat_JSON AccessLogFormat = "json"
+
+const defaultRequestIdHeader = "X-Request-Id"
+
+// maxRequestIdLength bounds request ids accepted from clients
+const maxRequestIdLength = 128
+
+// HTTPMiddlewareOptions
+type HTTPMiddlewareOptions struct {
+	// RequestIdHeader is read to propagate the request id of the client and set on the response.
+	// X-Request-Id is used if empty.
+	RequestIdHeader string
+
+	// NewRequestId generates the request id when the client sends none. NewRequestId is used if nil.
+	NewRequestId func() string
+
+	// AccessLogFormat is combined or json
+	AccessLogFormat AccessLogFormat
+
+	// AccessLogLevel returns the level of the access event.
+	// If nil, 5xx are logged at ERROR, 4xx at WARN and others at INFO.
+	AccessLogLevel func(status int) LogLevel
+
+	// DisableAccessLog disables the access event, the request scoped logger is still provided
+	DisableAccessLog bool
+}
+
+// NewDefaultHTTPMiddlewareOptions
+func NewDefaultHTTPMiddlewareOptions() HTTPMiddlewareOptions {
+	return HTTPMiddlewareOptions{
+		RequestIdHeader: defaultRequestIdHeader,
+		NewRequestId:    NewRequestId,
+		AccessLogFormat: AccessLogFormat_COMBINED,
+	}
+}
+
+// HTTPMiddleware returns net/http middleware which
+//   - propagates the request id from the request header or generates one,
+//   - puts a request scoped logger with the request_id field and the span of the
+//     traceparent header into the request context, see LoggerFromContext,
+//   - emits one access event per request.
+func HTTPMiddleware(logger Logger, options HTTPMiddlewareOptions) func(http.Handler) http.Handler {
+	if options.RequestIdHeader == "" {
+		options.RequestIdHeader = defaultRequestIdHeader
+	}
+
+	if options.NewRequestId == nil {
+		options.NewRequestId = NewRequestId
+	}
+
+	if options.AccessLogFormat == "" {
+		options.AccessLogFormat = AccessLogFormat_COMBINED
+	}
+
+	if options.AccessLogLevel == nil {
+		options.AccessLogLevel = defaultAccessLogLevel
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestId := r.Header.Get(options.RequestIdHeader)
+			if !isValidRequestId(requestId) {
+				requestId = options.NewRequestId()
+			}
+			w.Header().Set(options.RequestIdHeader, requestId)
+
+			ctx := ContextWithRequestId(r.Context(), requestId)
+			if _, ok := SpanContextFromContext(ctx); !ok {
+				if traceparent := r.Header.Get("traceparent"); traceparent != "" {
+					ctx = ContextWithTraceparent(ctx, traceparent)
+				}
+			}
+
+			requestLogger := logger.With(F("request_id", requestId))
+			requestLogger = requestLogger.WithContext(ctx)
+			ctx = ContextWithLogger(ctx, requestLogger)
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			defer func() {
+				// a panicking handler is logged with status 500 before the panic goes on to net/http
+				recovered := recover()
+				if !options.DisableAccessLog {
+					status := recorder.status()
+					if recovered != nil {
+						status = http.StatusInternalServerError
+					}
+					logAccess(requestLogger, options, r, recorder, start, status)
+				}
+				if recovered != nil {
+					panic(recovered)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+		})
+	}
+}
+
+// logAccess emits the access event of the request
+func logAccess(requestLogger Logger, options HTTPMiddlewareOptions, r *http.Request, recorder *responseRecorder, start time.Time, status int) {
+	event := &AccessLogEvent{
+		Format:     options.AccessLogFormat,
+		Time:       start,
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.Method,
+		Path:       r.URL.RequestURI(),
+		Proto:      r.Proto,
+		Status:     status,
+		Bytes:      recorder.bytes,
+		Latency:    time.Since(start),
+		Referer:    r.Referer(),
+		UserAgent:  r.UserAgent(),
+		User:       "-",
+	}
+	if r.URL.User != nil {
+		event.User = r.URL.User.Username()
+	} else if user, _, ok := r.BasicAuth(); ok && user != "" {
+		event.User = user
+	}
+
+	switch options.AccessLogLevel(event.Status) {
+	case LogLevel_TRACE:
+		requestLogger.STrace(event)
+	case LogLevel_DEBUG:
+		requestLogger.SDebug(event)
+	case LogLevel_WARN:
+		requestLogger.SWarn(event)
+	case LogLevel_ERROR, LogLevel_FATAL:
+		requestLogger.SError(event)
+	default:
+		requestLogger.SInfo(event)
+	}
+}
+
+// defaultAccessLogLevel
+func defaultAccessLogLevel(status int) LogLevel {
+	switch {
+	case status >= 500:
+		return LogLevel_ERROR
+	case status >= 400:
+		return LogLevel_WARN
+	default:
+		return LogLevel_INFO
+	}
+}
+
+// isValidRequestId accepts printable ascii ids of a reasonable length
+func isValidRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+	for i := 0; i < len(requestId); i++ {
+		if requestId[i] <= ' ' || requestId[i] > '~' || requestId[i] == '"' {
+			return false
+		}
+	}
+	return true
+}
+
+// AccessLogEvent is the event emitted by HTTPMiddleware for each request
+type AccessLogEvent struct {
+	Format     AccessLogFormat `json:"-"`
+	Time       time.Time       `json:"-"`
+	RemoteAddr string          `json:"remoteAddr"`
+	User       string          `json:"-"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	Proto      string          `json:"proto"`
+	Status     int             `json:"status"`
+	Bytes      int64           `json:"bytes"`
+	Latency    time.Duration   `json:"-"`
+	Referer    string          `json:"referer,omitempty"`
+	UserAgent  string          `json:"userAgent,omitempty"`
+}
+
+// Encode implements LogEvent.Encode
+// The combined format is the Apache Combined Log Format followed by the latency in microseconds.
+// The combined line is written as is, without the metadata.
+func (event *AccessLogEvent) Encode(metadata *LogEventMetadata) []byte {
+	if event.Format == AccessLogFormat_JSON {
+		return (&JsonLogEvent{event: struct {
+			*AccessLogEvent
+			LatencyMs float64 `json:"latencyMs"`
+		}{
+			AccessLogEvent: event,
+			LatencyMs:      float64(event.Latency.Microseconds()) / 1000,
+		}}).Encode(metadata)
+	}
+
+	return []byte(event.combined())
+}
+
+// combined formats the event as
+// host ident user [time] "request" status bytes "referer" "user agent" latency
+func (event *AccessLogEvent) combined() string {
+	host, _, err := net.SplitHostPort(event.RemoteAddr)
+	if err != nil {
+		host = event.RemoteAddr
+	}
+
+	var builder strings.Builder
+	builder.WriteString(orDash(host))
+	builder.WriteString(" - ")
+	builder.WriteString(orDash(event.User))
+	builder.WriteString(" [")
+	builder.WriteString(event.Time.Format("02/Jan/2006:15:04:05 -0700"))
+	builder.WriteString("] \"")
+	builder.WriteString(event.Method + " " + event.Path + " " + event.Proto)
+	builder.WriteString("\" ")
+	builder.WriteString(strconv.Itoa(event.Status))
+	builder.WriteString(" ")
+	if event.Bytes > 0 {
+		builder.WriteString(strconv.FormatInt(event.Bytes, 10))
+	} else {
+		builder.WriteString("-")
+	}
+	builder.WriteString(" " + strconv.Quote(orDash(event.Referer)))
+	builder.WriteString(" " + strconv.Quote(orDash(event.UserAgent)))
+	builder.WriteString(" ")
+	builder.WriteString(strconv.FormatInt(event.Latency.Microseconds(), 10))
+	return builder.String()
+}
+
+// orDash
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// responseRecorder records the status and the size of the response
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	bytes      int64
+}
+
+// WriteHeader implements http.ResponseWriter
+// Informational 1xx codes are not the status of the response.
+func (recorder *responseRecorder) WriteHeader(statusCode int) {
+	if recorder.statusCode == 0 && statusCode >= 200 {
+		recorder.statusCode = statusCode
+	}
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements http.ResponseWriter
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = http.StatusOK
+	}
+	n, err := recorder.ResponseWriter.Write(data)
+	recorder.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher
+func (recorder *responseRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker
+func (recorder *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(recorder.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the original writer for http.ResponseController
+func (recorder *responseRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// status
+func (recorder *responseRecorder) status() int {
+	if recorder.statusCode == 0 {
+		return http.StatusOK
+	}
+	return recorder.statusCode
+}
@@ -0,0 +1,117 @@
+package golog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger, ok := LoggerFromContext(r.Context())
+		assert.True(t, ok)
+		logger.Info("handler")
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+
+	// combined, propagates the request id
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("accessLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		server := HTTPMiddleware(logger, NewDefaultHTTPMiddlewareOptions())(handler)
+
+		request := httptest.NewRequest(http.MethodPost, "/path?q=1", nil)
+		request.Header.Set("X-Request-Id", "abc")
+		request.Header.Set("User-Agent", "test-agent")
+		request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, "abc", response.Header().Get("X-Request-Id"))
+		lines := strings.Split(appender.String(), "\n")
+		require.GreaterOrEqual(t, len(lines), 2)
+		assert.Equal(t, "   () handler trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01 request_id=abc", lines[0])
+		// the combined line is written without the metadata
+		pattern := `^192\.0\.2\.1 - - \[[^\]]+\] "POST /path\?q=1 HTTP/1\.1" 201 5 "-" "test-agent" \d+$`
+		assert.Regexp(t, regexp.MustCompile(pattern), lines[1])
+	}()
+
+	// json, generates the request id
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("accessLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		options := NewDefaultHTTPMiddlewareOptions()
+		options.AccessLogFormat = AccessLogFormat_JSON
+		options.NewRequestId = func() string {
+			return "generated"
+		}
+		server := HTTPMiddleware(logger, options)(handler)
+
+		response := httptest.NewRecorder()
+		server.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, "generated", response.Header().Get("X-Request-Id"))
+
+		lines := strings.Split(appender.String(), "\n")
+		require.GreaterOrEqual(t, len(lines), 2)
+		var access struct {
+			EventData struct {
+				RemoteAddr string  `json:"remoteAddr"`
+				Method     string  `json:"method"`
+				Path       string  `json:"path"`
+				Status     int     `json:"status"`
+				Bytes      int64   `json:"bytes"`
+				LatencyMs  float64 `json:"latencyMs"`
+			}
+			Fields map[string]string `json:"fields"`
+		}
+		assert.Nil(t, json.Unmarshal([]byte(lines[1]), &access))
+		assert.Equal(t, "192.0.2.1:1234", access.EventData.RemoteAddr)
+		assert.Equal(t, "GET", access.EventData.Method)
+		assert.Equal(t, "/", access.EventData.Path)
+		assert.Equal(t, http.StatusCreated, access.EventData.Status)
+		assert.Equal(t, int64(5), access.EventData.Bytes)
+		assert.Equal(t, "generated", access.Fields["request_id"])
+	}()
+	// a panicking handler is logged as 500 and panics again
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("accessLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		server := HTTPMiddleware(logger, NewDefaultHTTPMiddlewareOptions())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("handler is broken")
+		}))
+
+		assert.PanicsWithValue(t, "handler is broken", func() {
+			server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+		})
+		assert.Contains(t, appender.String(), `"GET /panic HTTP/1.1" 500 - `)
+	}()
+}
+
+func TestResponseRecorder_WriteHeader(t *testing.T) {
+
+	// informational codes are not the status of the response
+	recorder := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+	recorder.WriteHeader(http.StatusEarlyHints)
+	assert.Equal(t, http.StatusOK, recorder.status())
+	recorder.WriteHeader(http.StatusCreated)
+	assert.Equal(t, http.StatusCreated, recorder.status())
+}
+
+func TestDefaultAccessLogLevel(t *testing.T) {
+
+	assert.Equal(t, LogLevel_INFO, defaultAccessLogLevel(200))
+	assert.Equal(t, LogLevel_WARN, defaultAccessLogLevel(404))
+	assert.Equal(t, LogLevel_ERROR, defaultAccessLogLevel(503))
+}
@@ -0,0 +1,149 @@
+package golog
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultMaxRetries = 5
+
+const minRetryBackoff = time.Millisecond * 100
+
+const maxRetryBackoff = time.Second * 30
+
+// maxRetryAfter bounds the delay requested by Retry-After
+const maxRetryAfter = time.Minute * 5
+
+// maxErrorBodySize limits the response body quoted in errors
+const maxErrorBodySize = 512
+
+// httpStatusError is returned for a response which is not successful
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements error
+func (err *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d : %s", err.StatusCode, err.Body)
+}
+
+// isRetryableStatus reports whether the request may succeed later
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or as an http date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// retryAfterDelay returns the delay requested by a Retry-After header, bounded by maxRetryAfter
+func retryAfterDelay(value string, now time.Time) (time.Duration, bool) {
+	delay, ok := parseRetryAfter(value, now)
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay, ok
+}
+
+// retryBackoff returns an exponential delay with full jitter for the attempt, starting at 0
+func retryBackoff(attempt int) time.Duration {
+	return jitteredBackoff(attempt, minRetryBackoff, maxRetryBackoff)
+}
+
+// jitteredBackoff returns a delay doubling from min up to max for the attempt, starting at 0.
+// The delay is randomized between half and the whole of the backoff.
+func jitteredBackoff(attempt int, min time.Duration, max time.Duration) time.Duration {
+	backoff := max
+	if attempt < 16 {
+		backoff = min << uint(attempt)
+		if backoff > max || backoff <= 0 {
+			backoff = max
+		}
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// waitRetry waits for the delay before a retry.
+// It returns false without waiting the delay out once cancel is closed, e.g. when the appender is closing.
+func waitRetry(delay time.Duration, cancel <-chan struct{}) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+// doWithRetry sends the request built by newRequest and returns the body of a successful response.
+// Network errors and retryable statuses are retried up to maxRetries times,
+// honouring Retry-After when the server sends it. Retries stop once cancel is closed.
+func doWithRetry(client *http.Client, maxRetries int, cancel <-chan struct{}, newRequest func() (*http.Request, error)) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		request, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		delay := retryBackoff(attempt)
+		response, err := client.Do(request)
+		if err == nil {
+			body, readErr := io.ReadAll(response.Body)
+			response.Body.Close()
+
+			if response.StatusCode >= 200 && response.StatusCode < 300 {
+				return body, readErr
+			}
+
+			if len(body) > maxErrorBodySize {
+				body = body[:maxErrorBodySize]
+			}
+			err = &httpStatusError{StatusCode: response.StatusCode, Body: string(body)}
+			if !isRetryableStatus(response.StatusCode) {
+				return nil, err
+			}
+
+			if retryAfter, ok := retryAfterDelay(response.Header.Get("Retry-After"), time.Now()); ok {
+				delay = retryAfter
+			}
+		}
+
+		lastErr = err
+		if attempt >= maxRetries || !waitRetry(delay, cancel) {
+			return nil, fmt.Errorf("giving up after %d attempts : %w", attempt+1, lastErr)
+		}
+	}
+}
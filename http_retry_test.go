@@ -0,0 +1,128 @@
+package golog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		input    string
+		expected time.Duration
+		ok       bool
+	}{
+		{input: "", expected: 0, ok: false},
+		{input: "3", expected: 3 * time.Second, ok: true},
+		{input: "-1", expected: 0, ok: false},
+		{input: "Sun, 18 Oct 2026 00:00:10 GMT", expected: 10 * time.Second, ok: true},
+		{input: "Sat, 17 Oct 2026 00:00:00 GMT", expected: 0, ok: true},
+		{input: "soon", expected: 0, ok: false},
+	}
+
+	for _, c := range cases {
+		actual, ok := parseRetryAfter(c.input, now)
+		assert.Equal(t, c.ok, ok, c.input)
+		assert.Equal(t, c.expected, actual, c.input)
+	}
+	// the delay is bounded
+	delay, ok := retryAfterDelay("86400", now)
+	assert.True(t, ok)
+	assert.Equal(t, maxRetryAfter, delay)
+	delay, ok = retryAfterDelay("3", now)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, delay)
+}
+
+func TestDoWithRetry(t *testing.T) {
+
+	// retryable status
+	func() {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("ok"))
+		}))
+		defer server.Close()
+
+		body, err := doWithRetry(server.Client(), 5, nil, func() (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, server.URL, nil)
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, "ok", string(body))
+		assert.Equal(t, 3, attempts)
+	}()
+
+	// permanent error
+	func() {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("bad"))
+		}))
+		defer server.Close()
+
+		_, err := doWithRetry(server.Client(), 5, nil, func() (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, server.URL, nil)
+		})
+		assert.Equal(t, &httpStatusError{StatusCode: http.StatusBadRequest, Body: "bad"}, err)
+		assert.Equal(t, 1, attempts)
+	}()
+
+	// gives up
+	func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		_, err := doWithRetry(server.Client(), 1, nil, func() (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, server.URL, nil)
+		})
+		assert.NotNil(t, err)
+	}()
+	// cancelled while waiting for Retry-After
+	func() {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		cancel := make(chan struct{})
+		time.AfterFunc(50*time.Millisecond, func() { close(cancel) })
+		start := time.Now()
+		_, err := doWithRetry(server.Client(), 5, cancel, func() (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, server.URL, nil)
+		})
+		assert.NotNil(t, err)
+		assert.Equal(t, 1, attempts)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	}()
+}
+
+func TestJitteredBackoff(t *testing.T) {
+	for attempt := 0; attempt < 70; attempt++ {
+		backoff := time.Second * 10
+		if attempt < 7 {
+			backoff = time.Millisecond * 100 << uint(attempt)
+		}
+
+		delay := jitteredBackoff(attempt, time.Millisecond*100, time.Second*10)
+		assert.True(t, delay >= backoff/2 && delay <= backoff, attempt)
+	}
+}
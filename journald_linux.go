@@ -0,0 +1,91 @@
+//go:build linux
+
+package golog
+
+import (
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// memfd_create is not defined by the syscall package on every architecture
+var memfdCreateSyscalls = map[string]uintptr{
+	"386":      356,
+	"amd64":    319,
+	"arm":      385,
+	"arm64":    279,
+	"loong64":  279,
+	"riscv64":  279,
+	"ppc64":    360,
+	"ppc64le":  360,
+	"s390x":    350,
+	"mips64":   5314,
+	"mips64le": 5314,
+}
+
+const (
+	memfdCloexec       = 0x1
+	memfdAllowSealing  = 0x2
+	fcntlAddSeals      = 0x409
+	journaldEntrySeals = 0x1 | 0x2 | 0x4 | 0x8 // seal, shrink, grow and write
+)
+
+// isJournaldEntryTooLarge reports whether the entry does not fit in a datagram
+func isJournaldEntryTooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// sendJournaldEntryFd writes the entry to a sealed memfd and sends its descriptor.
+// An unlinked file in /dev/shm is used if memfd is not available, which journald also accepts.
+func sendJournaldEntryFd(conn *net.UnixConn, entry []byte) error {
+	file, sealable, err := createJournaldEntryFile()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(entry); err != nil {
+		return err
+	}
+	if sealable {
+		if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, file.Fd(), fcntlAddSeals, journaldEntrySeals); errno != 0 {
+			return errno
+		}
+	}
+
+	// WriteMsgUnix does not accept connected datagram sockets
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sendErr error
+	err = rawConn.Write(func(fd uintptr) bool {
+		sendErr = syscall.Sendmsg(int(fd), nil, syscall.UnixRights(int(file.Fd())), nil, 0)
+		return sendErr != syscall.EAGAIN
+	})
+	return errors.Join(err, sendErr)
+}
+
+// createJournaldEntryFile returns a memfd, or an unlinked file which can not be sealed
+func createJournaldEntryFile() (*os.File, bool, error) {
+	if trap, ok := memfdCreateSyscalls[runtime.GOARCH]; ok {
+		name := []byte("golog-journal\x00")
+		fd, _, errno := syscall.Syscall(trap, uintptr(unsafe.Pointer(&name[0])), memfdCloexec|memfdAllowSealing, 0)
+		if errno == 0 {
+			return os.NewFile(fd, "golog-journal"), true, nil
+		}
+	}
+
+	file, err := os.CreateTemp("/dev/shm", "golog-journal-")
+	if err != nil {
+		return nil, false, err
+	}
+	if err := os.Remove(file.Name()); err != nil {
+		file.Close()
+		return nil, false, err
+	}
+	return file, false, nil
+}
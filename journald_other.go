@@ -0,0 +1,18 @@
+//go:build !linux
+
+package golog
+
+import (
+	"errors"
+	"net"
+)
+
+// isJournaldEntryTooLarge reports whether the entry does not fit in a datagram
+func isJournaldEntryTooLarge(err error) bool {
+	return false
+}
+
+// sendJournaldEntryFd is supported on Linux only
+func sendJournaldEntryFd(conn *net.UnixConn, entry []byte) error {
+	return errors.New("passing journal entries by file descriptor is supported on linux only")
+}
@@ -0,0 +1,532 @@
+package golog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// kafka api keys and the versions used
+const (
+	kafkaApiKeyProduce  = 0
+	kafkaApiKeyMetadata = 3
+
+	kafkaProduceVersion  = 3
+	kafkaMetadataVersion = 4
+)
+
+// kafka record batch attributes
+const (
+	kafkaCodecNone   = 0
+	kafkaCodecGzip   = 1
+	kafkaCodecSnappy = 2
+	kafkaCodecLz4    = 3
+)
+
+// kafkaMaxResponseSize bounds the size of a response read from a broker
+const kafkaMaxResponseSize = 64 * 1024 * 1024
+
+// kafkaSnappyBlockSize is the size of the blocks of the xerial snappy framing
+const kafkaSnappyBlockSize = 32 * 1024
+
+var kafkaSnappyMagic = []byte{0x82, 'S', 'N', 'A', 'P', 'P', 'Y', 0}
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// kafkaError is an error code of a response
+type kafkaError int16
+
+// Error implements error
+func (err kafkaError) Error() string {
+	switch err {
+	case 3:
+		return "kafka error 3 : unknown topic or partition"
+	case 5:
+		return "kafka error 5 : leader not available"
+	case 6:
+		return "kafka error 6 : not leader or follower"
+	case 10:
+		return "kafka error 10 : message too large"
+	default:
+		return "kafka error " + strconv.Itoa(int(err))
+	}
+}
+
+// retryable reports whether the request may succeed after refreshing metadata
+func (err kafkaError) retryable() bool {
+	switch err {
+	case 3, 5, 6, 7, 13, 14, 15, 19, 20:
+		return true
+	default:
+		return false
+	}
+}
+
+// kafkaEncoder appends the primitive types of the kafka protocol
+type kafkaEncoder struct {
+	buf []byte
+}
+
+func (encoder *kafkaEncoder) int8(v int8) {
+	encoder.buf = append(encoder.buf, byte(v))
+}
+
+func (encoder *kafkaEncoder) int16(v int16) {
+	encoder.buf = binary.BigEndian.AppendUint16(encoder.buf, uint16(v))
+}
+
+func (encoder *kafkaEncoder) int32(v int32) {
+	encoder.buf = binary.BigEndian.AppendUint32(encoder.buf, uint32(v))
+}
+
+func (encoder *kafkaEncoder) int64(v int64) {
+	encoder.buf = binary.BigEndian.AppendUint64(encoder.buf, uint64(v))
+}
+
+func (encoder *kafkaEncoder) varint(v int64) {
+	encoder.buf = binary.AppendVarint(encoder.buf, v)
+}
+
+func (encoder *kafkaEncoder) string(s string) {
+	encoder.int16(int16(len(s)))
+	encoder.buf = append(encoder.buf, s...)
+}
+
+func (encoder *kafkaEncoder) nullableString(s *string) {
+	if s == nil {
+		encoder.int16(-1)
+		return
+	}
+	encoder.string(*s)
+}
+
+func (encoder *kafkaEncoder) bytes(data []byte) {
+	encoder.int32(int32(len(data)))
+	encoder.buf = append(encoder.buf, data...)
+}
+
+// varintBytes appends the data with a varint length, -1 for nil
+func (encoder *kafkaEncoder) varintBytes(data []byte) {
+	if data == nil {
+		encoder.varint(-1)
+		return
+	}
+	encoder.varint(int64(len(data)))
+	encoder.buf = append(encoder.buf, data...)
+}
+
+// kafkaDecoder reads the primitive types of the kafka protocol, the first error is kept
+type kafkaDecoder struct {
+	buf []byte
+	err error
+}
+
+func (decoder *kafkaDecoder) next(n int) []byte {
+	if decoder.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(decoder.buf) {
+		decoder.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	data := decoder.buf[:n]
+	decoder.buf = decoder.buf[n:]
+	return data
+}
+
+func (decoder *kafkaDecoder) int8() int8 {
+	if data := decoder.next(1); data != nil {
+		return int8(data[0])
+	}
+	return 0
+}
+
+func (decoder *kafkaDecoder) int16() int16 {
+	if data := decoder.next(2); data != nil {
+		return int16(binary.BigEndian.Uint16(data))
+	}
+	return 0
+}
+
+func (decoder *kafkaDecoder) int32() int32 {
+	if data := decoder.next(4); data != nil {
+		return int32(binary.BigEndian.Uint32(data))
+	}
+	return 0
+}
+
+func (decoder *kafkaDecoder) int64() int64 {
+	if data := decoder.next(8); data != nil {
+		return int64(binary.BigEndian.Uint64(data))
+	}
+	return 0
+}
+
+func (decoder *kafkaDecoder) varint() int64 {
+	if decoder.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(decoder.buf)
+	if n <= 0 {
+		decoder.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	decoder.buf = decoder.buf[n:]
+	return v
+}
+
+func (decoder *kafkaDecoder) string() string {
+	n := decoder.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(decoder.next(int(n)))
+}
+
+func (decoder *kafkaDecoder) bytes() []byte {
+	n := decoder.int32()
+	if n < 0 {
+		return nil
+	}
+	return decoder.next(int(n))
+}
+
+func (decoder *kafkaDecoder) varintBytes() []byte {
+	n := decoder.varint()
+	if n < 0 {
+		return nil
+	}
+	return decoder.next(int(n))
+}
+
+// arrayLength returns the length of an array, bounded by the remaining bytes
+func (decoder *kafkaDecoder) arrayLength() int {
+	n := int(decoder.int32())
+	if n > len(decoder.buf) {
+		decoder.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	return n
+}
+
+// kafkaHeader is a header of a record
+type kafkaHeader struct {
+	key   string
+	value []byte
+}
+
+// kafkaMessage is a record to produce
+type kafkaMessage struct {
+	key       []byte
+	value     []byte
+	headers   []kafkaHeader
+	timestamp time.Time
+}
+
+// encodeKafkaRecordBatch encodes messages as a record batch of magic 2 with the compression codec
+func encodeKafkaRecordBatch(messages []kafkaMessage, codec int16) ([]byte, error) {
+	firstTimestamp := messages[0].timestamp.UnixMilli()
+	maxTimestamp := firstTimestamp
+
+	records := &kafkaEncoder{}
+	for i, message := range messages {
+		timestamp := message.timestamp.UnixMilli()
+		if timestamp > maxTimestamp {
+			maxTimestamp = timestamp
+		}
+
+		record := &kafkaEncoder{}
+		record.int8(0)
+		record.varint(timestamp - firstTimestamp)
+		record.varint(int64(i))
+		record.varintBytes(message.key)
+		record.varintBytes(message.value)
+		record.varint(int64(len(message.headers)))
+		for _, header := range message.headers {
+			record.varintBytes([]byte(header.key))
+			record.varintBytes(header.value)
+		}
+
+		records.varint(int64(len(record.buf)))
+		records.buf = append(records.buf, record.buf...)
+	}
+
+	compressed, err := kafkaCompress(records.buf, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	// the crc covers the batch from the attributes to the end
+	body := &kafkaEncoder{}
+	body.int16(codec)
+	body.int32(int32(len(messages) - 1))
+	body.int64(firstTimestamp)
+	body.int64(maxTimestamp)
+	body.int64(-1) // producer id
+	body.int16(-1) // producer epoch
+	body.int32(-1) // base sequence
+	body.int32(int32(len(messages)))
+	body.buf = append(body.buf, compressed...)
+
+	batch := &kafkaEncoder{}
+	batch.int64(0)                        // base offset
+	batch.int32(int32(len(body.buf) + 9)) // batch length from the partition leader epoch
+	batch.int32(-1)                       // partition leader epoch
+	batch.int8(2)                         // magic
+	batch.int32(int32(crc32.Checksum(body.buf, crc32cTable)))
+	batch.buf = append(batch.buf, body.buf...)
+	return batch.buf, nil
+}
+
+// kafkaCompress compresses the records of a batch
+func kafkaCompress(data []byte, codec int16) ([]byte, error) {
+	switch codec {
+	case kafkaCodecGzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case kafkaCodecSnappy:
+		return kafkaSnappyEncode(data), nil
+	case kafkaCodecLz4:
+		return lz4EncodeFrame(data), nil
+	default:
+		return data, nil
+	}
+}
+
+// kafkaSnappyEncode compresses data in the xerial snappy framing used by the Java client
+func kafkaSnappyEncode(data []byte) []byte {
+	buf := append([]byte{}, kafkaSnappyMagic...)
+	buf = binary.BigEndian.AppendUint32(buf, 1) // version
+	buf = binary.BigEndian.AppendUint32(buf, 1) // compatible version
+	for len(data) > 0 {
+		n := len(data)
+		if n > kafkaSnappyBlockSize {
+			n = kafkaSnappyBlockSize
+		}
+		block := snappyEncode(data[:n])
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(block)))
+		buf = append(buf, block...)
+		data = data[n:]
+	}
+	return buf
+}
+
+// kafkaMurmur2 is the hash of the default partitioner of the Java client
+func kafkaMurmur2(data []byte) int32 {
+	const m uint32 = 0x5bd1e995
+	length := len(data)
+	h := uint32(0x9747b28c) ^ uint32(length)
+
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> 24
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// kafkaConn is a connection to a broker
+type kafkaConn struct {
+	conn          net.Conn
+	clientId      string
+	timeout       time.Duration
+	correlationId int32
+}
+
+// dialKafka
+func dialKafka(address string, clientId string, timeout time.Duration) (*kafkaConn, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaConn{conn: conn, clientId: clientId, timeout: timeout}, nil
+}
+
+// roundTrip sends the request and reads its response, without reading if expectResponse is false
+func (conn *kafkaConn) roundTrip(apiKey int16, apiVersion int16, body []byte, expectResponse bool) (*kafkaDecoder, error) {
+	conn.correlationId++
+
+	request := &kafkaEncoder{}
+	request.int32(0)
+	request.int16(apiKey)
+	request.int16(apiVersion)
+	request.int32(conn.correlationId)
+	request.string(conn.clientId)
+	request.buf = append(request.buf, body...)
+	binary.BigEndian.PutUint32(request.buf, uint32(len(request.buf)-4))
+
+	if err := conn.conn.SetDeadline(time.Now().Add(conn.timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.conn.Write(request.buf); err != nil {
+		return nil, err
+	}
+	if !expectResponse {
+		return nil, nil
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(conn.conn, size[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(size[:])
+	if length < 4 || length > kafkaMaxResponseSize {
+		return nil, fmt.Errorf("invalid response size %d", length)
+	}
+
+	response := make([]byte, length)
+	if _, err := io.ReadFull(conn.conn, response); err != nil {
+		return nil, err
+	}
+
+	decoder := &kafkaDecoder{buf: response}
+	if correlationId := decoder.int32(); correlationId != conn.correlationId {
+		return nil, errors.New("unexpected correlation id " + strconv.Itoa(int(correlationId)))
+	}
+	return decoder, nil
+}
+
+// close
+func (conn *kafkaConn) close() error {
+	return conn.conn.Close()
+}
+
+// kafkaTopicMetadata is the partitions of a topic and the addresses of their leaders
+type kafkaTopicMetadata struct {
+	partitions []int32
+	leaders    map[int32]string
+}
+
+// metadata requests the metadata of the topic
+func (conn *kafkaConn) metadata(topic string) (*kafkaTopicMetadata, error) {
+	request := &kafkaEncoder{}
+	request.int32(1)
+	request.string(topic)
+	request.int8(1) // allow auto topic creation
+
+	decoder, err := conn.roundTrip(kafkaApiKeyMetadata, kafkaMetadataVersion, request.buf, true)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder.int32() // throttle time
+	brokers := map[int32]string{}
+	for i, n := 0, decoder.arrayLength(); i < n; i++ {
+		nodeId := decoder.int32()
+		host := decoder.string()
+		port := decoder.int32()
+		decoder.string() // rack
+		brokers[nodeId] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	decoder.string() // cluster id
+	decoder.int32()  // controller id
+
+	metadata := &kafkaTopicMetadata{leaders: map[int32]string{}}
+	var topicErr error
+	for i, n := 0, decoder.arrayLength(); i < n; i++ {
+		errorCode := decoder.int16()
+		name := decoder.string()
+		decoder.int8() // is internal
+		for j, m := 0, decoder.arrayLength(); j < m; j++ {
+			decoder.int16() // partition error code
+			partition := decoder.int32()
+			leader := decoder.int32()
+			// replica and isr nodes
+			for array := 0; array < 2; array++ {
+				for k, l := 0, decoder.arrayLength(); k < l; k++ {
+					decoder.int32()
+				}
+			}
+			if name == topic {
+				metadata.partitions = append(metadata.partitions, partition)
+				if address, ok := brokers[leader]; ok {
+					metadata.leaders[partition] = address
+				}
+			}
+		}
+		if name == topic && errorCode != 0 {
+			topicErr = kafkaError(errorCode)
+		}
+	}
+
+	if decoder.err != nil {
+		return nil, decoder.err
+	}
+	if topicErr != nil {
+		return nil, topicErr
+	}
+	if len(metadata.partitions) == 0 {
+		return nil, kafkaError(3)
+	}
+	return metadata, nil
+}
+
+// produce sends the record batches of the partitions and returns the error of each partition.
+// The broker does not respond when acks is 0.
+func (conn *kafkaConn) produce(topic string, acks int16, timeout time.Duration, batches map[int32][]byte) (map[int32]error, error) {
+	request := &kafkaEncoder{}
+	request.nullableString(nil) // transactional id
+	request.int16(acks)
+	request.int32(int32(timeout / time.Millisecond))
+	request.int32(1)
+	request.string(topic)
+	request.int32(int32(len(batches)))
+	for partition, batch := range batches {
+		request.int32(partition)
+		request.bytes(batch)
+	}
+
+	decoder, err := conn.roundTrip(kafkaApiKeyProduce, kafkaProduceVersion, request.buf, acks != 0)
+	if err != nil || decoder == nil {
+		return nil, err
+	}
+
+	errs := map[int32]error{}
+	for i, n := 0, decoder.arrayLength(); i < n; i++ {
+		decoder.string() // topic
+		for j, m := 0, decoder.arrayLength(); j < m; j++ {
+			partition := decoder.int32()
+			if errorCode := decoder.int16(); errorCode != 0 {
+				errs[partition] = kafkaError(errorCode)
+			}
+			decoder.int64() // base offset
+			decoder.int64() // log append time
+		}
+	}
+	decoder.int32() // throttle time
+	return errs, decoder.err
+}
@@ -0,0 +1,132 @@
+package golog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeKafkaRecordBatch decodes a record batch of magic 2 and returns its codec and messages
+func decodeKafkaRecordBatch(data []byte) (int16, []kafkaMessage, error) {
+	decoder := &kafkaDecoder{buf: data}
+	decoder.int64() // base offset
+	if length := decoder.int32(); int(length) != len(decoder.buf) {
+		return 0, nil, errors.New("invalid batch length")
+	}
+	decoder.int32() // partition leader epoch
+	if magic := decoder.int8(); magic != 2 {
+		return 0, nil, errors.New("invalid magic")
+	}
+	crc := uint32(decoder.int32())
+	if crc32.Checksum(decoder.buf, crc32cTable) != crc {
+		return 0, nil, errors.New("invalid crc")
+	}
+
+	codec := decoder.int16() & 0x7
+	decoder.int32() // last offset delta
+	firstTimestamp := decoder.int64()
+	decoder.int64() // max timestamp
+	decoder.int64() // producer id
+	decoder.int16() // producer epoch
+	decoder.int32() // base sequence
+	count := int(decoder.int32())
+	if decoder.err != nil {
+		return 0, nil, decoder.err
+	}
+
+	records, err := kafkaDecompress(decoder.buf, codec)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	decoder = &kafkaDecoder{buf: records}
+	var messages []kafkaMessage
+	for i := 0; i < count; i++ {
+		record := &kafkaDecoder{buf: decoder.next(int(decoder.varint()))}
+		record.int8() // attributes
+		message := kafkaMessage{timestamp: time.UnixMilli(firstTimestamp + record.varint())}
+		record.varint() // offset delta
+		message.key = record.varintBytes()
+		message.value = record.varintBytes()
+		for j, n := 0, int(record.varint()); j < n; j++ {
+			message.headers = append(message.headers, kafkaHeader{key: string(record.varintBytes()), value: record.varintBytes()})
+		}
+		if record.err != nil {
+			return 0, nil, record.err
+		}
+		messages = append(messages, message)
+	}
+	return codec, messages, decoder.err
+}
+
+// kafkaDecompress
+func kafkaDecompress(data []byte, codec int16) ([]byte, error) {
+	switch codec {
+	case kafkaCodecGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(reader)
+	case kafkaCodecSnappy:
+		if !bytes.HasPrefix(data, kafkaSnappyMagic) {
+			return nil, errors.New("invalid snappy magic")
+		}
+		data = data[16:]
+		var dst []byte
+		for len(data) > 0 {
+			n := binary.BigEndian.Uint32(data)
+			block, err := snappyDecode(data[4 : 4+n])
+			if err != nil {
+				return nil, err
+			}
+			dst = append(dst, block...)
+			data = data[4+n:]
+		}
+		return dst, nil
+	case kafkaCodecLz4:
+		return lz4DecodeFrame(data)
+	default:
+		return data, nil
+	}
+}
+
+func TestEncodeKafkaRecordBatch(t *testing.T) {
+
+	now := time.UnixMilli(time.Now().UnixMilli())
+	messages := []kafkaMessage{
+		{key: []byte("key1"), value: []byte("value1"), timestamp: now, headers: []kafkaHeader{{key: "level", value: []byte("INFO")}}},
+		{value: []byte(strings.Repeat("value2 ", 10000)), timestamp: now.Add(-time.Second)},
+	}
+
+	for _, codec := range []int16{kafkaCodecNone, kafkaCodecGzip, kafkaCodecSnappy, kafkaCodecLz4} {
+		batch, err := encodeKafkaRecordBatch(messages, codec)
+		assert.Nil(t, err)
+
+		decodedCodec, decoded, err := decodeKafkaRecordBatch(batch)
+		assert.Nil(t, err)
+		assert.Equal(t, codec, decodedCodec)
+		assert.Equal(t, messages, decoded)
+		if codec != kafkaCodecNone {
+			assert.True(t, len(batch) < 10000)
+		}
+	}
+}
+
+func TestKafkaMurmur2(t *testing.T) {
+	// the values of the Java client
+	assert.Equal(t, int32(-973932308), kafkaMurmur2([]byte("21")))
+	assert.Equal(t, int32(-790332482), kafkaMurmur2([]byte("foobar")))
+	assert.Equal(t, int32(-985981536), kafkaMurmur2([]byte("a-little-bit-long-string")))
+	assert.Equal(t, int32(-1486304829), kafkaMurmur2([]byte("a-little-bit-longer-string")))
+	assert.Equal(t, int32(-58897971), kafkaMurmur2([]byte("lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8")))
+	assert.Equal(t, int32(479470107), kafkaMurmur2([]byte("abc")))
+}
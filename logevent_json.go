@@ -21,7 +21,7 @@ func (jsonLogEvent *JsonLogEvent) Encode(data *LogEventMetadata) []byte {
 		// encode json
 		encoded, err := json.Marshal(jsonLogEvent.event)
 		if err != nil {
-			fmt.Fprintf(os.Stdout, err.Error())
+			fmt.Fprint(os.Stdout, err.Error())
 		}
 
 		return encoded
@@ -35,6 +35,11 @@ func (jsonLogEvent *JsonLogEvent) Encode(data *LogEventMetadata) []byte {
 			SourceLine string `json:"sourceLine,omitempty"`
 			SourceFile string `json:"sourceFile,omitempty"`
 			LoggerName string `json:"loggerName,omitempty"`
+			TraceId    string `json:"trace_id,omitempty"`
+			SpanId     string `json:"span_id,omitempty"`
+			TraceFlags string `json:"trace_flags,omitempty"`
+			Fields     map[string]interface{} `json:"fields,omitempty"`
+			StackTrace StackTrace `json:"stackTrace,omitempty"`
 		}{
 			EventData: jsonLogEvent.event,
 
@@ -43,6 +48,11 @@ func (jsonLogEvent *JsonLogEvent) Encode(data *LogEventMetadata) []byte {
 			SourceLine: data.GetSourceLine(),
 			SourceFile: data.GetSourceFile(),
 			LoggerName: data.GetLoggerName(),
+			TraceId:    data.TraceId,
+			SpanId:     data.SpanId,
+			TraceFlags: data.TraceFlags,
+			Fields:     data.Fields.Map(),
+			StackTrace: data.StackTrace,
 		}
 		encoded, err := json.Marshal(eventData)
 
@@ -16,6 +16,26 @@ func (logEvent *TextLogEvent) Encode(metadata *LogEventMetadata) []byte {
 				metadata.GetSourceLine() + ") " +
 					logEvent.Event
 
+		if metadata.TraceId != "" {
+			data += " trace_id=" + metadata.TraceId +
+				" span_id=" + metadata.SpanId +
+				" trace_flags=" + metadata.TraceFlags
+		}
+
+		if len(metadata.Fields) > 0 {
+			data += " " + metadata.Fields.String()
+		}
+
+		if len(metadata.StackTrace) > 0 {
+			data += "\n" + metadata.StackTrace.String()
+		}
+
+		for _, field := range metadata.Fields {
+			if detail, ok := field.Value.(ErrorDetail); ok {
+				data += detail.stackTraceBlock()
+			}
+		}
+
 					return []byte(data)
 	}
 
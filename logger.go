@@ -2,6 +2,7 @@ package golog
 
 import (
 	"os"
+	"errors"
 	"fmt"
 	"io"
 )
@@ -49,6 +50,46 @@ type Logger struct {
 	//
 	// If not specified, the default config wil be used
 	metadataConfig *MetadataConfig
+
+	// fields
+	// Private Option
+	//
+	// Structured fields added to every event, see With
+	fields Fields
+
+	// stackTraceLevels
+	// Private Option
+	//
+	// Levels whose events capture the stack trace of the caller
+	stackTraceLevels LogLevels
+
+	// panicConfig
+	// Private Option
+	//
+	// If not specified, the default config will be used
+	panicConfig *PanicConfig
+
+	// exitFunc
+	// Private Option
+	//
+	// If not specified, os.Exit will be used
+	exitFunc ExitFunc
+
+	// preExitHooks
+	// Private Option
+	preExitHooks []func()
+
+	// crashDump
+	// Private Option
+	//
+	// If specified, the last events are kept for the crash dump written on FATAL
+	crashDump *crashDump
+
+	// spanContext
+	// Private Option
+	//
+	// The active span set by WithContext
+	spanContext *SpanContext
 }
 
 // doAppendIfLevelEnabled
@@ -62,8 +103,19 @@ func (logger *Logger) doAppendIfLevelEnabled(logEvent LogEvent, metadata *LogEve
 	}(os.Stderr)
 
 	if appenders, ok := logger.levelAppender[level]; ok {
-		event := logEvent.Encode(metadata)
+		if logger.crashDump != nil {
+			logger.crashDump.record(level, logEvent, metadata)
+		}
+
+		var event []byte
 		for _, appender := range appenders {
+			if eventAppender, ok := appender.(EventAppender); ok {
+				eventAppender.AppendEvent(level, logEvent, metadata)
+				continue
+			}
+			if event == nil {
+				event = logEvent.Encode(metadata)
+			}
 			appender.Write(event)
 		}
 	}
@@ -75,8 +127,13 @@ func (logger *Logger) newMetadata(level LogLevel) LogEventMetadata {
 	metadata = NewLogEventMetadata(logger.metadataConfig, logger.metadataFormatter)
 	metadata.setLogLevel(level)
 	metadata.setLoggerName(logger.Name)
-	metadata.setSource(4)
+	metadata.setSource(3)
 	metadata.setTime()
+	metadata.setFields(logger.fields)
+	metadata.setSpanContext(logger.spanContext)
+	if logger.stackTraceLevels.Contains(level) {
+		metadata.setStackTrace(2)
+	}
 	return metadata
 }
 
@@ -139,8 +196,7 @@ func (logger *Logger) Fatal(string string) {
 		logger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, nil, LogLevel_FATAL)
 	}
 
-	logger.Close()
-	os.Exit(1)
+	logger.exit()
 }
 
 // Tracef encodes according to format specifier and calls specified appender to print.
@@ -202,8 +258,7 @@ func (logger *Logger) Fatalf(format string, args ...interface{}) {
 		logger.doAppendIfLevelEnabled(&FormatLogEvent{format: format, args: args,}, nil, LogLevel_FATAL)
 	}
 
-	logger.Close()
-	os.Exit(1)
+	logger.exit()
 }
 
 // Tracej encodes as Json binary and calls specified appender to print.
@@ -265,8 +320,7 @@ func (logger *Logger) Fatalj(obj interface{}) {
 		logger.doAppendIfLevelEnabled(&JsonLogEvent{event: obj,}, nil, LogLevel_FATAL)
 	}
 
-	logger.Close()
-	os.Exit(1)
+	logger.exit()
 }
 
 // STrace encodes as user defined logEvent and calls specified appender to print it.
@@ -328,8 +382,18 @@ func (logger *Logger) SFatal(logEvent LogEvent) {
 		logger.doAppendIfLevelEnabled(logEvent, nil, LogLevel_FATAL)
 	}
 
-	logger.Close()
-	os.Exit(1)
+	logger.exit()
+}
+
+// With returns a copy of the logger which adds the given fields to every event.
+// The returned logger shares its appenders with the original logger.
+// Fields are carried by LogEventMetadata, so they are dropped when metadata is disabled.
+func (logger *Logger) With(fields ...Field) Logger {
+	child := *logger
+	child.fields = make(Fields, 0, len(logger.fields)+len(fields))
+	child.fields = append(child.fields, logger.fields...)
+	child.fields = append(child.fields, fields...)
+	return child
 }
 
 // SetAppender
@@ -357,6 +421,12 @@ func (logger *Logger) SetMetadataConfig(config *MetadataConfig) {
 	logger.metadataConfig = config
 }
 
+// SetStackTraceLevels captures the stack trace of the caller for events of the specified levels.
+// e.g. SetStackTraceLevels(LogLevel_ERROR, LogLevel_FATAL)
+func (logger *Logger) SetStackTraceLevels(logLevels ...LogLevel) {
+	logger.stackTraceLevels = logLevels
+}
+
 // SetLogLevel enables the specified log level
 func (logger *Logger) SetAppenderWithLevel(logLevel LogLevel, appender ...Appender) {
 	logger.levelAppender[logLevel] = appender
@@ -369,6 +439,20 @@ func (logger *Logger) SetAppenderWithLevels(logLevels []LogLevel, appender ...Ap
 	}
 }
 
+// Flush flushes the appenders implementing Syncer, e.g. FileAppender.
+// An appender set for several levels is flushed once.
+func (logger *Logger) Flush() error {
+	var errs []error
+	for _, appender := range logger.appenders() {
+		if syncer, ok := appender.(Syncer); ok {
+			if err := syncer.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Close implements io.Closer
 func (logger *Logger) Close() error {
 
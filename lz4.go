@@ -0,0 +1,178 @@
+package golog
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const lz4FrameMagic = 0x184D2204
+
+// lz4BlockSize is the maximum block size of the frames, 64 KB
+const lz4BlockSize = 64 * 1024
+
+// lz4 frame descriptor, version 1 with independent blocks of 64 KB and no checksums
+const (
+	lz4FrameFlags      = 0x60
+	lz4FrameBlockFlags = 0x40
+)
+
+// lz4 block format limits, the last 5 bytes are literals and the last match starts 12 bytes before the end
+const (
+	lz4MinMatch     = 4
+	lz4LastLiterals = 5
+	lz4MatchLimit   = 12
+)
+
+const lz4HashBits = 14
+
+// lz4UncompressedBit marks a block stored as is
+const lz4UncompressedBit = 1 << 31
+
+// lz4EncodeFrame compresses src in the lz4 frame format, as used by Kafka.
+// Blocks are compressed by a greedy encoder like snappyEncode, and stored as is when they do not shrink.
+func lz4EncodeFrame(src []byte) []byte {
+	dst := binary.LittleEndian.AppendUint32(make([]byte, 0, len(src)/2+16), lz4FrameMagic)
+	dst = append(dst, lz4FrameFlags, lz4FrameBlockFlags)
+	dst = append(dst, byte(xxh32(dst[4:6], 0)>>8))
+
+	for len(src) > 0 {
+		n := len(src)
+		if n > lz4BlockSize {
+			n = lz4BlockSize
+		}
+
+		block := lz4EncodeBlock(src[:n])
+		if len(block) < n {
+			dst = binary.LittleEndian.AppendUint32(dst, uint32(len(block)))
+			dst = append(dst, block...)
+		} else {
+			dst = binary.LittleEndian.AppendUint32(dst, uint32(n)|lz4UncompressedBit)
+			dst = append(dst, src[:n]...)
+		}
+		src = src[n:]
+	}
+
+	// end mark
+	return binary.LittleEndian.AppendUint32(dst, 0)
+}
+
+// lz4EncodeBlock compresses src in the lz4 block format
+func lz4EncodeBlock(src []byte) []byte {
+	var dst []byte
+	var table [1 << lz4HashBits]int
+	literalStart := 0
+
+	for i := 0; i+lz4MatchLimit < len(src); {
+		key := binary.LittleEndian.Uint32(src[i:])
+		hash := (key * 2654435761) >> (32 - lz4HashBits)
+
+		// the table holds positions plus one, zero is empty
+		candidate := table[hash] - 1
+		table[hash] = i + 1
+
+		if candidate < 0 || i-candidate > 65535 || binary.LittleEndian.Uint32(src[candidate:]) != key {
+			i++
+			continue
+		}
+
+		length := lz4MinMatch
+		for i+length < len(src)-lz4LastLiterals && src[candidate+length] == src[i+length] {
+			length++
+		}
+
+		dst = lz4AppendSequence(dst, src[literalStart:i], i-candidate, length)
+		i += length
+		literalStart = i
+	}
+	return lz4AppendSequence(dst, src[literalStart:], 0, 0)
+}
+
+// lz4AppendSequence appends literals followed by a match, the last sequence has no match
+func lz4AppendSequence(dst []byte, literals []byte, offset int, length int) []byte {
+	token := len(dst)
+	dst = append(dst, 0)
+
+	if len(literals) >= 15 {
+		dst[token] = 15 << 4
+		dst = lz4AppendLength(dst, len(literals)-15)
+	} else {
+		dst[token] = byte(len(literals)) << 4
+	}
+	dst = append(dst, literals...)
+
+	if length == 0 {
+		return dst
+	}
+
+	dst = binary.LittleEndian.AppendUint16(dst, uint16(offset))
+	if length-lz4MinMatch >= 15 {
+		dst[token] |= 15
+		dst = lz4AppendLength(dst, length-lz4MinMatch-15)
+	} else {
+		dst[token] |= byte(length - lz4MinMatch)
+	}
+	return dst
+}
+
+// lz4AppendLength appends the remainder of a length as bytes of 255 and a last byte
+func lz4AppendLength(dst []byte, n int) []byte {
+	for n >= 255 {
+		dst = append(dst, 255)
+		n -= 255
+	}
+	return append(dst, byte(n))
+}
+
+// xxHash32 primes
+const (
+	xxh32Prime1 uint32 = 2654435761
+	xxh32Prime2 uint32 = 2246822519
+	xxh32Prime3 uint32 = 3266489917
+	xxh32Prime4 uint32 = 668265263
+	xxh32Prime5 uint32 = 374761393
+)
+
+// xxh32 returns the xxHash32 of data, used for the lz4 frame header checksum
+func xxh32(data []byte, seed uint32) uint32 {
+	length := uint32(len(data))
+	var h uint32
+
+	if len(data) >= 16 {
+		v1 := seed + xxh32Prime1 + xxh32Prime2
+		v2 := seed + xxh32Prime2
+		v3 := seed
+		v4 := seed - xxh32Prime1
+		for ; len(data) >= 16; data = data[16:] {
+			v1 = xxh32Round(v1, binary.LittleEndian.Uint32(data[0:]))
+			v2 = xxh32Round(v2, binary.LittleEndian.Uint32(data[4:]))
+			v3 = xxh32Round(v3, binary.LittleEndian.Uint32(data[8:]))
+			v4 = xxh32Round(v4, binary.LittleEndian.Uint32(data[12:]))
+		}
+		h = bits.RotateLeft32(v1, 1) + bits.RotateLeft32(v2, 7) + bits.RotateLeft32(v3, 12) + bits.RotateLeft32(v4, 18)
+	} else {
+		h = seed + xxh32Prime5
+	}
+
+	h += length
+	for ; len(data) >= 4; data = data[4:] {
+		h += binary.LittleEndian.Uint32(data) * xxh32Prime3
+		h = bits.RotateLeft32(h, 17) * xxh32Prime4
+	}
+	for _, b := range data {
+		h += uint32(b) * xxh32Prime5
+		h = bits.RotateLeft32(h, 11) * xxh32Prime1
+	}
+
+	h ^= h >> 15
+	h *= xxh32Prime2
+	h ^= h >> 13
+	h *= xxh32Prime3
+	h ^= h >> 16
+	return h
+}
+
+// xxh32Round
+func xxh32Round(acc uint32, input uint32) uint32 {
+	acc += input * xxh32Prime2
+	return bits.RotateLeft32(acc, 13) * xxh32Prime1
+}
@@ -0,0 +1,123 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lz4DecodeFrame decompresses the lz4 frame format without checksums
+func lz4DecodeFrame(src []byte) ([]byte, error) {
+	if len(src) < 7 || binary.LittleEndian.Uint32(src) != lz4FrameMagic {
+		return nil, errors.New("invalid magic")
+	}
+	if byte(xxh32(src[4:6], 0)>>8) != src[6] {
+		return nil, errors.New("invalid header checksum")
+	}
+	src = src[7:]
+
+	var dst []byte
+	for {
+		size := binary.LittleEndian.Uint32(src)
+		src = src[4:]
+		if size == 0 {
+			return dst, nil
+		}
+
+		n := int(size &^ lz4UncompressedBit)
+		if size&lz4UncompressedBit != 0 {
+			dst = append(dst, src[:n]...)
+		} else {
+			block, err := lz4DecodeBlock(src[:n])
+			if err != nil {
+				return nil, err
+			}
+			dst = append(dst, block...)
+		}
+		src = src[n:]
+	}
+}
+
+// lz4DecodeBlock decompresses the lz4 block format
+func lz4DecodeBlock(src []byte) ([]byte, error) {
+	var dst []byte
+	readLength := func() int {
+		n := 0
+		for {
+			b := int(src[0])
+			src = src[1:]
+			n += b
+			if b != 255 {
+				return n
+			}
+		}
+	}
+
+	for len(src) > 0 {
+		token := src[0]
+		src = src[1:]
+
+		literals := int(token >> 4)
+		if literals == 15 {
+			literals += readLength()
+		}
+		dst = append(dst, src[:literals]...)
+		src = src[literals:]
+		if len(src) == 0 {
+			return dst, nil
+		}
+
+		offset := int(binary.LittleEndian.Uint16(src))
+		src = src[2:]
+		length := int(token & 15)
+		if length == 15 {
+			length += readLength()
+		}
+		length += lz4MinMatch
+
+		if offset <= 0 || offset > len(dst) {
+			return nil, errors.New("invalid offset")
+		}
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	return dst, nil
+}
+
+func TestLz4EncodeFrame(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+	noise := make([]byte, 100000)
+	random.Read(noise)
+
+	for _, src := range [][]byte{
+		nil,
+		[]byte("a"),
+		[]byte("abcabcabcabcabcabcabcabc"),
+		bytes.Repeat([]byte("level=info message=hello "), 10000),
+		noise,
+	} {
+		encoded := lz4EncodeFrame(src)
+		decoded, err := lz4DecodeFrame(encoded)
+		assert.Nil(t, err)
+		assert.True(t, bytes.Equal(src, decoded))
+	}
+
+	// the frame header of the descriptor
+	assert.Equal(t, []byte{0x04, 0x22, 0x4d, 0x18, 0x60, 0x40, 0x82}, lz4EncodeFrame(nil)[:7])
+
+	// repeated input is compressed
+	repeated := bytes.Repeat([]byte("level=info message=hello "), 10000)
+	assert.True(t, len(lz4EncodeFrame(repeated)) < len(repeated)/10)
+}
+
+func TestXxh32(t *testing.T) {
+	assert.Equal(t, uint32(0x02cc5d05), xxh32(nil, 0))
+	assert.Equal(t, uint32(0x550d7456), xxh32([]byte("a"), 0))
+	assert.Equal(t, uint32(0x32d153ff), xxh32([]byte("abc"), 0))
+}
@@ -17,14 +17,29 @@ type LoggerName = string
 // UnixTime
 type UnixTime = int64
 
+// UnixNano
+type UnixNano = int64
+
 // LogEventMetadata
 type LogEventMetadata struct {
 	LogLevel   LogLevel
 	UnixTime   UnixTime
+	UnixNano   UnixNano
 	SourceFile SourceFile
 	SourceLine SourceLine
 	LoggerName LoggerName
 
+	// Fields are the structured fields bound to the logger with Logger.With
+	Fields Fields
+
+	// StackTrace is captured for the levels set by Logger.SetStackTraceLevels
+	StackTrace StackTrace
+
+	// TraceId, SpanId and TraceFlags identify the active span set by Logger.WithContext
+	TraceId    string
+	SpanId     string
+	TraceFlags string
+
 	MetadataFormatter
 	MetadataConfig
 }
@@ -138,6 +153,36 @@ func (metadata *LogEventMetadata) setSource(skip int) {
 	}
 }
 
+// setFields
+func (metadata *LogEventMetadata) setFields(fields Fields) {
+	if metadata == nil {
+		return
+	}
+
+	metadata.Fields = fields
+}
+
+// setSpanContext
+func (metadata *LogEventMetadata) setSpanContext(spanContext *SpanContext) {
+	if metadata == nil || spanContext == nil {
+		return
+	}
+
+	metadata.TraceId = spanContext.TraceId
+	metadata.SpanId = spanContext.SpanId
+	metadata.TraceFlags = spanContext.TraceFlags
+}
+
+// setStackTrace
+// skip is the number of frames to skip, 0 identifying the caller of setStackTrace
+func (metadata *LogEventMetadata) setStackTrace(skip int) {
+	if metadata == nil {
+		return
+	}
+
+	metadata.StackTrace = NewStackTrace(skip + 1)
+}
+
 // setTime
 func (metadata *LogEventMetadata) setTime() {
 	if metadata == nil {
@@ -145,7 +190,9 @@ func (metadata *LogEventMetadata) setTime() {
 	}
 
 	if metadata.IsEnabledTime == true {
-		metadata.UnixTime = time.Now().Unix()
+		now := time.Now()
+		metadata.UnixTime = now.Unix()
+		metadata.UnixNano = now.UnixNano()
 	}
 }
 
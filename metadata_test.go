@@ -1 +1,50 @@
 package golog
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sourceAppender keeps the metadata of the last event
+type sourceAppender struct {
+	metadata LogEventMetadata
+}
+
+func (appender *sourceAppender) AppendEvent(level LogLevel, logEvent LogEvent, metadata *LogEventMetadata) error {
+	appender.metadata = *metadata
+	return nil
+}
+
+func (appender *sourceAppender) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (appender *sourceAppender) Close() error {
+	return nil
+}
+
+func TestLogEventMetadata_source(t *testing.T) {
+	appender := &sourceAppender{}
+	logger := NewLogger("source", LogLevel_TRACE, appender)
+
+	// the source is the line calling the logger
+	_, file, line, _ := runtime.Caller(0)
+	logger.Info("message")
+	assert.Equal(t, file, appender.metadata.SourceFile)
+	assert.Equal(t, line+1, appender.metadata.SourceLine)
+
+	_, _, line, _ = runtime.Caller(0)
+	logger.Errorf("message %d", 1)
+	assert.Equal(t, line+1, appender.metadata.SourceLine)
+
+	_, _, line, _ = runtime.Caller(0)
+	logger.SWarn(&TextLogEvent{Event: "message"})
+	assert.Equal(t, line+1, appender.metadata.SourceLine)
+
+	_, _, line, _ = runtime.Caller(0)
+	logger.InfoContext(context.Background(), "message")
+	assert.Equal(t, line+1, appender.metadata.SourceLine)
+}
@@ -0,0 +1,98 @@
+package golog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PanicConfig
+type PanicConfig struct {
+	// LogLevel is the level of the event logged for a recovered panic
+	LogLevel LogLevel
+
+	// Repanic panics again with the recovered value after logging it.
+	// If false, the goroutine continues after the deferred RecoverPanic.
+	Repanic bool
+}
+
+// NewDefaultPanicConfig
+func NewDefaultPanicConfig() PanicConfig {
+	return PanicConfig{
+		LogLevel: LogLevel_FATAL,
+		Repanic:  true,
+	}
+}
+
+// SetPanicConfig
+func (logger *Logger) SetPanicConfig(config *PanicConfig) {
+	logger.panicConfig = config
+}
+
+// RecoverPanic recovers a panic, logs it with the stack of the goroutine and
+// flushes the appenders. It must be called directly by defer.
+//
+//	defer logger.RecoverPanic()
+func (logger *Logger) RecoverPanic() {
+	value := recover()
+	if value == nil {
+		return
+	}
+
+	config := NewDefaultPanicConfig()
+	if logger.panicConfig != nil {
+		config = *logger.panicConfig
+	}
+
+	// skip RecoverPanic, the stack starts at the panic
+	logger.logPanic(config.LogLevel, value, NewStackTrace(1))
+	if err := logger.Flush(); err != nil {
+		warnLogger.Warnf("flush appender is failed , error : %s", err.Error())
+	}
+
+	if config.Repanic {
+		panic(value)
+	}
+}
+
+// Go runs f in a new goroutine which recovers and logs panics, see RecoverPanic
+func (logger *Logger) Go(f func()) {
+	go func() {
+		defer logger.RecoverPanic()
+		f()
+	}()
+}
+
+// logPanic
+func (logger *Logger) logPanic(level LogLevel, value interface{}, stackTrace StackTrace) {
+	fields := Fields{F("panic", fmt.Sprint(value))}
+	if err, ok := value.(error); ok {
+		fields = append(fields, Err(err))
+	}
+
+	panicLogger := logger.With(fields...)
+	logEvent := &TextLogEvent{Event: "panic recovered"}
+	if !panicLogger.enabledMetadata {
+		panicLogger.doAppendIfLevelEnabled(logEvent, nil, level)
+		return
+	}
+
+	metadata := panicLogger.newMetadata(level)
+	metadata.StackTrace = stackTrace
+	metadata.setSourceFromStackTrace()
+	panicLogger.doAppendIfLevelEnabled(logEvent, &metadata, level)
+}
+
+// setSourceFromStackTrace sets the source to the first frame outside the runtime
+func (metadata *LogEventMetadata) setSourceFromStackTrace() {
+	if metadata.IsEnabledSourceLine == false && metadata.IsEnabledSourceFile == false {
+		return
+	}
+
+	for _, frame := range metadata.StackTrace {
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			metadata.SourceFile = frame.File
+			metadata.SourceLine = frame.Line
+			return
+		}
+	}
+}
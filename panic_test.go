@@ -0,0 +1,63 @@
+package golog
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_RecoverPanic(t *testing.T) {
+
+	// continue
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		logger.SetPanicConfig(&PanicConfig{LogLevel: LogLevel_ERROR, Repanic: false})
+
+		func() {
+			defer logger.RecoverPanic()
+			panic("boom")
+		}()
+
+		lines := strings.Split(appender.String(), "\n")
+		assert.Equal(t, "   () panic recovered panic=boom", lines[0])
+		assert.Equal(t, "\truntime.gopanic", lines[1])
+		assert.Equal(t, true, strings.HasPrefix(lines[3], "\tgithub.com/ajainc/golog.TestLogger_RecoverPanic.func1"))
+	}()
+
+	// repanic
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+
+		assert.PanicsWithValue(t, "boom", func() {
+			defer logger.RecoverPanic()
+			panic("boom")
+		})
+		assert.Equal(t, true, strings.HasPrefix(appender.String(), "   () panic recovered panic=boom\n"))
+	}()
+}
+
+func TestLogger_Go(t *testing.T) {
+
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		logger.SetPanicConfig(&PanicConfig{LogLevel: LogLevel_ERROR, Repanic: false})
+
+		wg := new(sync.WaitGroup)
+		wg.Add(1)
+		logger.Go(func() {
+			defer wg.Done()
+			panic("boom")
+		})
+		wg.Wait()
+
+		assert.Equal(t, true, strings.HasPrefix(appender.String(), "   () panic recovered panic=boom\n"))
+	}()
+}
@@ -0,0 +1,80 @@
+package golog
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// protobuf wire types
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+	protoWireFixed32 = 5
+)
+
+// protoBuffer is a minimal protocol buffers encoder for the wire formats golog exports to.
+// Fields are appended in the order they are written; zero values are written as is,
+// callers skip them where proto3 would.
+type protoBuffer struct {
+	data []byte
+}
+
+// bytes returns the encoded message
+func (buffer *protoBuffer) bytes() []byte {
+	return buffer.data
+}
+
+// tag
+func (buffer *protoBuffer) tag(fieldNumber int, wireType int) {
+	buffer.varint(uint64(fieldNumber)<<3 | uint64(wireType))
+}
+
+// varint
+func (buffer *protoBuffer) varint(value uint64) {
+	buffer.data = binary.AppendUvarint(buffer.data, value)
+}
+
+// varintField writes an int32, int64, uint32, uint64, bool or enum field
+func (buffer *protoBuffer) varintField(fieldNumber int, value uint64) {
+	buffer.tag(fieldNumber, protoWireVarint)
+	buffer.varint(value)
+}
+
+// fixed64Field writes a fixed64 or sfixed64 field
+func (buffer *protoBuffer) fixed64Field(fieldNumber int, value uint64) {
+	buffer.tag(fieldNumber, protoWireFixed64)
+	buffer.data = binary.LittleEndian.AppendUint64(buffer.data, value)
+}
+
+// fixed32Field writes a fixed32 or sfixed32 field
+func (buffer *protoBuffer) fixed32Field(fieldNumber int, value uint32) {
+	buffer.tag(fieldNumber, protoWireFixed32)
+	buffer.data = binary.LittleEndian.AppendUint32(buffer.data, value)
+}
+
+// doubleField writes a double field
+func (buffer *protoBuffer) doubleField(fieldNumber int, value float64) {
+	buffer.fixed64Field(fieldNumber, math.Float64bits(value))
+}
+
+// bytesField writes a bytes field
+func (buffer *protoBuffer) bytesField(fieldNumber int, value []byte) {
+	buffer.tag(fieldNumber, protoWireBytes)
+	buffer.varint(uint64(len(value)))
+	buffer.data = append(buffer.data, value...)
+}
+
+// stringField writes a string field
+func (buffer *protoBuffer) stringField(fieldNumber int, value string) {
+	buffer.tag(fieldNumber, protoWireBytes)
+	buffer.varint(uint64(len(value)))
+	buffer.data = append(buffer.data, value...)
+}
+
+// messageField writes an embedded message field encoded by encode
+func (buffer *protoBuffer) messageField(fieldNumber int, encode func(message *protoBuffer)) {
+	message := &protoBuffer{}
+	encode(message)
+	buffer.bytesField(fieldNumber, message.data)
+}
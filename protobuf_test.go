@@ -0,0 +1,78 @@
+package golog
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// protoField is a decoded field, value holds varints and fixed values, data holds bytes
+type protoField struct {
+	number int
+	value  uint64
+	data   []byte
+}
+
+// decodeProto decodes the top level fields of a message
+func decodeProto(t *testing.T, message []byte) []protoField {
+	var fields []protoField
+	for len(message) > 0 {
+		tag, n := binary.Uvarint(message)
+		assert.True(t, n > 0)
+		message = message[n:]
+
+		field := protoField{number: int(tag >> 3)}
+		switch tag & 0x7 {
+		case protoWireVarint:
+			field.value, n = binary.Uvarint(message)
+			message = message[n:]
+		case protoWireFixed64:
+			field.value = binary.LittleEndian.Uint64(message)
+			message = message[8:]
+		case protoWireFixed32:
+			field.value = uint64(binary.LittleEndian.Uint32(message))
+			message = message[4:]
+		case protoWireBytes:
+			length, n := binary.Uvarint(message)
+			field.data = message[n : n+int(length)]
+			message = message[n+int(length):]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&0x7)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// protoFields returns the fields of the number
+func protoFields(fields []protoField, number int) []protoField {
+	var found []protoField
+	for _, field := range fields {
+		if field.number == number {
+			found = append(found, field)
+		}
+	}
+	return found
+}
+
+func TestProtoBuffer(t *testing.T) {
+
+	buffer := &protoBuffer{}
+	buffer.varintField(1, 150)
+	buffer.stringField(2, "testing")
+	buffer.fixed64Field(3, 1)
+	buffer.fixed32Field(4, 2)
+	buffer.messageField(5, func(message *protoBuffer) {
+		message.varintField(1, 1)
+	})
+
+	assert.Equal(t, []byte{0x08, 0x96, 0x01}, buffer.bytes()[:3])
+
+	fields := decodeProto(t, buffer.bytes())
+	assert.Equal(t, uint64(150), fields[0].value)
+	assert.Equal(t, "testing", string(fields[1].data))
+	assert.Equal(t, uint64(1), fields[2].value)
+	assert.Equal(t, uint64(2), fields[3].value)
+	assert.Equal(t, []byte{0x08, 0x01}, fields[4].data)
+}
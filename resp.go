@@ -0,0 +1,174 @@
+package golog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// respMaxBulkSize bounds the size of a bulk string read from the server
+const respMaxBulkSize = 512 * 1024 * 1024
+
+// respError is an error reply of the server, the connection is still usable
+type respError string
+
+// Error implements error
+func (err respError) Error() string {
+	return string(err)
+}
+
+// retryable reports whether the error reply is transient, e.g. the server is loading the dataset
+func (err respError) retryable() bool {
+	for _, prefix := range []string{"LOADING", "BUSY", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN"} {
+		if strings.HasPrefix(string(err), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// respConn is a connection to a Redis server speaking RESP2.
+// Commands are buffered by send and written by flush, so that they can be pipelined.
+type respConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+	timeout time.Duration
+}
+
+// dialResp connects to the server, authenticates if password is not empty and selects the database
+func dialResp(address string, username string, password string, database int, timeout time.Duration) (*respConn, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &respConn{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		writer:  bufio.NewWriter(conn),
+		timeout: timeout,
+	}
+
+	if password != "" {
+		if username != "" {
+			_, err = client.do("AUTH", username, password)
+		} else {
+			_, err = client.do("AUTH", password)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if database != 0 {
+		if _, err := client.do("SELECT", strconv.Itoa(database)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// send buffers the command
+func (client *respConn) send(args ...string) {
+	client.writer.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		client.writer.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n")
+		client.writer.WriteString(arg)
+		client.writer.WriteString("\r\n")
+	}
+}
+
+// flush writes the buffered commands, the replies must be read before timeout
+func (client *respConn) flush(timeout time.Duration) error {
+	if err := client.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	return client.writer.Flush()
+}
+
+// do sends the command and reads its reply
+func (client *respConn) do(args ...string) (interface{}, error) {
+	client.send(args...)
+	if err := client.flush(client.timeout); err != nil {
+		return nil, err
+	}
+	return client.receive()
+}
+
+// receive reads a reply, which is a string, an int64, nil or a slice of replies.
+// An error reply is returned as respError.
+func (client *respConn) receive() (interface{}, error) {
+	line, err := client.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, respError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size > respMaxBulkSize {
+			return nil, fmt.Errorf("invalid bulk size %q", line[1:])
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(client.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid array size %q", line[1:])
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		replies := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			reply, err := client.receive()
+			if _, ok := err.(respError); err != nil && !ok {
+				return nil, err
+			}
+			replies = append(replies, reply)
+		}
+		return replies, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+}
+
+// readLine reads a line without CRLF
+func (client *respConn) readLine() (string, error) {
+	line, err := client.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("invalid line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// close
+func (client *respConn) close() error {
+	return client.conn.Close()
+}
@@ -0,0 +1,115 @@
+package golog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Shutdown flushes and closes all appenders concurrently.
+// It returns when every appender is closed or ctx is done, whichever comes first.
+func (logger *Logger) Shutdown(ctx context.Context) error {
+	appenders := logger.appenders()
+	results := make(chan error, len(appenders))
+
+	for _, appender := range appenders {
+		go func(appender Appender) {
+			var flushErr error
+			if syncer, ok := appender.(Syncer); ok {
+				flushErr = syncer.Flush()
+			}
+			results <- errors.Join(flushErr, appender.Close())
+		}(appender)
+	}
+
+	var errs []error
+	for range appenders {
+		select {
+		case err := <-results:
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ShutdownOnSignal shuts the logger down within timeout when one of signals is received,
+// then exits with 128 + the signal number. SIGTERM and SIGINT are used if no signal is specified.
+// The pre exit hooks are called before the shutdown.
+//
+// Applications which handle the signals themselves should call Shutdown
+// at the end of their own shutdown instead.
+// The returned function stops the handler.
+func (logger *Logger) ShutdownOnSignal(timeout time.Duration, signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGTERM, syscall.SIGINT}
+	}
+
+	received := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(received, signals...)
+
+	go func() {
+		select {
+		case sig := <-received:
+			signal.Stop(received)
+
+			for _, hook := range logger.preExitHooks {
+				hook()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			if err := logger.Shutdown(ctx); err != nil {
+				warnLogger.Warnf("shutdown logger is failed , error : %s", err.Error())
+			}
+			cancel()
+
+			code := 1
+			if sig, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(sig)
+			}
+			logger.osExit(code)
+		case <-done:
+		}
+	}()
+
+	once := new(sync.Once)
+	return func() {
+		once.Do(func() {
+			signal.Stop(received)
+			close(done)
+		})
+	}
+}
+
+// appenders returns the appenders of all levels, each appender once
+func (logger *Logger) appenders() []Appender {
+	var appenders []Appender
+	seen := map[Appender]bool{}
+
+	for _, v := range logger.levelAppender {
+		for _, appender := range v {
+			if appender == nil {
+				continue
+			}
+			// only pointers are safely comparable
+			if reflect.TypeOf(appender).Kind() != reflect.Ptr {
+				appenders = append(appenders, appender)
+				continue
+			}
+			if !seen[appender] {
+				seen[appender] = true
+				appenders = append(appenders, appender)
+			}
+		}
+	}
+	return appenders
+}
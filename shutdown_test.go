@@ -0,0 +1,82 @@
+package golog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingAppender blocks on Close until released
+type blockingAppender struct {
+	release chan struct{}
+}
+
+func (appender *blockingAppender) Write(data []byte) (n int, err error) {
+	return len(data), nil
+}
+
+func (appender *blockingAppender) Close() error {
+	<-appender.release
+	return nil
+}
+
+func TestLogger_Shutdown(t *testing.T) {
+
+	// buffered events are flushed
+	func() {
+		fileName := filepath.Join(t.TempDir(), "shutdown")
+		appender, err := NewFileAppender(fileName)
+		assert.Nil(t, err)
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		logger.Info("message")
+
+		assert.Nil(t, logger.Shutdown(context.Background()))
+		actual, err := os.ReadFile(fileName)
+		assert.Nil(t, err)
+		assert.Equal(t, "   () message\n", string(actual))
+	}()
+
+	// deadline
+	func() {
+		appender := &blockingAppender{release: make(chan struct{})}
+		defer close(appender.release)
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, logger.Shutdown(ctx), context.DeadlineExceeded)
+	}()
+}
+
+// flushCountingAppender counts the flushes and fails them
+type flushCountingAppender struct {
+	flushes int
+}
+
+func (appender *flushCountingAppender) Write(data []byte) (n int, err error) {
+	return len(data), nil
+}
+
+func (appender *flushCountingAppender) Flush() error {
+	appender.flushes++
+	return errors.New("disk is full")
+}
+
+func (appender *flushCountingAppender) Close() error {
+	return nil
+}
+
+func TestLogger_Flush(t *testing.T) {
+
+	// an appender of several levels is flushed once and its error is returned
+	appender := &flushCountingAppender{}
+	logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+	assert.NotNil(t, logger.Flush())
+	assert.Equal(t, 1, appender.flushes)
+}
@@ -0,0 +1,45 @@
+//go:build unix
+
+package golog
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_ShutdownOnSignal(t *testing.T) {
+
+	func() {
+		fileName := filepath.Join(t.TempDir(), "shutdown")
+		appender, err := NewFileAppender(fileName)
+		assert.Nil(t, err)
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+
+		exited := make(chan int, 1)
+		logger.SetExitFunc(func(code int) {
+			exited <- code
+		})
+		stop := logger.ShutdownOnSignal(time.Second, syscall.SIGUSR1)
+		defer stop()
+
+		logger.Info("message")
+		syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+
+		select {
+		case code := <-exited:
+			assert.Equal(t, 128+int(syscall.SIGUSR1), code)
+		case <-time.After(5 * time.Second):
+			t.Fatal("logger is not shut down")
+		}
+
+		actual, err := os.ReadFile(fileName)
+		assert.Nil(t, err)
+		assert.Equal(t, "   () message\n", string(actual))
+	}()
+}
@@ -0,0 +1,85 @@
+package golog
+
+import (
+	"encoding/binary"
+)
+
+// snappy tag types
+const (
+	snappyTagLiteral = 0
+	snappyTagCopy2   = 2
+)
+
+// snappyHashBits is the size of the match table
+const snappyHashBits = 14
+
+// snappyMaxOffset is the maximum offset of a copy with a 2 byte offset
+const snappyMaxOffset = 65535
+
+// snappyEncode compresses src in the snappy block format, as pushed to Loki.
+// It is a greedy encoder finding 4 byte matches by hash, emitting literals and copies with 2 byte offsets.
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(make([]byte, 0, len(src)/2+16), uint64(len(src)))
+
+	var table [1 << snappyHashBits]int
+	literalStart := 0
+	for i := 0; i+4 <= len(src); {
+		key := binary.LittleEndian.Uint32(src[i:])
+		hash := (key * 0x1e35a7bd) >> (32 - snappyHashBits)
+
+		// the table holds positions plus one, zero is empty
+		candidate := table[hash] - 1
+		table[hash] = i + 1
+
+		if candidate < 0 || i-candidate > snappyMaxOffset || binary.LittleEndian.Uint32(src[candidate:]) != key {
+			i++
+			continue
+		}
+
+		length := 4
+		for i+length < len(src) && src[candidate+length] == src[i+length] {
+			length++
+		}
+
+		dst = snappyAppendLiteral(dst, src[literalStart:i])
+		dst = snappyAppendCopy(dst, i-candidate, length)
+		i += length
+		literalStart = i
+	}
+	return snappyAppendLiteral(dst, src[literalStart:])
+}
+
+// snappyAppendLiteral
+func snappyAppendLiteral(dst []byte, literal []byte) []byte {
+	if len(literal) == 0 {
+		return dst
+	}
+
+	n := uint32(len(literal) - 1)
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2|snappyTagLiteral)
+	case n < 1<<8:
+		dst = append(dst, 60<<2|snappyTagLiteral, byte(n))
+	case n < 1<<16:
+		dst = append(dst, 61<<2|snappyTagLiteral, byte(n), byte(n>>8))
+	case n < 1<<24:
+		dst = append(dst, 62<<2|snappyTagLiteral, byte(n), byte(n>>8), byte(n>>16))
+	default:
+		dst = append(dst, 63<<2|snappyTagLiteral, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+	}
+	return append(dst, literal...)
+}
+
+// snappyAppendCopy appends copies of at most 64 bytes
+func snappyAppendCopy(dst []byte, offset int, length int) []byte {
+	for length > 0 {
+		n := length
+		if n > 64 {
+			n = 64
+		}
+		dst = append(dst, byte(n-1)<<2|snappyTagCopy2, byte(offset), byte(offset>>8))
+		length -= n
+	}
+	return dst
+}
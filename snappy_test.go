@@ -0,0 +1,92 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// snappyDecode decompresses the snappy block format
+func snappyDecode(src []byte) ([]byte, error) {
+	length, n := binary.Uvarint(src)
+	if n <= 0 {
+		return nil, errors.New("invalid length")
+	}
+	src = src[n:]
+
+	dst := make([]byte, 0, length)
+	for len(src) > 0 {
+		tag := src[0]
+		var offset, size int
+		switch tag & 0x3 {
+		case 0:
+			size = int(tag >> 2)
+			src = src[1:]
+			if size >= 60 {
+				extra := size - 59
+				size = 0
+				for i := 0; i < extra; i++ {
+					size |= int(src[i]) << (8 * i)
+				}
+				src = src[extra:]
+			}
+			size++
+			dst = append(dst, src[:size]...)
+			src = src[size:]
+			continue
+		case 1:
+			size = 4 + int(tag>>2)&0x7
+			offset = int(tag&0xe0)<<3 | int(src[1])
+			src = src[2:]
+		case 2:
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+		case 3:
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+		}
+
+		if offset <= 0 || offset > len(dst) {
+			return nil, errors.New("invalid offset")
+		}
+		for i := 0; i < size; i++ {
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+
+	if uint64(len(dst)) != length {
+		return nil, errors.New("invalid length")
+	}
+	return dst, nil
+}
+
+func TestSnappyEncode(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+	noise := make([]byte, 100000)
+	random.Read(noise)
+
+	for _, src := range [][]byte{
+		nil,
+		[]byte("a"),
+		[]byte("abcabcabcabcabcabcabcabc"),
+		bytes.Repeat([]byte("level=info message=hello "), 1000),
+		noise,
+	} {
+		encoded := snappyEncode(src)
+		decoded, err := snappyDecode(encoded)
+		assert.Nil(t, err)
+		assert.Equal(t, len(src), len(decoded))
+		assert.True(t, bytes.Equal(src, decoded))
+	}
+
+	// repeated input is compressed
+	repeated := bytes.Repeat([]byte("level=info message=hello "), 1000)
+	assert.True(t, len(snappyEncode(repeated)) < len(repeated)/10)
+}
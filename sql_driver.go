@@ -0,0 +1,556 @@
+package golog
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const defaultSlowQueryThreshold = time.Millisecond * 500
+
+// LoggingDriverOptions
+type LoggingDriverOptions struct {
+	// SlowQueryThreshold escalates the event of a query or exec taking longer from DEBUG to WARN.
+	// Slow queries are not escalated if zero or less.
+	SlowQueryThreshold time.Duration
+
+	// ShowArgs logs the values of the arguments, otherwise they are logged as REDACTED
+	ShowArgs bool
+}
+
+// NewDefaultLoggingDriverOptions
+func NewDefaultLoggingDriverOptions() LoggingDriverOptions {
+	return LoggingDriverOptions{
+		SlowQueryThreshold: defaultSlowQueryThreshold,
+	}
+}
+
+// LoggingDriver is a database/sql/driver.Driver which logs every query and exec of the wrapped driver
+// with its duration, row count, error and arguments.
+// Events are logged at DEBUG, at WARN if slower than SlowQueryThreshold and at ERROR if failed.
+// The request scoped logger of the context is used if present, see LoggerFromContext.
+//
+// Example:
+//
+//	sql.Register("logging-postgres", golog.NewLoggingDriver(logger, &pq.Driver{}, golog.NewDefaultLoggingDriverOptions()))
+type LoggingDriver struct {
+	driver  driver.Driver
+	logger  Logger
+	options LoggingDriverOptions
+}
+
+// NewLoggingDriver returns new LoggingDriver
+func NewLoggingDriver(logger Logger, wrapped driver.Driver, options LoggingDriverOptions) *LoggingDriver {
+	return &LoggingDriver{
+		driver:  wrapped,
+		logger:  logger,
+		options: options,
+	}
+}
+
+// Open implements driver.Driver
+func (d *LoggingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &loggingConn{conn: conn, driver: d}, nil
+}
+
+// OpenConnector implements driver.DriverContext
+func (d *LoggingDriver) OpenConnector(name string) (driver.Connector, error) {
+	if driverContext, ok := d.driver.(driver.DriverContext); ok {
+		connector, err := driverContext.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &loggingConnector{connector: connector, driver: d}, nil
+	}
+	return &loggingConnector{connector: dsnConnector{name: name, driver: d.driver}, driver: d}, nil
+}
+
+// NewLoggingConnector returns a driver.Connector for sql.OpenDB which logs like LoggingDriver
+func NewLoggingConnector(logger Logger, connector driver.Connector, options LoggingDriverOptions) driver.Connector {
+	return &loggingConnector{
+		connector: connector,
+		driver:    NewLoggingDriver(logger, connector.Driver(), options),
+	}
+}
+
+// loggingConnector
+type loggingConnector struct {
+	connector driver.Connector
+	driver    *LoggingDriver
+}
+
+// Connect implements driver.Connector
+func (connector *loggingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := connector.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &loggingConn{conn: conn, driver: connector.driver}, nil
+}
+
+// Driver implements driver.Connector
+func (connector *loggingConnector) Driver() driver.Driver {
+	return connector.driver
+}
+
+// Close closes the wrapped connector if it is an io.Closer, called by sql.DB.Close
+func (connector *loggingConnector) Close() error {
+	if closer, ok := connector.connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// dsnConnector opens connections of a driver without driver.DriverContext
+type dsnConnector struct {
+	name   string
+	driver driver.Driver
+}
+
+// Connect implements driver.Connector
+func (connector dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return connector.driver.Open(connector.name)
+}
+
+// Driver implements driver.Connector
+func (connector dsnConnector) Driver() driver.Driver {
+	return connector.driver
+}
+
+// log logs the finished query or exec
+func (d *LoggingDriver) log(ctx context.Context, kind string, query string, args []driver.NamedValue, start time.Time, rows int64, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	logger, ok := LoggerFromContext(ctx)
+	if !ok {
+		logger = d.logger.WithContext(ctx)
+	}
+
+	duration := time.Since(start)
+	fields := []Field{F("sql.duration_ms", float64(duration.Microseconds())/1000)}
+	if rows >= 0 {
+		fields = append(fields, F("sql.rows", rows))
+	}
+	if len(args) > 0 {
+		fields = append(fields, F("sql.args", formatSqlArgs(args, d.options.ShowArgs)))
+	}
+	if err != nil {
+		fields = append(fields, Err(err))
+	}
+	logger = logger.With(fields...)
+
+	message := kind + " : " + query
+	switch {
+	case err != nil:
+		logger.Error(message)
+	case d.options.SlowQueryThreshold > 0 && duration >= d.options.SlowQueryThreshold:
+		logger.Warn(message)
+	default:
+		logger.Debug(message)
+	}
+}
+
+// formatSqlArgs returns the arguments as $ordinal=value or :name=value
+func formatSqlArgs(args []driver.NamedValue, show bool) []string {
+	formatted := make([]string, 0, len(args))
+	for _, arg := range args {
+		placeholder := "$" + strconv.Itoa(arg.Ordinal)
+		if arg.Name != "" {
+			placeholder = ":" + arg.Name
+		}
+
+		value := redactedValue
+		if show {
+			value = formatSqlValue(arg.Value)
+		}
+		formatted = append(formatted, placeholder+"="+value)
+	}
+	return formatted
+}
+
+// formatSqlValue
+func formatSqlValue(value driver.Value) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return strconv.Quote(v)
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(v))
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// loggingConn
+type loggingConn struct {
+	conn   driver.Conn
+	driver *LoggingDriver
+}
+
+// Prepare implements driver.Conn
+func (conn *loggingConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := conn.conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &loggingStmt{stmt: stmt, conn: conn.conn, query: query, driver: conn.driver}, nil
+}
+
+// PrepareContext implements driver.ConnPrepareContext
+func (conn *loggingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if prepare, ok := conn.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = prepare.PrepareContext(ctx, query)
+	} else {
+		stmt, err = conn.conn.Prepare(query)
+		if err == nil && ctx.Err() != nil {
+			stmt.Close()
+			err = ctx.Err()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &loggingStmt{stmt: stmt, conn: conn.conn, query: query, driver: conn.driver}, nil
+}
+
+// Close implements driver.Conn
+func (conn *loggingConn) Close() error {
+	return conn.conn.Close()
+}
+
+// Begin implements driver.Conn
+func (conn *loggingConn) Begin() (driver.Tx, error) {
+	return conn.conn.Begin()
+}
+
+// BeginTx implements driver.ConnBeginTx
+func (conn *loggingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginTx, ok := conn.conn.(driver.ConnBeginTx); ok {
+		return beginTx.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 || opts.ReadOnly {
+		return nil, errors.New("driver does not support non-default isolation level or read-only transactions")
+	}
+	return conn.Begin()
+}
+
+// ExecContext implements driver.ExecerContext
+func (conn *loggingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+
+	var result driver.Result
+	var err error
+	switch execer := conn.conn.(type) {
+	case driver.ExecerContext:
+		result, err = execer.ExecContext(ctx, query, args)
+	case driver.Execer:
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			result, err = execer.Exec(query, values)
+		}
+	default:
+		// database/sql prepares a statement instead
+		return nil, driver.ErrSkip
+	}
+
+	conn.driver.log(ctx, "exec", query, args, start, rowsAffected(result, err), err)
+	return result, err
+}
+
+// QueryContext implements driver.QueryerContext
+func (conn *loggingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+
+	var rows driver.Rows
+	var err error
+	switch queryer := conn.conn.(type) {
+	case driver.QueryerContext:
+		rows, err = queryer.QueryContext(ctx, query, args)
+	case driver.Queryer:
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = queryer.Query(query, values)
+		}
+	default:
+		// database/sql prepares a statement instead
+		return nil, driver.ErrSkip
+	}
+
+	return conn.driver.wrapRows(ctx, query, args, start, rows, err)
+}
+
+// Ping implements driver.Pinger
+func (conn *loggingConn) Ping(ctx context.Context) error {
+	if pinger, ok := conn.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession implements driver.SessionResetter
+func (conn *loggingConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := conn.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator
+func (conn *loggingConn) IsValid() bool {
+	if validator, ok := conn.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue implements driver.NamedValueChecker
+func (conn *loggingConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := conn.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// loggingStmt
+type loggingStmt struct {
+	stmt   driver.Stmt
+	conn   driver.Conn
+	query  string
+	driver *LoggingDriver
+}
+
+// Close implements driver.Stmt
+func (stmt *loggingStmt) Close() error {
+	return stmt.stmt.Close()
+}
+
+// NumInput implements driver.Stmt
+func (stmt *loggingStmt) NumInput() int {
+	return stmt.stmt.NumInput()
+}
+
+// Exec implements driver.Stmt
+func (stmt *loggingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return stmt.ExecContext(context.Background(), valuesToNamedValues(args))
+}
+
+// Query implements driver.Stmt
+func (stmt *loggingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return stmt.QueryContext(context.Background(), valuesToNamedValues(args))
+}
+
+// ExecContext implements driver.StmtExecContext
+func (stmt *loggingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+
+	var result driver.Result
+	var err error
+	if execer, ok := stmt.stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			result, err = stmt.stmt.Exec(values)
+		}
+	}
+
+	stmt.driver.log(ctx, "exec", stmt.query, args, start, rowsAffected(result, err), err)
+	return result, err
+}
+
+// QueryContext implements driver.StmtQueryContext
+func (stmt *loggingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+
+	var rows driver.Rows
+	var err error
+	if queryer, ok := stmt.stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = stmt.stmt.Query(values)
+		}
+	}
+
+	return stmt.driver.wrapRows(ctx, stmt.query, args, start, rows, err)
+}
+
+// CheckNamedValue implements driver.NamedValueChecker
+// As database/sql does, the checker of the conn is used if the statement has none.
+func (stmt *loggingStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := stmt.stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	if checker, ok := stmt.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// ColumnConverter implements driver.ColumnConverter
+// database/sql uses it for the arguments skipped by CheckNamedValue.
+func (stmt *loggingStmt) ColumnConverter(index int) driver.ValueConverter {
+	if converter, ok := stmt.stmt.(driver.ColumnConverter); ok {
+		return converter.ColumnConverter(index)
+	}
+	return driver.DefaultParameterConverter
+}
+
+// wrapRows logs a failed query at once, otherwise when its rows are closed
+func (d *LoggingDriver) wrapRows(ctx context.Context, query string, args []driver.NamedValue, start time.Time, rows driver.Rows, err error) (driver.Rows, error) {
+	if err != nil {
+		d.log(ctx, "query", query, args, start, -1, err)
+		return nil, err
+	}
+	return &loggingRows{
+		rows: rows,
+		log: func(count int64, err error) {
+			d.log(ctx, "query", query, args, start, count, err)
+		},
+	}, nil
+}
+
+// rowsAffected returns -1 if unknown
+func rowsAffected(result driver.Result, err error) int64 {
+	if err != nil || result == nil {
+		return -1
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return rows
+}
+
+// namedValuesToValues converts arguments for drivers without context support
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("driver does not support the use of Named Parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// valuesToNamedValues
+func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
+	namedValues := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		namedValues[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return namedValues
+}
+
+// loggingRows counts the rows and logs the query when closed.
+// The duration of the event includes reading the rows.
+type loggingRows struct {
+	rows   driver.Rows
+	log    func(count int64, err error)
+	count  int64
+	err    error
+	closed bool
+}
+
+// Columns implements driver.Rows
+func (rows *loggingRows) Columns() []string {
+	return rows.rows.Columns()
+}
+
+// Next implements driver.Rows
+func (rows *loggingRows) Next(dest []driver.Value) error {
+	err := rows.rows.Next(dest)
+	if err == nil {
+		rows.count++
+	} else if err != io.EOF {
+		rows.err = err
+	}
+	return err
+}
+
+// Close implements driver.Rows
+func (rows *loggingRows) Close() error {
+	err := rows.rows.Close()
+	if !rows.closed {
+		rows.closed = true
+		if rows.err == nil {
+			rows.err = err
+		}
+		rows.log(rows.count, rows.err)
+	}
+	return err
+}
+
+// HasNextResultSet implements driver.RowsNextResultSet
+func (rows *loggingRows) HasNextResultSet() bool {
+	if next, ok := rows.rows.(driver.RowsNextResultSet); ok {
+		return next.HasNextResultSet()
+	}
+	return false
+}
+
+// NextResultSet implements driver.RowsNextResultSet
+func (rows *loggingRows) NextResultSet() error {
+	if next, ok := rows.rows.(driver.RowsNextResultSet); ok {
+		return next.NextResultSet()
+	}
+	return io.EOF
+}
+
+// ColumnTypeScanType implements driver.RowsColumnTypeScanType
+func (rows *loggingRows) ColumnTypeScanType(index int) reflect.Type {
+	if columnType, ok := rows.rows.(driver.RowsColumnTypeScanType); ok {
+		return columnType.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+// ColumnTypeDatabaseTypeName implements driver.RowsColumnTypeDatabaseTypeName
+func (rows *loggingRows) ColumnTypeDatabaseTypeName(index int) string {
+	if columnType, ok := rows.rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return columnType.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+// ColumnTypeLength implements driver.RowsColumnTypeLength
+func (rows *loggingRows) ColumnTypeLength(index int) (int64, bool) {
+	if columnType, ok := rows.rows.(driver.RowsColumnTypeLength); ok {
+		return columnType.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+// ColumnTypeNullable implements driver.RowsColumnTypeNullable
+func (rows *loggingRows) ColumnTypeNullable(index int) (bool, bool) {
+	if columnType, ok := rows.rows.(driver.RowsColumnTypeNullable); ok {
+		return columnType.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+// ColumnTypePrecisionScale implements driver.RowsColumnTypePrecisionScale
+func (rows *loggingRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if columnType, ok := rows.rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return columnType.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
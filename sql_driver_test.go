@@ -0,0 +1,262 @@
+package golog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver is an in-memory driver whose behaviour depends on the query
+type fakeDriver struct{}
+
+// Open implements driver.Driver
+func (fakeDriver) Open(_ string) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+// fakeConnector
+type fakeConnector struct{}
+
+// Connect implements driver.Connector
+func (fakeConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+// Driver implements driver.Connector
+func (fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+// fakeConn
+type fakeConn struct{}
+
+func (conn *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.Contains(query, "CONVERT") {
+		return &fakeConvertingStmt{fakeStmt{query: query}}, nil
+	}
+	return &fakeStmt{query: query}, nil
+}
+
+func (conn *fakeConn) Close() error {
+	return nil
+}
+
+func (conn *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (conn *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := fakeRun(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (conn *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if err := fakeRun(query); err != nil {
+		return nil, err
+	}
+	return &fakeRows{count: 3}, nil
+}
+
+// fakeRun sleeps for SLOW queries and fails FAIL queries
+func fakeRun(query string) error {
+	if strings.Contains(query, "SLOW") {
+		time.Sleep(time.Millisecond * 20)
+	}
+	if strings.Contains(query, "FAIL") {
+		return errors.New("syntax error")
+	}
+	return nil
+}
+
+// fakeStmt supports only the legacy methods
+type fakeStmt struct {
+	query string
+}
+
+func (stmt *fakeStmt) Close() error {
+	return nil
+}
+
+func (stmt *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (stmt *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := fakeRun(stmt.query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (stmt *fakeStmt) Query(_ []driver.Value) (driver.Rows, error) {
+	if err := fakeRun(stmt.query); err != nil {
+		return nil, err
+	}
+	return &fakeRows{count: 3}, nil
+}
+
+// fakeConvertingStmt multiplies integer arguments by 10 in CheckNamedValue and upper-cases the others in ColumnConverter
+type fakeConvertingStmt struct {
+	fakeStmt
+}
+
+func (stmt *fakeConvertingStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if n, ok := value.Value.(int); ok {
+		value.Value = int64(n * 10)
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (stmt *fakeConvertingStmt) ColumnConverter(_ int) driver.ValueConverter {
+	return upperCaseConverter{}
+}
+
+// upperCaseConverter
+type upperCaseConverter struct{}
+
+func (upperCaseConverter) ConvertValue(value interface{}) (driver.Value, error) {
+	if s, ok := value.(string); ok {
+		return strings.ToUpper(s), nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(value)
+}
+
+// fakeRows returns count rows of a single id column
+type fakeRows struct {
+	count int
+	next  int
+}
+
+func (rows *fakeRows) Columns() []string {
+	return []string{"id"}
+}
+
+func (rows *fakeRows) Close() error {
+	return nil
+}
+
+func (rows *fakeRows) Next(dest []driver.Value) error {
+	if rows.next >= rows.count {
+		return io.EOF
+	}
+	rows.next++
+	dest[0] = int64(rows.next)
+	return nil
+}
+
+// withoutDuration replaces the varying durations
+func withoutDuration(s string) string {
+	return regexp.MustCompile(`sql\.duration_ms=[0-9.]+`).ReplaceAllString(s, "sql.duration_ms=X")
+}
+
+// the driver is registered once, sql.Register panics on a second registration, e.g. with go test -count=2
+func init() {
+	sql.Register("golog-fake", NewLoggingDriver(NewLogger("unused", LogLevel_TRACE, newBufferAppender()), fakeDriver{}, NewDefaultLoggingDriverOptions()))
+}
+
+func TestLoggingDriver(t *testing.T) {
+
+	// exec and query at DEBUG, arguments redacted
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("sqlLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{IsEnabledLogLevel: true})
+		db := sql.OpenDB(NewLoggingConnector(logger, fakeConnector{}, NewDefaultLoggingDriverOptions()))
+		defer db.Close()
+
+		result, err := db.Exec("INSERT INTO users VALUES (?, ?)", 1, "bob")
+		assert.Nil(t, err)
+		affected, _ := result.RowsAffected()
+		assert.Equal(t, int64(2), affected)
+
+		rows, err := db.Query("SELECT id FROM users")
+		assert.Nil(t, err)
+		count := 0
+		for rows.Next() {
+			count++
+		}
+		assert.Nil(t, rows.Close())
+		assert.Equal(t, 3, count)
+
+		assert.Equal(t,
+			"[DEBUG]   () exec : INSERT INTO users VALUES (?, ?) sql.duration_ms=X sql.rows=2 sql.args=\"[$1=REDACTED $2=REDACTED]\"\n"+
+				"[DEBUG]   () query : SELECT id FROM users sql.duration_ms=X sql.rows=3\n",
+			withoutDuration(appender.String()))
+	}()
+
+	// slow queries at WARN, errors at ERROR, arguments shown
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("sqlLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{IsEnabledLogLevel: true})
+		options := NewDefaultLoggingDriverOptions()
+		options.SlowQueryThreshold = time.Millisecond * 10
+		options.ShowArgs = true
+		db := sql.OpenDB(NewLoggingConnector(logger, fakeConnector{}, options))
+		defer db.Close()
+
+		_, err := db.Exec("UPDATE SLOW users SET name = ? WHERE id = ?", "alice", 1)
+		assert.Nil(t, err)
+
+		_, err = db.Query("SELECT FAIL", nil)
+		assert.NotNil(t, err)
+
+		assert.Equal(t,
+			"[WARN]   () exec : UPDATE SLOW users SET name = ? WHERE id = ? sql.duration_ms=X sql.rows=2 sql.args=\"[$1=\\\"alice\\\" $2=1]\"\n"+
+				"[ERROR]   () query : SELECT FAIL sql.duration_ms=X sql.args=\"[$1=NULL]\" error=\"syntax error\"\n",
+			withoutDuration(appender.String()))
+	}()
+
+	// prepared statements of a registered driver, request scoped logger
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("sqlLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		db, err := sql.Open("golog-fake", "")
+		assert.Nil(t, err)
+		defer db.Close()
+
+		ctx := ContextWithLogger(context.Background(), logger.With(F("request_id", "abc")))
+		stmt, err := db.PrepareContext(ctx, "DELETE FROM users WHERE id = ?")
+		assert.Nil(t, err)
+		_, err = stmt.ExecContext(ctx, 1)
+		assert.Nil(t, err)
+		assert.Nil(t, stmt.Close())
+
+		assert.Equal(t,
+			"   () exec : DELETE FROM users WHERE id = ? request_id=abc sql.duration_ms=X sql.rows=1 sql.args=\"[$1=REDACTED]\"\n",
+			withoutDuration(appender.String()))
+	}()
+
+	// the checker and the converter of the statement are used
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("sqlLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		options := NewDefaultLoggingDriverOptions()
+		options.ShowArgs = true
+		db := sql.OpenDB(NewLoggingConnector(logger, fakeConnector{}, options))
+		defer db.Close()
+
+		stmt, err := db.Prepare("UPDATE CONVERT users SET name = ? WHERE id = ?")
+		assert.Nil(t, err)
+		_, err = stmt.Exec("alice", 1)
+		assert.Nil(t, err)
+		assert.Nil(t, stmt.Close())
+
+		assert.Equal(t,
+			"   () exec : UPDATE CONVERT users SET name = ? WHERE id = ? sql.duration_ms=X sql.rows=2 sql.args=\"[$1=\\\"ALICE\\\" $2=10]\"\n",
+			withoutDuration(appender.String()))
+	}()
+}
@@ -0,0 +1,70 @@
+package golog
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// defaultStackTraceDepth is the max number of frames captured
+const defaultStackTraceDepth = 64
+
+// StackFrame
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// StackTrace is a list of frames, the innermost call first
+type StackTrace []StackFrame
+
+// String returns the stack trace as a multi-line block
+//
+//	main.handler
+//		/src/main.go:10
+func (stackTrace StackTrace) String() string {
+	var builder strings.Builder
+	for i, frame := range stackTrace {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString("\t")
+		builder.WriteString(frame.Function)
+		builder.WriteString("\n\t\t")
+		builder.WriteString(frame.File)
+		builder.WriteString(":")
+		builder.WriteString(strconv.Itoa(frame.Line))
+	}
+	return builder.String()
+}
+
+// NewStackTrace captures the stack of the calling goroutine.
+// skip is the number of frames to skip, 0 identifying the caller of NewStackTrace.
+func NewStackTrace(skip int) StackTrace {
+	pcs := make([]uintptr, defaultStackTraceDepth)
+	n := runtime.Callers(skip+2, pcs)
+	return newStackTraceFromPCs(pcs[:n])
+}
+
+// newStackTraceFromPCs
+func newStackTraceFromPCs(pcs []uintptr) StackTrace {
+	if len(pcs) == 0 {
+		return nil
+	}
+
+	stackTrace := make(StackTrace, 0, len(pcs))
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		stackTrace = append(stackTrace, StackFrame{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		})
+		if !more {
+			break
+		}
+	}
+	return stackTrace
+}
@@ -0,0 +1,63 @@
+package golog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStackTrace(t *testing.T) {
+
+	func() {
+		stackTrace := NewStackTrace(0)
+		assert.Equal(t, "github.com/ajainc/golog.TestNewStackTrace.func1", stackTrace[0].Function)
+		assert.Equal(t, true, strings.HasSuffix(stackTrace[0].File, "stacktrace_test.go"))
+	}()
+}
+
+func TestStackTrace_String(t *testing.T) {
+
+	stackTrace := StackTrace{
+		{Function: "main.handler", File: "/src/main.go", Line: 10},
+		{Function: "main.main", File: "/src/main.go", Line: 3},
+	}
+	assert.Equal(t, "\tmain.handler\n\t\t/src/main.go:10\n\tmain.main\n\t\t/src/main.go:3", stackTrace.String())
+}
+
+func TestLogger_SetStackTraceLevels(t *testing.T) {
+
+	// text
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		logger.SetStackTraceLevels(LogLevel_ERROR)
+		logger.Info("info")
+		logger.Error("error")
+
+		lines := strings.Split(appender.String(), "\n")
+		require.GreaterOrEqual(t, len(lines), 3)
+		assert.Equal(t, "   () info", lines[0])
+		assert.Equal(t, "   () error", lines[1])
+		assert.Equal(t, "\tgithub.com/ajainc/golog.TestLogger_SetStackTraceLevels.func1", lines[2])
+	}()
+
+	// json
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		logger.SetStackTraceLevels(LogLevel_ERROR)
+		logger.Errorj(struct{}{})
+
+		var decoded struct {
+			StackTrace StackTrace `json:"stackTrace"`
+		}
+		assert.Nil(t, json.Unmarshal([]byte(appender.String()), &decoded))
+		require.NotEmpty(t, decoded.StackTrace)
+		assert.Equal(t, "github.com/ajainc/golog.TestLogger_SetStackTraceLevels.func2", decoded.StackTrace[0].Function)
+	}()
+}
@@ -0,0 +1,223 @@
+package golog
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// SpanContext identifies the span which an event belongs to, as defined by W3C Trace Context.
+// Ids and flags are lowercase hex strings.
+type SpanContext struct {
+	TraceId    string
+	SpanId     string
+	TraceFlags string
+}
+
+// IsValid reports whether the trace id and the span id are set
+func (spanContext SpanContext) IsValid() bool {
+	return spanContext.TraceId != "" && spanContext.SpanId != ""
+}
+
+// IsSampled reports whether the sampled flag is set
+func (spanContext SpanContext) IsSampled() bool {
+	flags, err := hex.DecodeString(spanContext.TraceFlags)
+	return err == nil && len(flags) == 1 && flags[0]&0x01 == 0x01
+}
+
+// Traceparent returns the span context formatted as a W3C traceparent header
+func (spanContext SpanContext) Traceparent() string {
+	flags := spanContext.TraceFlags
+	if flags == "" {
+		flags = "00"
+	}
+	return "00-" + spanContext.TraceId + "-" + spanContext.SpanId + "-" + flags
+}
+
+// ParseTraceparent parses a W3C traceparent header, e.g.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceparent(traceparent string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent : %s", traceparent)
+	}
+
+	version, traceId, spanId, flags := parts[0], parts[1], parts[2], parts[3]
+
+	// future versions may append fields, version 00 must not
+	if !isLowerHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent version : %s", traceparent)
+	}
+
+	if !isLowerHex(traceId, 32) || traceId == strings.Repeat("0", 32) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent trace id : %s", traceparent)
+	}
+
+	if !isLowerHex(spanId, 16) || spanId == strings.Repeat("0", 16) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent span id : %s", traceparent)
+	}
+
+	if !isLowerHex(flags, 2) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent flags : %s", traceparent)
+	}
+
+	return SpanContext{TraceId: traceId, SpanId: spanId, TraceFlags: flags}, nil
+}
+
+// isLowerHex
+func isLowerHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// spanContextKey
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx which carries spanContext
+func ContextWithSpanContext(ctx context.Context, spanContext SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, spanContext)
+}
+
+// ContextWithTraceparent returns a copy of ctx which carries the span context of the traceparent header.
+// ctx is returned as is if the header is invalid.
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	spanContext, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx
+	}
+	return ContextWithSpanContext(ctx, spanContext)
+}
+
+// SpanContextExtractor returns the active span of ctx.
+// It bridges tracing libraries without golog depending on them, e.g. for OpenTelemetry
+//
+//	golog.SetSpanContextExtractor(func(ctx context.Context) (golog.SpanContext, bool) {
+//		spanContext := trace.SpanContextFromContext(ctx)
+//		return golog.SpanContext{
+//			TraceId:    spanContext.TraceID().String(),
+//			SpanId:     spanContext.SpanID().String(),
+//			TraceFlags: spanContext.TraceFlags().String(),
+//		}, spanContext.IsValid()
+//	})
+type SpanContextExtractor = func(ctx context.Context) (SpanContext, bool)
+
+var (
+	spanContextExtractorMu *sync.RWMutex = new(sync.RWMutex)
+	spanContextExtractor   SpanContextExtractor
+)
+
+// SetSpanContextExtractor sets the extractor used by SpanContextFromContext
+func SetSpanContextExtractor(extractor SpanContextExtractor) {
+	spanContextExtractorMu.Lock()
+	defer spanContextExtractorMu.Unlock()
+	spanContextExtractor = extractor
+}
+
+// SpanContextFromContext returns the active span of ctx.
+// The extractor set by SetSpanContextExtractor is used first,
+// then the span context set by ContextWithSpanContext or ContextWithTraceparent.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+
+	spanContextExtractorMu.RLock()
+	extractor := spanContextExtractor
+	spanContextExtractorMu.RUnlock()
+
+	if extractor != nil {
+		if spanContext, ok := extractor(ctx); ok && spanContext.IsValid() {
+			return spanContext, true
+		}
+	}
+
+	spanContext, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return spanContext, ok && spanContext.IsValid()
+}
+
+// WithContext returns a copy of the logger whose events carry the active span of ctx.
+// The logger is returned as is if ctx has no active span.
+func (logger *Logger) WithContext(ctx context.Context) Logger {
+	child := *logger
+	if spanContext, ok := SpanContextFromContext(ctx); ok {
+		child.spanContext = &spanContext
+	}
+	return child
+}
+
+// TraceContext calls specified appender to print string with the active span of ctx.
+func (logger *Logger) TraceContext(ctx context.Context, string string) {
+	contextLogger := logger.WithContext(ctx)
+	if contextLogger.enabledMetadata {
+		metadata := contextLogger.newMetadata(LogLevel_TRACE)
+		contextLogger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, &metadata, LogLevel_TRACE)
+	} else {
+		contextLogger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, nil, LogLevel_TRACE)
+	}
+}
+
+// DebugContext calls specified appender to print string with the active span of ctx.
+func (logger *Logger) DebugContext(ctx context.Context, string string) {
+	contextLogger := logger.WithContext(ctx)
+	if contextLogger.enabledMetadata {
+		metadata := contextLogger.newMetadata(LogLevel_DEBUG)
+		contextLogger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, &metadata, LogLevel_DEBUG)
+	} else {
+		contextLogger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, nil, LogLevel_DEBUG)
+	}
+}
+
+// InfoContext calls specified appender to print string with the active span of ctx.
+func (logger *Logger) InfoContext(ctx context.Context, string string) {
+	contextLogger := logger.WithContext(ctx)
+	if contextLogger.enabledMetadata {
+		metadata := contextLogger.newMetadata(LogLevel_INFO)
+		contextLogger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, &metadata, LogLevel_INFO)
+	} else {
+		contextLogger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, nil, LogLevel_INFO)
+	}
+}
+
+// WarnContext calls specified appender to print string with the active span of ctx.
+func (logger *Logger) WarnContext(ctx context.Context, string string) {
+	contextLogger := logger.WithContext(ctx)
+	if contextLogger.enabledMetadata {
+		metadata := contextLogger.newMetadata(LogLevel_WARN)
+		contextLogger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, &metadata, LogLevel_WARN)
+	} else {
+		contextLogger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, nil, LogLevel_WARN)
+	}
+}
+
+// ErrorContext calls specified appender to print string with the active span of ctx.
+func (logger *Logger) ErrorContext(ctx context.Context, string string) {
+	contextLogger := logger.WithContext(ctx)
+	if contextLogger.enabledMetadata {
+		metadata := contextLogger.newMetadata(LogLevel_ERROR)
+		contextLogger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, &metadata, LogLevel_ERROR)
+	} else {
+		contextLogger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, nil, LogLevel_ERROR)
+	}
+}
+
+// FatalContext calls specified appender to print string with the active span of ctx.
+func (logger *Logger) FatalContext(ctx context.Context, string string) {
+	contextLogger := logger.WithContext(ctx)
+	if contextLogger.enabledMetadata {
+		metadata := contextLogger.newMetadata(LogLevel_FATAL)
+		contextLogger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, &metadata, LogLevel_FATAL)
+	} else {
+		contextLogger.doAppendIfLevelEnabled(&TextLogEvent{Event: string}, nil, LogLevel_FATAL)
+	}
+
+	contextLogger.exit()
+}
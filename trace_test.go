@@ -0,0 +1,107 @@
+package golog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+
+	cases := []struct {
+		input    string
+		expected SpanContext
+		isValid  bool
+	}{
+		{
+			input:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expected: SpanContext{TraceId: "4bf92f3577b34da6a3ce929d0e0e4736", SpanId: "00f067aa0ba902b7", TraceFlags: "01"},
+			isValid:  true,
+		},
+		{
+			// future version with an additional field
+			input:    "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra",
+			expected: SpanContext{TraceId: "4bf92f3577b34da6a3ce929d0e0e4736", SpanId: "00f067aa0ba902b7", TraceFlags: "00"},
+			isValid:  true,
+		},
+		{input: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{input: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{input: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{input: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{input: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{input: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7"},
+		{input: ""},
+	}
+
+	for _, c := range cases {
+		actual, err := ParseTraceparent(c.input)
+		assert.Equal(t, c.isValid, err == nil, c.input)
+		assert.Equal(t, c.expected, actual, c.input)
+	}
+}
+
+func TestSpanContextFromContext(t *testing.T) {
+
+	// traceparent
+	func() {
+		ctx := ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		spanContext, ok := SpanContextFromContext(ctx)
+		assert.Equal(t, true, ok)
+		assert.Equal(t, true, spanContext.IsSampled())
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", spanContext.Traceparent())
+	}()
+
+	// extractor
+	func() {
+		SetSpanContextExtractor(func(ctx context.Context) (SpanContext, bool) {
+			return SpanContext{TraceId: "1", SpanId: "2", TraceFlags: "00"}, true
+		})
+		defer SetSpanContextExtractor(nil)
+
+		spanContext, ok := SpanContextFromContext(context.Background())
+		assert.Equal(t, true, ok)
+		assert.Equal(t, SpanContext{TraceId: "1", SpanId: "2", TraceFlags: "00"}, spanContext)
+	}()
+
+	// no span
+	func() {
+		_, ok := SpanContextFromContext(context.Background())
+		assert.Equal(t, false, ok)
+	}()
+}
+
+func TestLogger_WithContext(t *testing.T) {
+
+	ctx := ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	// text
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		spanLogger := logger.WithContext(ctx)
+		spanLogger.Info("message")
+		assert.Equal(t, "   () message trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01\n", appender.String())
+	}()
+
+	// json
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		spanLogger := logger.WithContext(ctx)
+		spanLogger.Infoj(struct{}{})
+		assert.Equal(t, `{"EventData":{},"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01"}`+"\n", appender.String())
+	}()
+
+	// the span is picked up by the log calls taking ctx
+	func() {
+		appender := newBufferAppender()
+		logger := NewLogger("testLogger", LogLevel_TRACE, appender)
+		logger.SetMetadataConfig(&MetadataConfig{})
+		logger.InfoContext(ctx, "message")
+		logger.WarnContext(context.Background(), "no span")
+		assert.Equal(t, "   () message trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01\n   () no span\n", appender.String())
+	}()
+}